@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"iter"
+	"sync"
+	"sync/atomic"
+)
+
+// A backend stores a cache's entries.
+// Writes are serialized by the cache's mu, and reads hold it for reading,
+// unless the backend supports reads concurrent with writes; see lockFree
+type backend[K comparable, V any] interface {
+	load(key K) (V, bool)
+	store(key K, val V)
+	delete(key K)
+	len() int
+	all() iter.Seq2[K, V]
+
+	// lockFree reports if reads can run without holding mu at all
+	lockFree() bool
+}
+
+// mapBackend is the default backend, a plain map
+type mapBackend[K comparable, V any] map[K]V
+
+func (m mapBackend[K, V]) load(key K) (V, bool) {
+	v, ok := m[key]
+	return v, ok
+}
+
+func (m mapBackend[K, V]) store(key K, val V) {
+	m[key] = val
+}
+
+func (m mapBackend[K, V]) delete(key K) {
+	delete(m, key)
+}
+
+func (m mapBackend[K, V]) len() int {
+	return len(m)
+}
+
+func (m mapBackend[K, V]) all() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for key, val := range m {
+			if !yield(key, val) {
+				return
+			}
+		}
+	}
+}
+
+func (m mapBackend[K, V]) lockFree() bool {
+	return false
+}
+
+// syncMapBackend is a backend built on a sync.Map, selected by WithSyncMap.
+// Reads do not lock the cache, so they never wait for writes
+type syncMapBackend[K comparable, V any] struct {
+	m sync.Map
+	n atomic.Int64
+}
+
+func (b *syncMapBackend[K, V]) load(key K) (V, bool) {
+	v, ok := b.m.Load(key)
+	if !ok {
+		var zero V
+		return zero, false
+	}
+
+	return v.(V), true
+}
+
+func (b *syncMapBackend[K, V]) store(key K, val V) {
+	if _, loaded := b.m.Swap(key, val); !loaded {
+		b.n.Add(1)
+	}
+}
+
+func (b *syncMapBackend[K, V]) delete(key K) {
+	if _, loaded := b.m.LoadAndDelete(key); loaded {
+		b.n.Add(-1)
+	}
+}
+
+func (b *syncMapBackend[K, V]) len() int {
+	return int(b.n.Load())
+}
+
+func (b *syncMapBackend[K, V]) all() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		b.m.Range(func(key, val any) bool {
+			return yield(key.(K), val.(V))
+		})
+	}
+}
+
+func (b *syncMapBackend[K, V]) lockFree() bool {
+	return true
+}
@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithSyncMap(t *testing.T) {
+	c := NewWithOptions(WithSyncMap())
+	c.Set("1", 1)
+	c.Set("2", 2)
+	c.Set("3", 3, Expire(time.Millisecond))
+	c.Set("2", 20)
+	c.Delete("1")
+
+	if result, expected := c.Get("2"), T(20); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	time.Sleep(time.Millisecond * 2)
+
+	if result, expected := c.Items(), map[string]T{"2": 20}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if result, expected := c.Size(), 1; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	c.Clear()
+	if !c.IsEmpty() {
+		t.Errorf("Cache should have been empty, had keys: %v", c.Keys())
+	}
+}
+
+func TestWithSyncMapConcurrent(t *testing.T) {
+	c := NewWithOptions(WithSyncMap())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c.Set(strconv.Itoa(i*100+j), j)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c.Get(strconv.Itoa(j))
+				c.Keys()
+			}
+		}()
+	}
+
+	wg.Wait()
+	if result, expected := c.Size(), 1000; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func BenchmarkGetParallelSyncMap(b *testing.B) {
+	c := NewWithOptions(WithSyncMap())
+	for i := 0; i < 1000; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			c.Get(strconv.Itoa(i % 1000))
+			i++
+		}
+	})
+}
@@ -1,174 +1,2100 @@
 package cache
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"reflect"
 	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// T is a type for cache value
-type T interface{}
+// T is the value type of a StringCache
+type T = any
 
-// A Cache is a thread-safe store for fast item storage and retrieval
-type Cache struct {
-	itemOps   chan func(map[string]T)
-	expiryOps chan func(map[string]*time.Timer)
+// ErrClosed is the value panicked with when a closed cache is used
+var ErrClosed = errors.New("cache: use of closed cache")
+
+// An expiry tracks when an entry is due to be removed from the cache.
+// Its fields are guarded by the cache's expiryMu.
+// In caches created with WithCleanupInterval, timer is nil and the entry is
+// removed by the next sweep after its deadline. In caches created with WithTTLBuckets,
+// timer is nil and the entry is removed when its bucket fires
+type expiry[K comparable] struct {
+	key      K
+	timer    *time.Timer
+	bucket   *expiryBucket[K]
+	deadline time.Time
+	after    func()
+}
+
+// stop stops e's timer, or removes it from its bucket, if it has either
+func (e *expiry[K]) stop() {
+	if e.timer != nil {
+		e.timer.Stop()
+	}
+
+	if e.bucket != nil {
+		e.bucket.remove(e)
+		e.bucket = nil
+	}
+}
+
+// An expiryBucket groups the expiries of a cache created with WithTTLBuckets that share a rounded deadline,
+// so that a single timer removes all of their entries. Its fields are guarded by the cache's expiryMu
+type expiryBucket[K comparable] struct {
+	deadline int64
+	timer    *time.Timer
+	expiries map[*expiry[K]]struct{}
+
+	// buckets is the cache's buckets map, which the bucket leaves once it has no expiries left
+	buckets map[int64]*expiryBucket[K]
+}
+
+// remove removes e from b, stopping b's timer if b is left empty
+func (b *expiryBucket[K]) remove(e *expiry[K]) {
+	delete(b.expiries, e)
+	if len(b.expiries) == 0 && b.buckets[b.deadline] == b {
+		b.timer.Stop()
+		delete(b.buckets, b.deadline)
+	}
+}
+
+// A Cache is a thread-safe store for fast item storage and retrieval,
+// holding values of type V at keys of type K
+type Cache[K comparable, V any] struct {
+	// mu guards items and the other fields documented as guarded by it.
+	// expiryMu guards expiries. When both are held, mu must be locked first
+	mu       sync.RWMutex
+	items    backend[K, V]
+	expiryMu sync.Mutex
+	expiries map[K]*expiry[K]
+
+	// buckets holds the expiry buckets of a cache created with WithTTLBuckets by their deadline,
+	// in Unix nanoseconds. It is guarded by expiryMu
+	buckets    map[int64]*expiryBucket[K]
+	ttlBuckets int
+
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// bounded is set once reads must lock mu for writing, since they record keys with policy.
+	// Resize sets it before adding a policy, holding gate, which reads that do not lock mu hold for reading
+	bounded atomic.Bool
+	gate    sync.RWMutex
+
+	// opts holds the configuration the cache was created with, along with any limit set by Resize,
+	// so Clone can copy it. It is guarded by mu
+	opts cacheOptions
+
+	defaultExpiry time.Duration
+	maxSize       int
+	maxWeight     int64
+	weigh         func(key K, val V) int64
+	policy        Policy[K]
+	onEvict       func(key K, val V, reason EvictionReason)
+	onSet         func(key K, old, new V, replaced bool)
+	onDelete      func(key K, val V)
+
+	cleanupInterval time.Duration
+	loader          func(key K) (V, error)
+	singleFlight    bool
+	negativeTTL     time.Duration
+	refreshAfter    time.Duration
+	circuit         *circuit[K]
+	writeBehind     *writeBehind[K, V]
+	write           func(key K, val V) error
+	fallback        func(key K) V
+	logger          Logger
+
+	// events holds the entries stored or removed by the running item operation.
+	// It is guarded by mu
+	events []event[K, V]
+
+	// waiters holds the channels WaitForKey callers block on, closed once an entry is stored at their key.
+	// It is guarded by mu
+	waiters map[K]*waiter
+
+	// loads holds the loader calls in progress, so concurrent misses on a key can share one.
+	// It is guarded by mu
+	loads map[K]*load[V]
+
+	// negatives holds the keys the loader had no value for, while WithNegativeCaching remembers them.
+	// It is guarded by mu
+	negatives map[K]negativeEntry
+
+	// watchers holds the channels returned by Watch for each key. It is guarded by mu
+	watchers map[K][]chan WatchEvent[K, V]
+
+	// prefixWatchers holds the channels returned by WatchPrefix. It is guarded by mu
+	prefixWatchers []prefixWatcher[K, V]
+
+	// tags maps each tag to the keys set with it, and keyTags maps each key to its tags.
+	// They are guarded by mu
+	tags    map[string]map[K]struct{}
+	keyTags map[K][]string
+
+	// computeTimes holds how long each entry took to compute, for Probabilistic. It is guarded by mu
+	computeTimes map[K]time.Duration
+
+	// writeTimes holds when each entry was stored, for a cache created with WithRefreshAfterWrite.
+	// It is guarded by mu
+	writeTimes map[K]time.Time
+
+	// version is incremented whenever an entry is stored or removed, so Transaction can tell
+	// if the cache changed while it was unlocked. It is guarded by mu
+	version uint64
+
+	// weights holds the weight of each entry, and weight their total,
+	// for a cache created with WithMaxMemory or WithMaxWeight.
+	// They are guarded by mu
+	weights map[K]int64
+	weight  int64
+
+	// stats holds the usage counters reported by Stats
+	stats stats
+
+	// scans holds the snapshots of the scans in progress started by Scan
+	scans scanner[K]
+}
+
+// An event records an entry that was stored or removed by an item operation,
+// so that callbacks can be called once the operation has completed
+type event[K comparable, V any] struct {
+	key     K
+	old     V
+	val     V
+	exists  bool
+	removed bool
+	reason  EvictionReason
+
+	// taken is set for entries removed by GetAndDelete, which are not passed to OnEvict
+	taken bool
+
+	// after is set for an expired entry removed by purge, whose AfterFunc is called in place of the callbacks
+	after func()
+
+	// write is set for an entry stored by an operation that did not pass it to WithWriteThrough's fn first,
+	// which commitWrite then passes it to in place of the callbacks
+	write bool
+}
+
+// A StringCache is a Cache with string keys and values of any type, as created by New
+type StringCache = Cache[string, T]
+
+// New returns an empty cache
+func New() *StringCache {
+	return NewWithOptions()
+}
+
+// NewWithOptions returns an empty cache configured by the specified options
+func NewWithOptions(options ...CacheOption) *StringCache {
+	return NewCache[string, T](options...)
+}
+
+// NewCache returns an empty cache holding values of type V at keys of type K, configured by the specified options.
+// It panics if a WithEvictionPolicy, WithOnEvict, WithOnSet, WithOnDelete, WithLoader, WithCircuitBreakerFallback,
+// WithWeigher, WithWriteBehind or WithWriteThrough option was created for different key or value types
+func NewCache[K comparable, V any](options ...CacheOption) *Cache[K, V] {
+	return newCache[K, V](newCacheOptions(options))
+}
+
+// NewFromMap returns a cache configured by the specified options and holding the entries of initial,
+// which are stored in a single pass before it is returned. The cache does not retain initial
+func NewFromMap(initial map[string]T, options ...CacheOption) *StringCache {
+	return NewCacheFromMap(initial, options...)
+}
+
+// NewCacheFromMap returns a cache holding the entries of initial, configured by the specified options.
+// Entries are stored as by SetMany, so a default expiry or size limit applies to them. See NewFromMap and NewCache
+func NewCacheFromMap[K comparable, V any](initial map[K]V, options ...CacheOption) *Cache[K, V] {
+	c := newCache[K, V](newCacheOptions(options))
+	c.SetMany(initial)
+	return c
+}
+
+func newCache[K comparable, V any](opts cacheOptions) *Cache[K, V] {
+	c := &Cache[K, V]{
+		items:    make(mapBackend[K, V], max(opts.initialCapacity, 0)),
+		expiries: make(map[K]*expiry[K], max(opts.initialCapacity, 0)),
+		done:     make(chan struct{}),
+		opts:     opts,
+		waiters:  map[K]*waiter{},
+		loads:    map[K]*load[V]{},
+		tags:     map[string]map[K]struct{}{},
+		keyTags:  map[K][]string{},
+
+		computeTimes: map[K]time.Duration{},
+		writeTimes:   map[K]time.Time{},
+		negatives:    map[K]negativeEntry{},
+		watchers:     map[K][]chan WatchEvent[K, V]{},
+		weights:      map[K]int64{},
+
+		defaultExpiry: opts.defaultExpiry,
+		onEvict:       option[func(K, V, EvictionReason)](opts.onEvict, "WithOnEvict"),
+		onSet:         option[func(K, V, V, bool)](opts.onSet, "WithOnSet"),
+		onDelete:      option[func(K, V)](opts.onDelete, "WithOnDelete"),
+
+		cleanupInterval: opts.cleanupInterval,
+		buckets:         map[int64]*expiryBucket[K]{},
+		ttlBuckets:      opts.ttlBuckets,
+		loader:          option[func(K) (V, error)](opts.loader, "WithLoader"),
+		singleFlight:    opts.singleFlight,
+		negativeTTL:     opts.negativeTTL,
+		refreshAfter:    opts.refreshAfter,
+		fallback:        option[func(K) V](opts.fallback, "WithCircuitBreakerFallback"),
+		write:           option[func(K, V) error](opts.writeThrough, "WithWriteThrough"),
+		logger:          opts.logger,
+	}
+
+	if opts.syncMap {
+		c.items = &syncMapBackend[K, V]{}
+	}
+
+	if c.refreshAfter > 0 && c.loader == nil {
+		panic("cache: WithRefreshAfterWrite requires WithLoader")
+	}
+
+	c.maxWeight, c.weigh = weighing[K, V](opts)
+
+	if opts.maxSize > 0 || c.maxWeight > 0 {
+		c.maxSize = max(opts.maxSize, 0)
+		c.policy = newPolicy[K](opts)
+		c.bounded.Store(true)
+	}
+
+	if opts.maxFailures > 0 {
+		c.circuit = newCircuit[K](opts)
+	}
+
+	if opts.writeBehind != nil {
+		c.writeBehind = newWriteBehind[K, V](opts)
+	}
+
+	if c.cleanupInterval > 0 {
+		go c.loopCleanup()
+	}
+
+	return c
+}
+
+// option returns the value held for an option of type O, or the zero O if the option was not passed.
+// It panics if the option was created for a cache with different key or value types
+func option[O any](val any, name string) O {
+	o, ok := val.(O)
+	if !ok && val != nil {
+		panic(fmt.Sprintf("cache: %s option of type %T used with a cache requiring %T", name, val, o))
+	}
+
+	return o
+}
+
+// loopCleanup sweeps expired entries from the cache at its cleanup interval until the cache is closed
+func (c *Cache[K, V]) loopCleanup() {
+	ticker := time.NewTicker(c.cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, ok := c.sweep(); !ok {
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// sweep removes all entries whose deadline has passed, stopping their timers, then calls their after funcs.
+// The expiries are collected and their entries removed with mu held throughout, so an entry set in the meantime
+// is never removed by its predecessor's expiry. Returns the number of entries removed, and false if the cache has been closed
+func (c *Cache[K, V]) sweep() (int, bool) {
+	removed := make(chan []*expiry[K], 1)
+	ok := c.tryItemOp(func(items backend[K, V]) {
+		var expired []*expiry[K]
+		c.tryExpiryOp(func(expiries map[K]*expiry[K]) {
+			now := time.Now()
+			for key, e := range expiries {
+				if !e.deadline.After(now) {
+					e.stop()
+					delete(expiries, key)
+					expired = append(expired, e)
+				}
+			}
+		})
+
+		var evicted []*expiry[K]
+		for _, e := range expired {
+			if c.evict(items, e.key, Expired) {
+				evicted = append(evicted, e)
+			}
+		}
+
+		removed <- evicted
+	})
+
+	if !ok {
+		return 0, false
+	}
+
+	evicted := <-removed
+	for _, e := range evicted {
+		if e.after != nil {
+			e.after()
+		}
+	}
+
+	return len(evicted), true
+}
+
+// ClearExpired removes every entry whose expiry has passed but has not been removed yet,
+// as happens between sweeps in a cache created with WithCleanupInterval.
+// OnEvict callbacks are called with Expired, and AfterFunc functions are called.
+// Returns the number of entries that were removed
+func (c *Cache[K, V]) ClearExpired() int {
+	n, ok := c.sweep()
+	if !ok {
+		panic(ErrClosed)
+	}
+
+	return n
+}
+
+// itemOp runs op with the cache locked for writing, panicking with ErrClosed if the cache has been closed.
+// Item operations may run expiry operations, but expiry operations must never run item operations,
+// so the two can be nested without deadlocking.
+func (c *Cache[K, V]) itemOp(op func(backend[K, V])) {
+	if !c.tryItemOp(op) {
+		panic(ErrClosed)
+	}
+}
+
+// tryItemOp runs op with the cache locked for writing.
+// Returns false if the cache has been closed and op will never run.
+func (c *Cache[K, V]) tryItemOp(op func(backend[K, V])) bool {
+	return c.ctxItemOp(context.Background(), op) == nil
+}
+
+// ctxItemOp runs op with the cache locked for writing, giving up if ctx is done before the lock is acquired.
+// Returns ErrClosed if the cache has been closed, or ctx.Err() if ctx is done; in both cases op will never run.
+func (c *Cache[K, V]) ctxItemOp(ctx context.Context, op func(backend[K, V])) error {
+	return c.runItemOp(ctx, true, op)
+}
+
+// readOp runs op with the cache locked for reading, panicking with ErrClosed if the cache has been closed.
+// op must not modify the cache
+func (c *Cache[K, V]) readOp(op func(backend[K, V])) {
+	if err := c.ctxReadOp(context.Background(), op); err != nil {
+		panic(err)
+	}
+}
+
+// ctxReadOp runs op with the cache locked for reading, giving up if ctx is done before the lock is acquired.
+// See ctxItemOp
+func (c *Cache[K, V]) ctxReadOp(ctx context.Context, op func(backend[K, V])) error {
+	return c.runItemOp(ctx, false, op)
+}
+
+// runItemOp runs op with the cache locked for writing, or for reading if write is false.
+// Since reads on a bounded cache update its EvictionPolicy, they lock it for writing too,
+// while reads on a cache created with WithSyncMap do not lock it at all.
+// If the cache has OnEvict, OnSet or OnDelete callbacks, they are called for each entry op removed or stored
+// once the cache has been unlocked.
+func (c *Cache[K, V]) runItemOp(ctx context.Context, write bool, op func(backend[K, V])) error {
+	write = write || c.bounded.Load()
+	if !write && c.items.lockFree() {
+		if ok, err := c.runFree(ctx, op); ok {
+			return err
+		}
+
+		write = true
+	}
+
+	if err := c.lock(ctx, write); err != nil {
+		return err
+	}
+
+	// Resize may have added a policy while we waited for the read lock
+	if !write && c.bounded.Load() {
+		c.unlock(false)
+		write = true
+		if err := c.lock(ctx, true); err != nil {
+			return err
+		}
+	}
+
+	select {
+	case <-c.done:
+		c.unlock(write)
+		return ErrClosed
+	default:
+	}
+
+	for _, e := range c.runLocked(write, op) {
+		c.notify(e)
+	}
+
+	return nil
+}
+
+// runFree runs op without locking mu, holding gate so Resize cannot add a policy meanwhile.
+// Returns false without running op if the cache has one, so op must lock mu for writing
+func (c *Cache[K, V]) runFree(ctx context.Context, op func(backend[K, V])) (bool, error) {
+	c.gate.RLock()
+	defer c.gate.RUnlock()
+
+	if c.bounded.Load() {
+		return false, nil
+	}
+
+	select {
+	case <-c.done:
+		return true, ErrClosed
+	case <-ctx.Done():
+		return true, ctx.Err()
+	default:
+	}
+
+	op(c.items)
+	return true, nil
+}
+
+// runLocked runs op on the cache's items, which must be locked for writing, or for reading if write is false,
+// then unlocks them and returns the events op recorded. The cache is unlocked even if op panics,
+// since op may call user code such as a Modify fn, and a caller may recover from the panic.
+// Events recorded before such a panic are dropped
+func (c *Cache[K, V]) runLocked(write bool, op func(backend[K, V])) (events []event[K, V]) {
+	defer func() {
+		if write {
+			events = c.events
+			c.events = nil
+		}
+
+		c.unlock(write)
+	}()
+
+	op(c.items)
+	return nil
+}
+
+// lock locks mu for writing, or for reading if write is false, giving up if ctx is done first
+func (c *Cache[K, V]) lock(ctx context.Context, write bool) error {
+	if ctx.Done() == nil {
+		c.lockNow(write)
+		return nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if write && c.mu.TryLock() || !write && c.mu.TryRLock() {
+		return nil
+	}
+
+	// lock in another goroutine, so we can stop waiting if ctx is done first.
+	// If we do stop, the goroutine releases the lock as soon as it gets it
+	locked := make(chan struct{})
+	abandoned := make(chan struct{})
+	go func() {
+		c.lockNow(write)
+		select {
+		case locked <- struct{}{}:
+		case <-abandoned:
+			c.unlock(write)
+		}
+	}()
+
+	select {
+	case <-locked:
+		return nil
+	case <-ctx.Done():
+		close(abandoned)
+		return ctx.Err()
+	}
+}
+
+func (c *Cache[K, V]) lockNow(write bool) {
+	if write {
+		c.mu.Lock()
+	} else {
+		c.mu.RLock()
+	}
+}
+
+func (c *Cache[K, V]) unlock(write bool) {
+	if write {
+		c.mu.Unlock()
+	} else {
+		c.mu.RUnlock()
+	}
+}
+
+// notify calls the callbacks that apply to e
+func (c *Cache[K, V]) notify(e event[K, V]) {
+	if e.after != nil {
+		e.after()
+		return
+	}
+
+	if e.write {
+		c.commitWrite(e.key, e.val)
+		return
+	}
+
+	if !e.removed {
+		c.onSet(e.key, e.old, e.val, e.exists)
+		return
+	}
+
+	c.log(LevelInfo, "evict", e.key, map[string]interface{}{"reason": e.reason.String()})
+
+	if c.onEvict != nil && !e.taken {
+		c.onEvict(e.key, e.val, e.reason)
+	}
+
+	if c.onDelete != nil && e.reason != Capacity {
+		c.onDelete(e.key, e.val)
+	}
+}
+
+// expiryOp runs op with the cache's expiries locked, panicking with ErrClosed if the cache has been closed
+func (c *Cache[K, V]) expiryOp(op func(map[K]*expiry[K])) {
+	if !c.tryExpiryOp(op) {
+		panic(ErrClosed)
+	}
+}
+
+// tryExpiryOp runs op with the cache's expiries locked.
+// Returns false if the cache has been closed and op will never run
+func (c *Cache[K, V]) tryExpiryOp(op func(map[K]*expiry[K])) bool {
+	c.expiryMu.Lock()
+	defer c.expiryMu.Unlock()
+
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+
+	op(c.expiries)
+	return true
+}
+
+// Close stops all pending expiry timers and the cache's cleanup goroutine, if it has one.
+// In a cache created with WithWriteBehind, Close returns once the queued entries have been flushed.
+// Any use of the cache after Close will panic with ErrClosed.
+// Calling Close more than once returns ErrClosed.
+func (c *Cache[K, V]) Close() error {
+	err := ErrClosed
+	c.closeOnce.Do(func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		c.expiryMu.Lock()
+		defer c.expiryMu.Unlock()
+
+		for key, e := range c.expiries {
+			e.stop()
+			delete(c.expiries, key)
+		}
+
+		c.closeWatchers()
+		close(c.done)
+		err = nil
+	})
+
+	if err == nil && c.writeBehind != nil {
+		c.writeBehind.close()
+	}
+
+	return err
+}
+
+// Set will set the val into the cache at the specified key.
+// If an entry already exists at the specified key, it will be overwritten.
+// The options param can be used to perform logic after the entry has be inserted.
+// In a cache created with WithWriteThrough, the entry is only stored if the write succeeds,
+// and a failed write is logged at LevelError. Use SetE to see the error
+func (c *Cache[K, V]) Set(key K, val V, options ...SetOption) {
+	if c.writeOrLog(key, val) {
+		c.set(key, val, options)
+	}
+}
+
+// SetE behaves like Set, but returns the error from the function passed to WithWriteThrough, if any,
+// in which case the entry is not stored and the cache is left unchanged
+func (c *Cache[K, V]) SetE(key K, val V, options ...SetOption) error {
+	if err := c.writeThrough(key, val); err != nil {
+		return err
+	}
+
+	c.set(key, val, options)
+	return nil
+}
+
+// writeThrough passes the entry to the function passed to WithWriteThrough, if there is one
+func (c *Cache[K, V]) writeThrough(key K, val V) error {
+	if c.write == nil {
+		return nil
+	}
+
+	return c.write(key, val)
+}
+
+// writeOrLog passes the entry to the function passed to WithWriteThrough, if there is one, logging a failed write
+// at LevelError. Returns false if the write failed, in which case the entry must not be stored
+func (c *Cache[K, V]) writeOrLog(key K, val V) bool {
+	if err := c.writeThrough(key, val); err != nil {
+		c.log(LevelError, "write-through failed", key, map[string]interface{}{"error": err})
+		return false
+	}
+
+	return true
+}
+
+// commitWrite passes an entry that was stored without being written first to the function passed to WithWriteThrough,
+// once the cache has been unlocked. If the write fails, it is logged at LevelError and the entry is removed,
+// unless it has been replaced in the meantime, so the cache does not keep a value the backing store never got
+func (c *Cache[K, V]) commitWrite(key K, val V) {
+	if c.writeOrLog(key, val) {
+		return
+	}
+
+	c.tryItemOp(func(items backend[K, V]) {
+		if v, ok := items.load(key); ok && reflect.DeepEqual(v, val) {
+			c.evict(items, key, Manual)
+			c.tryExpiryOp(func(expiries map[K]*expiry[K]) {
+				stopExpiry(expiries, key)
+			})
+		}
+	})
+}
+
+func (c *Cache[K, V]) set(key K, val V, options []SetOption) {
+	c.itemOp(func(items backend[K, V]) {
+		c.placeWritten(items, key, val, options)
+	})
+}
+
+// SetMany will set each entry of entries into the cache, overwriting any existing entries.
+// All entries are stored in a single pass, which is much cheaper than calling Set for each one.
+// The options param is applied to every entry as it is inserted.
+// In a cache created with WithWriteThrough, every entry is written before the cache is locked,
+// and an entry whose write fails is logged at LevelError and not stored, as with Set
+func (c *Cache[K, V]) SetMany(entries map[K]V, options ...SetOption) {
+	written := make(map[K]bool, len(entries))
+	for key, val := range entries {
+		written[key] = c.writeOrLog(key, val)
+	}
+
+	c.itemOp(func(items backend[K, V]) {
+		for key, val := range entries {
+			if written[key] {
+				c.placeWritten(items, key, val, options)
+			}
+		}
+	})
+}
+
+// MSet will set each entry of entries into the cache in a single pass, like Redis's MSET,
+// so no other operation sees only some of them. Existing entries are overwritten and their expiries cleared.
+// It behaves like SetMany, but returns ErrClosed instead of panicking if the cache has been closed.
+// In a cache created with WithWriteThrough, every entry is written before the cache is locked;
+// if a write fails, no entry is stored and its error is returned, though the writes before it have been made
+func (c *Cache[K, V]) MSet(entries map[K]V) error {
+	for key, val := range entries {
+		if err := c.writeThrough(key, val); err != nil {
+			return err
+		}
+	}
+
+	return c.ctxItemOp(context.Background(), func(items backend[K, V]) {
+		for key, val := range entries {
+			c.placeWritten(items, key, val, nil)
+		}
+	})
+}
+
+// A BatchEntry is an entry to be stored by SetBatch, along with the options to apply to it
+type BatchEntry[K comparable, V any] struct {
+	Key     K
+	Value   V
+	Options []SetOption
+}
+
+// SetBatch will set each of entries into the cache in order, overwriting any existing entries,
+// and applying each entry's own options to it as it is inserted. Like SetMany, all entries are stored
+// in a single pass, so no other operation sees only some of them. If a key appears more than once,
+// the last entry for it wins. In a cache created with WithWriteThrough, entries are written as by SetMany
+func (c *Cache[K, V]) SetBatch(entries []BatchEntry[K, V]) {
+	written := make([]bool, len(entries))
+	for i, e := range entries {
+		written[i] = c.writeOrLog(e.Key, e.Value)
+	}
+
+	c.itemOp(func(items backend[K, V]) {
+		for i, e := range entries {
+			if written[i] {
+				c.placeWritten(items, e.Key, e.Value, e.Options)
+			}
+		}
+	})
+}
+
+// GetOrSet retrieves an entry at the specified key.
+// If no entry exists, fn is called to compute the value, which is then stored and returned.
+// The lookup and the store happen atomically, so fn is called at most once per missing key.
+// The options param is only applied when a new entry is stored.
+// Since fn runs while the cache is locked, it must not call back into the cache,
+// unless the cache was created with WithSingleFlight.
+func (c *Cache[K, V]) GetOrSet(key K, fn func() V, options ...SetOption) V {
+	if c.singleFlight {
+		v, _, _ := c.getOrLoad(key, func() (V, error) { return fn(), nil }, options, false)
+		return v
+	}
+
+	result := make(chan V, 1)
+	c.itemOp(func(items backend[K, V]) {
+		if v, ok := c.lookup(items, key); ok {
+			result <- v
+			return
+		}
+
+		start := time.Now()
+		v := fn()
+		c.computeTimes[key] = time.Since(start)
+		c.place(items, key, v, options)
+		result <- v
+	})
+
+	return <-result
+}
+
+// SetIfAbsent will set the val into the cache at the specified key only if no entry exists there.
+// Returns true if the val was stored.
+// The options param is only applied when the val is stored.
+func (c *Cache[K, V]) SetIfAbsent(key K, val V, options ...SetOption) bool {
+	stored := make(chan bool, 1)
+	c.itemOp(func(items backend[K, V]) {
+		if _, ok := c.find(items, key); ok {
+			stored <- false
+			return
+		}
+
+		c.place(items, key, val, options)
+		stored <- true
+	})
+
+	return <-stored
+}
+
+// SetDefault will set val as the default value at the specified key, storing it only if no entry exists there.
+// It behaves exactly like SetIfAbsent, under a name that says why the value is being stored.
+// Returns true if the val was stored.
+func (c *Cache[K, V]) SetDefault(key K, val V, options ...SetOption) bool {
+	return c.SetIfAbsent(key, val, options...)
+}
+
+// SetNX will set the val into the cache at the specified key only if no entry exists there, like Redis's SETNX.
+// It behaves exactly like SetIfAbsent. Returns true if the val was stored.
+// As with SETNX, it can be used to take a simple lock that expires if its holder never releases it:
+//
+//	if c.SetNX("lock:report", owner, Expire(time.Minute)) {
+//		defer c.CompareAndDelete("lock:report", owner)
+//		generateReport()
+//	}
+func (c *Cache[K, V]) SetNX(key K, val V, options ...SetOption) bool {
+	return c.SetIfAbsent(key, val, options...)
+}
+
+// SetIfPresent will set the val into the cache at the specified key only if an entry already exists there.
+// Returns true if the val was stored.
+// As with Set, any existing expiry is cleared and the options param is applied when the val is stored.
+func (c *Cache[K, V]) SetIfPresent(key K, val V, options ...SetOption) bool {
+	stored := make(chan bool, 1)
+	c.itemOp(func(items backend[K, V]) {
+		if _, ok := c.find(items, key); !ok {
+			stored <- false
+			return
+		}
+
+		c.place(items, key, val, options)
+		stored <- true
+	})
+
+	return <-stored
+}
+
+// SetXX will set the val into the cache at the specified key only if an entry already exists there,
+// like Redis's SET with XX. It behaves exactly like SetIfPresent. Returns true if the val was stored.
+// Unlike Set, which always stores, a missing entry is left missing; SetNX is its opposite.
+// When the val is stored, the old expiry is cleared and an Expire option starts a new one
+func (c *Cache[K, V]) SetXX(key K, val V, options ...SetOption) bool {
+	return c.SetIfPresent(key, val, options...)
+}
+
+// GetAndSet will set the val into the cache at the specified key and return the entry it replaced.
+// Returns bool specifying if an entry previously existed.
+// As with Set, any existing expiry is cleared and the options param is applied after the val is stored.
+// In a cache created with WithWriteThrough, val is only stored if the write succeeds, as with Set,
+// though the existing entry is returned either way
+func (c *Cache[K, V]) GetAndSet(key K, val V, options ...SetOption) (V, bool) {
+	written := c.writeOrLog(key, val)
+	result := make(chan V, 1)
+	exists := make(chan bool, 1)
+	c.itemOp(func(items backend[K, V]) {
+		v, ok := c.find(items, key)
+		if written {
+			c.placeWritten(items, key, val, options)
+		}
+
+		result <- v
+		exists <- ok
+	})
+
+	return <-result, <-exists
+}
+
+// CompareAndSwap will set newVal into the cache at the specified key only if
+// the existing entry is deeply equal to oldVal, as reported by reflect.DeepEqual.
+// Returns true if the swap happened.
+// As with Set, any existing expiry is cleared and the options param is applied when newVal is stored.
+func (c *Cache[K, V]) CompareAndSwap(key K, oldVal, newVal V, options ...SetOption) bool {
+	swapped := make(chan bool, 1)
+	c.itemOp(func(items backend[K, V]) {
+		if v, ok := c.find(items, key); !ok || !reflect.DeepEqual(v, oldVal) {
+			swapped <- false
+			return
+		}
+
+		c.place(items, key, newVal, options)
+		swapped <- true
+	})
+
+	return <-swapped
+}
+
+// place stores val at the specified key, removing the previous entry first if it has expired, replaces any expiry the previous entry had with the cache's default expiry,
+// if any, and then applies the options to the new entry. An Expire or AfterFunc option overrides the default expiry.
+// Since all of this happens with mu held, no other operation can see the new value alongside the previous
+// entry's expiry, nor can that expiry fire on the new value. It must only be called with mu held
+func (c *Cache[K, V]) place(items backend[K, V], key K, val V, options []SetOption) {
+	c.placeAs(items, key, val, options, false)
+}
+
+// placeWritten behaves like place, for an entry that has already been passed to the function passed to
+// WithWriteThrough, so it is not passed to it again. It must only be called with mu held
+func (c *Cache[K, V]) placeWritten(items backend[K, V], key K, val V, options []SetOption) {
+	c.placeAs(items, key, val, options, true)
+}
+
+func (c *Cache[K, V]) placeAs(items backend[K, V], key K, val V, options []SetOption, written bool) {
+	c.purge(items, key)
+	c.storeAs(items, key, val, written)
+	if _, ok := items.load(key); !ok {
+		return
+	}
+
+	c.tryExpiryOp(func(expiries map[K]*expiry[K]) {
+		stopExpiry(expiries, key)
+		if c.defaultExpiry > 0 {
+			expiries[key] = c.newExpiry(key, c.defaultExpiry, nil)
+		}
+	})
+
+	for _, option := range options {
+		option(entryTarget[K, V]{c: c, items: items, key: key, val: val})
+	}
+}
+
+// entryTarget is the setEntry for a freshly placed entry that SetOptions apply to. Its methods are called with mu held
+type entryTarget[K comparable, V any] struct {
+	c     *Cache[K, V]
+	items backend[K, V]
+	key   K
+	val   V
+}
+
+func (e entryTarget[K, V]) expire(d time.Duration, after func(val T)) {
+	var fn func()
+	if after != nil {
+		fn = func() { after(e.val) }
+	}
+
+	e.c.tryExpiryOp(func(expiries map[K]*expiry[K]) {
+		stopExpiry(expiries, e.key)
+		expiries[e.key] = e.c.newExpiry(e.key, d, fn)
+	})
+}
+
+func (e entryTarget[K, V]) computed(d time.Duration) {
+	if _, ok := e.items.load(e.key); ok {
+		e.c.computeTimes[e.key] = d
+	}
+}
+
+func (e entryTarget[K, V]) delete() {
+	e.c.evict(e.items, e.key, Manual)
+	e.c.tryExpiryOp(func(expiries map[K]*expiry[K]) {
+		stopExpiry(expiries, e.key)
+	})
+}
+
+// stopExpiry stops and removes the expiry for the specified key, if any.
+// It must only be called with expiryMu held
+func stopExpiry[K comparable](expiries map[K]*expiry[K], key K) {
+	if e, ok := expiries[key]; ok {
+		e.stop()
+		delete(expiries, key)
+	}
+}
+
+// newExpiry starts an expiry timer for the specified key, unless the cache sweeps expired entries instead.
+// It must only be called with expiryMu held
+func (c *Cache[K, V]) newExpiry(key K, d time.Duration, after func()) *expiry[K] {
+	e := &expiry[K]{
+		key:      key,
+		deadline: time.Now().Add(d),
+		after:    after,
+	}
+
+	switch {
+	case c.cleanupInterval > 0:
+	case c.ttlBuckets > 0:
+		c.addToBucket(e, d)
+	default:
+		e.timer = time.AfterFunc(d, func() { c.fire(e) })
+	}
+
+	return e
+}
+
+// addToBucket rounds e's deadline up to the next multiple of d divided by the number of buckets,
+// and adds e to the bucket for that deadline, starting the bucket's timer if it is new.
+// It must only be called with expiryMu held
+func (c *Cache[K, V]) addToBucket(e *expiry[K], d time.Duration) {
+	width := max(int64(d)/int64(c.ttlBuckets), 1)
+	deadline := (e.deadline.UnixNano() + width - 1) / width * width
+	e.deadline = time.Unix(0, deadline)
+
+	b, ok := c.buckets[deadline]
+	if !ok {
+		b = &expiryBucket[K]{deadline: deadline, expiries: map[*expiry[K]]struct{}{}, buckets: c.buckets}
+		b.timer = time.AfterFunc(time.Until(e.deadline), func() { c.fireBucket(b) })
+		c.buckets[deadline] = b
+	}
+
+	b.expiries[e] = struct{}{}
+	e.bucket = b
+}
+
+// resetExpiry reschedules the expiry for the specified key to d from now, keeping any AfterFunc callback.
+// A non-positive d removes the expiry. It must only be called with expiryMu held
+func (c *Cache[K, V]) resetExpiry(expiries map[K]*expiry[K], key K, d time.Duration) {
+	e, ok := expiries[key]
+	if ok {
+		e.stop()
+	}
+
+	switch {
+	case d <= 0:
+		delete(expiries, key)
+	case ok && c.ttlBuckets > 0 && c.cleanupInterval <= 0:
+		expiries[key] = c.newExpiry(key, d, e.after)
+	case ok:
+		if e.timer != nil {
+			e.timer.Reset(d)
+		}
+
+		e.deadline = time.Now().Add(d)
+	default:
+		expiries[key] = c.newExpiry(key, d, nil)
+	}
+}
+
+// Touch resets the expiry of the entry at the specified key to d from now, leaving its value untouched.
+// A d of zero removes the expiry, making the entry persistent.
+// Returns true if the entry exists
+func (c *Cache[K, V]) Touch(key K, d time.Duration) bool {
+	touched := make(chan bool, 1)
+	c.itemOp(func(items backend[K, V]) {
+		_, ok := c.find(items, key)
+		if ok {
+			c.tryExpiryOp(func(expiries map[K]*expiry[K]) {
+				c.resetExpiry(expiries, key, d)
+			})
+		}
+
+		touched <- ok
+	})
+
+	return <-touched
+}
+
+// ExpireAt sets the entry at the specified key to expire at the deadline t, leaving its value untouched.
+// If t has already passed, the entry is removed immediately.
+// Returns true if the entry exists
+func (c *Cache[K, V]) ExpireAt(key K, t time.Time) bool {
+	return c.Expire(key, time.Until(t))
+}
+
+// Expire sets the entry at the specified key to expire d from now, leaving its value untouched,
+// like Redis's EXPIRE. Any existing expiry is replaced. Unlike Touch, a non-positive d removes the entry immediately.
+// Returns true if the entry exists
+func (c *Cache[K, V]) Expire(key K, d time.Duration) bool {
+	found := make(chan bool, 1)
+	c.itemOp(func(items backend[K, V]) {
+		_, ok := c.find(items, key)
+		if ok {
+			if d <= 0 {
+				c.evict(items, key, Expired)
+			}
+
+			c.tryExpiryOp(func(expiries map[K]*expiry[K]) {
+				c.resetExpiry(expiries, key, d)
+			})
+		}
+
+		found <- ok
+	})
+
+	return <-found
+}
+
+// Persist removes the expiry from the entry at the specified key, leaving its value untouched,
+// like Redis's PERSIST. Any AfterFunc set on the entry will no longer be called.
+// Returns true if the entry existed and had an expiry
+func (c *Cache[K, V]) Persist(key K) bool {
+	persisted := make(chan bool, 1)
+	c.itemOp(func(items backend[K, V]) {
+		var ok bool
+		if _, exists := c.find(items, key); exists {
+			c.tryExpiryOp(func(expiries map[K]*expiry[K]) {
+				_, ok = expiries[key]
+				stopExpiry(expiries, key)
+			})
+		}
+
+		persisted <- ok
+	})
+
+	return <-persisted
+}
+
+// Rename moves the entry at oldKey to newKey, overwriting any entry already at newKey.
+// The entry keeps its remaining expiry, and is stored at newKey as Set would store it,
+// so it is passed to OnSet and queued by WithWriteBehind.
+// Returns false if no entry exists at oldKey
+func (c *Cache[K, V]) Rename(oldKey, newKey K) bool {
+	renamed := make(chan bool, 1)
+	c.itemOp(func(items backend[K, V]) {
+		if oldKey == newKey {
+			_, ok := c.find(items, oldKey)
+			renamed <- ok
+			return
+		}
+
+		m, ok := c.takeMoved(items, oldKey)
+		if ok {
+			c.putMoved(items, newKey, m)
+		}
+
+		renamed <- ok
+	})
+
+	return <-renamed
+}
+
+// A moved is an entry taken out of a cache by Rename, along with what it keeps at its new key
+type moved[K comparable, V any] struct {
+	val         V
+	tags        []string
+	computeTime time.Duration
+	computed    bool
+	written     time.Time
+	hasWritten  bool
+	deadline    time.Time
+	after       func()
+	expires     bool
+}
+
+// takeMoved removes the entry at the specified key from items for Rename, and returns it,
+// stopping its expiry. Returns false if no entry exists. It must only be called with mu held
+func (c *Cache[K, V]) takeMoved(items backend[K, V], key K) (moved[K, V], bool) {
+	var m moved[K, V]
+	v, ok := c.find(items, key)
+	if !ok {
+		return m, false
+	}
+
+	m.val, m.tags = v, c.keyTags[key]
+	m.computeTime, m.computed = c.computeTimes[key]
+	m.written, m.hasWritten = c.writeTimes[key]
+	c.remove(items, key)
+	var zero V
+	c.publish(key, v, zero, WatchDeleted)
+	c.tryExpiryOp(func(expiries map[K]*expiry[K]) {
+		if e, ok := expiries[key]; ok {
+			m.deadline, m.after, m.expires = e.deadline, e.after, true
+			stopExpiry(expiries, key)
+		}
+	})
+
+	return m, true
+}
+
+// putMoved stores an entry taken by takeMoved, from this cache or another, at the specified key,
+// overwriting any entry there. Its expiry is restarted with the same deadline and AfterFunc callback.
+// It must only be called with mu held
+func (c *Cache[K, V]) putMoved(items backend[K, V], key K, m moved[K, V]) {
+	c.purge(items, key)
+	c.evict(items, key, Manual)
+	c.tryExpiryOp(func(expiries map[K]*expiry[K]) {
+		stopExpiry(expiries, key)
+		if m.expires {
+			expiries[key] = c.newExpiry(key, time.Until(m.deadline), m.after)
+		}
+	})
+
+	c.store(items, key, m.val)
+	if _, ok := items.load(key); !ok {
+		return
+	}
+
+	c.tag(key, m.tags)
+	if m.computed {
+		c.computeTimes[key] = m.computeTime
+	}
+
+	if m.hasWritten {
+		c.writeTimes[key] = m.written
+	}
 }
 
-// New returns an empty cache
-func New() *Cache {
-	c := &Cache{
-		itemOps:   make(chan func(map[string]T)),
-		expiryOps: make(chan func(map[string]*time.Timer)),
+// store sets val into items at the specified key.
+// If the cache is bounded and now holds too many entries, entries are evicted as chosen by its policy,
+// unless the entry alone weighs more than the cache can hold, in which case only it is evicted.
+// In a cache created with WithWriteThrough, the entry is passed to commitWrite once the cache is unlocked.
+// It must only be called with mu held
+func (c *Cache[K, V]) store(items backend[K, V], key K, val V) {
+	c.storeAs(items, key, val, false)
+}
+
+// storeAs behaves like store, but leaves the entry out of write-through if written is true,
+// since it has already been written. It must only be called with mu held
+func (c *Cache[K, V]) storeAs(items backend[K, V], key K, val V, written bool) {
+	old, exists := items.load(key)
+	items.store(key, val)
+	c.version++
+	c.addWeight(key, val)
+	delete(c.negatives, key)
+	if c.refreshAfter > 0 {
+		c.writeTimes[key] = time.Now()
 	}
 
-	go c.loopItemOps()
-	go c.loopExpiryOps()
-	return c
+	c.stats.sets.Add(1)
+	if !exists {
+		c.stats.size.Add(1)
+	}
+
+	if c.onSet != nil {
+		c.events = append(c.events, event[K, V]{key: key, old: old, val: val, exists: exists})
+	}
+
+	if c.write != nil && !written {
+		c.events = append(c.events, event[K, V]{key: key, val: val, write: true})
+	}
+
+	c.publish(key, old, val, WatchSet)
+
+	if c.writeBehind != nil {
+		c.writeBehind.add(key, val)
+	}
+
+	c.wake(key)
+
+	if c.policy == nil {
+		return
+	}
+
+	c.policy.Record(key)
+	if c.oversized(key) {
+		// Evicting every other entry would still not make room for it
+		c.evict(items, key, Capacity)
+		c.tryExpiryOp(func(expiries map[K]*expiry[K]) { stopExpiry(expiries, key) })
+		return
+	}
+
+	c.trim(items)
 }
 
-func (c *Cache) loopItemOps() {
-	items := map[string]T{}
-	for op := range c.itemOps {
-		op(items)
+// trim evicts entries as chosen by the cache's policy until it is no longer over capacity.
+// It must only be called with mu held, and only if the cache has a policy
+func (c *Cache[K, V]) trim(items backend[K, V]) {
+	for c.overCapacity(items) {
+		evicted := c.victim(items)
+		c.evict(items, evicted, Capacity)
+		c.tryExpiryOp(func(expiries map[K]*expiry[K]) { stopExpiry(expiries, evicted) })
 	}
 }
 
-func (c *Cache) loopExpiryOps() {
-	expiries := map[string]*time.Timer{}
-	for op := range c.expiryOps {
-		op(expiries)
+// victim returns the key of the entry the cache's policy chooses to evict from items, which must not be empty.
+// The built-in policies take it straight from their lists, while other policies are passed every key in items.
+// It must only be called with mu held, and only if the cache has a policy
+func (c *Cache[K, V]) victim(items backend[K, V]) K {
+	if p, ok := c.policy.(listPolicy[K]); ok {
+		if key, ok := p.next(); ok {
+			if _, ok := items.load(key); ok {
+				return key
+			}
+		}
+	}
+
+	keys := make([]K, 0, items.len())
+	for k := range items.all() {
+		keys = append(keys, k)
 	}
+
+	evicted := c.policy.Evict(keys)
+	if _, ok := items.load(evicted); !ok {
+		evicted = keys[0]
+	}
+
+	return evicted
 }
 
-// Set will set the val into the cache at the specified key.
-// If an entry already exists at the specified key, it will be overwritten.
-// The options param can be used to perform logic after the entry has be inserted.
-func (c *Cache) Set(key string, val T, options ...SetOption) {
-	c.expiryOps <- func(expiries map[string]*time.Timer) {
-		if timer, ok := expiries[key]; ok {
-			timer.Stop()
+// remove deletes the entry at the specified key from items.
+// Returns false if no entry existed. It must only be called with mu held
+func (c *Cache[K, V]) remove(items backend[K, V], key K) bool {
+	if _, ok := items.load(key); !ok {
+		return false
+	}
+
+	items.delete(key)
+	c.version++
+	c.untag(key)
+	delete(c.computeTimes, key)
+	delete(c.writeTimes, key)
+	c.removeWeight(key)
+	c.stats.size.Add(-1)
+	if c.policy != nil {
+		c.policy.Remove(key)
+	}
+
+	return true
+}
+
+// evict removes the entry at the specified key from items, recording it for the OnEvict callback.
+// Returns false if no entry existed. It must only be called with mu held
+func (c *Cache[K, V]) evict(items backend[K, V], key K, reason EvictionReason) bool {
+	_, ok := c.take(items, key, reason, false)
+	return ok
+}
+
+// take removes the entry at the specified key from items and returns it, recording it for the callbacks,
+// though only for OnEvict if taken is false. Returns false if no entry existed. It must only be called with mu held
+func (c *Cache[K, V]) take(items backend[K, V], key K, reason EvictionReason, taken bool) (V, bool) {
+	val, ok := items.load(key)
+	if !ok {
+		return val, false
+	}
+
+	c.remove(items, key)
+	c.stats.record(reason)
+	var zero V
+	if reason == Expired {
+		c.publish(key, val, zero, WatchExpired)
+	} else {
+		c.publish(key, val, zero, WatchDeleted)
+	}
+
+	if c.onEvict != nil || c.onDelete != nil || c.logger != nil {
+		c.events = append(c.events, event[K, V]{key: key, val: val, removed: true, reason: reason, taken: taken})
+	}
+
+	return val, true
+}
+
+// lookup retrieves the entry at the specified key from items, recording the read as a hit or a miss.
+// It must only be called with mu held
+func (c *Cache[K, V]) lookup(items backend[K, V], key K) (V, bool) {
+	v, ok := c.live(items, key)
+	if !ok {
+		c.stats.misses.Add(1)
+		c.log(LevelDebug, "miss", key, nil)
+		return v, false
+	}
+
+	c.stats.hits.Add(1)
+	c.log(LevelDebug, "hit", key, nil)
+	if c.policy != nil {
+		c.policy.Record(key)
+	}
+
+	return v, true
+}
+
+// lazyExpiry reports if entries can outlive their deadline until they are removed,
+// as in caches created with WithCleanupInterval or WithTTLBuckets
+func (c *Cache[K, V]) lazyExpiry() bool {
+	return c.cleanupInterval > 0 || c.ttlBuckets > 0
+}
+
+// expired reports if the entry at the specified key has passed its deadline but not been removed yet.
+// Such an entry must be treated as missing. It must only be called from an item operation
+func (c *Cache[K, V]) expired(key K) bool {
+	if !c.lazyExpiry() {
+		return false
+	}
+
+	var expired bool
+	c.tryExpiryOp(func(expiries map[K]*expiry[K]) {
+		e, ok := expiries[key]
+		expired = ok && !e.deadline.After(time.Now())
+	})
+
+	return expired
+}
+
+// live retrieves the entry at the specified key from items, treating an expired entry as missing.
+// Unlike lookup, the read is not recorded. It must only be called from an item operation
+func (c *Cache[K, V]) live(items backend[K, V], key K) (V, bool) {
+	v, ok := items.load(key)
+	if ok && c.expired(key) {
+		var zero V
+		return zero, false
+	}
+
+	return v, ok
+}
+
+// find retrieves the entry at the specified key from items, first removing it as a sweep would
+// if it has expired, so that writes which depend on whether an entry exists treat it as missing.
+// It must only be called with mu held for writing
+func (c *Cache[K, V]) find(items backend[K, V], key K) (V, bool) {
+	c.purge(items, key)
+	return items.load(key)
+}
+
+// purge removes the entry at the specified key if it has passed its deadline but not been removed yet,
+// recording it for OnEvict with Expired. Its AfterFunc, if any, is called once the cache is unlocked.
+// It must only be called with mu held for writing
+func (c *Cache[K, V]) purge(items backend[K, V], key K) {
+	if !c.lazyExpiry() {
+		return
+	}
+
+	var e *expiry[K]
+	c.tryExpiryOp(func(expiries map[K]*expiry[K]) {
+		if x, ok := expiries[key]; ok && !x.deadline.After(time.Now()) {
+			x.stop()
 			delete(expiries, key)
+			e = x
 		}
+	})
+
+	if e != nil && c.evict(items, key, Expired) && e.after != nil {
+		c.events = append(c.events, event[K, V]{key: key, after: e.after})
 	}
+}
 
-	c.itemOps <- func(items map[string]T) {
-		items[key] = val
+// all iterates over the entries in items, leaving out those that have expired but not been removed yet.
+// It must only be called from an item operation
+func (c *Cache[K, V]) all(items backend[K, V]) iter.Seq2[K, V] {
+	expired := c.expiredSet()
+	if len(expired) == 0 {
+		return items.all()
 	}
 
-	for _, option := range options {
-		option(c, key, val)
+	return func(yield func(K, V) bool) {
+		for key, val := range items.all() {
+			if _, ok := expired[key]; ok {
+				continue
+			}
+
+			if !yield(key, val) {
+				return
+			}
+		}
 	}
 }
 
-// Clear removes all entries from the cache
-func (c *Cache) Clear() {
-	c.itemOps <- func(items map[string]T) {
-		for key := range items {
-			delete(items, key)
+// liveLen returns the number of entries in items, leaving out those that have expired but not been removed yet.
+// It must only be called from an item operation
+func (c *Cache[K, V]) liveLen(items backend[K, V]) int {
+	n := items.len()
+	for key := range c.expiredSet() {
+		if _, ok := items.load(key); ok {
+			n--
+		}
+	}
+
+	return n
+}
+
+// expiredSet returns the keys whose entries have passed their deadline but not been removed yet. See expired
+func (c *Cache[K, V]) expiredSet() map[K]struct{} {
+	if !c.lazyExpiry() {
+		return nil
+	}
+
+	var set map[K]struct{}
+	c.tryExpiryOp(func(expiries map[K]*expiry[K]) {
+		keys := expiredKeys(expiries)
+		if len(keys) > 0 {
+			set = make(map[K]struct{}, len(keys))
 		}
+
+		for _, key := range keys {
+			set[key] = struct{}{}
+		}
+	})
+
+	return set
+}
+
+// Clear removes all entries from the cache
+func (c *Cache[K, V]) Clear() {
+	c.itemOp(c.clearItems)
+}
+
+func (c *Cache[K, V]) clearItems(items backend[K, V]) {
+	for key := range items.all() {
+		c.evict(items, key, Manual)
 	}
+
+	clear(c.negatives)
+}
+
+// ClearEvery clears the cache on a loop at the specified interval.
+// The loop stops when the cache is closed or when the returned stop func is called,
+// which waits for the loop to exit and may be called more than once
+func (c *Cache[K, V]) ClearEvery(d time.Duration) (stop func()) {
+	return clearLoop(d, c.done, func() bool {
+		return c.tryItemOp(c.clearItems)
+	})
 }
 
-// ClearEvery clears the cache on a loop at the specified interval
-func (c *Cache) ClearEvery(d time.Duration) *time.Ticker {
+// clearLoop calls clear on a loop at the specified interval until done is closed, clear returns false,
+// or the returned stop func is called. stop waits for the loop to exit
+func clearLoop(d time.Duration, done <-chan struct{}, clear func() bool) (stop func()) {
 	ticker := time.NewTicker(d)
+	stopped := make(chan struct{})
+	exited := make(chan struct{})
 	go func() {
-		for range ticker.C {
-			c.Clear()
+		defer close(exited)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if !clear() {
+					return
+				}
+			case <-stopped:
+				return
+			case <-done:
+				return
+			}
 		}
 	}()
 
-	return ticker
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(stopped) })
+		<-exited
+	}
 }
 
 // Delete removes an entry from the cache at the specified key.
 // If no entry exists at the specified key, no action is taken
-func (c *Cache) Delete(key string) {
-	c.expiryOps <- func(expiries map[string]*time.Timer) {
-		if timer, ok := expiries[key]; ok {
-			timer.Stop()
-			delete(expiries, key)
+func (c *Cache[K, V]) Delete(key K) {
+	c.itemOp(func(items backend[K, V]) {
+		c.evict(items, key, Manual)
+		c.tryExpiryOp(func(expiries map[K]*expiry[K]) {
+			stopExpiry(expiries, key)
+		})
+	})
+}
+
+// DeleteMany removes the entries from the cache at the specified keys in a single pass.
+// Returns the number of entries that were removed
+func (c *Cache[K, V]) DeleteMany(keys []K) int {
+	result := make(chan int, 1)
+	c.itemOp(func(items backend[K, V]) {
+		c.tryExpiryOp(func(expiries map[K]*expiry[K]) {
+			for _, key := range keys {
+				stopExpiry(expiries, key)
+			}
+		})
+
+		var removed int
+		for _, key := range keys {
+			if c.evict(items, key, Manual) {
+				removed++
+			}
 		}
-	}
 
-	c.itemOps <- func(items map[string]T) {
-		if _, ok := items[key]; ok {
-			delete(items, key)
+		result <- removed
+	})
+
+	return <-result
+}
+
+// DeletePrefix removes every entry whose key starts with prefix in a single pass.
+// Keys that are not strings are matched by their fmt.Sprint representation.
+// Returns the number of entries that were removed
+func (c *Cache[K, V]) DeletePrefix(prefix string) int {
+	return c.deleteMatching(func(key K) bool {
+		return strings.HasPrefix(keyString(key), prefix)
+	})
+}
+
+// DeleteSuffix removes every entry whose key ends with suffix in a single pass.
+// Returns the number of entries that were removed
+func (c *Cache[K, V]) DeleteSuffix(suffix string) int {
+	return c.deleteMatching(func(key K) bool {
+		return strings.HasSuffix(keyString(key), suffix)
+	})
+}
+
+// DeleteContains removes every entry whose key contains substr in a single pass.
+// Returns the number of entries that were removed
+func (c *Cache[K, V]) DeleteContains(substr string) int {
+	return c.deleteMatching(func(key K) bool {
+		return strings.Contains(keyString(key), substr)
+	})
+}
+
+// deleteMatching removes every entry whose key matches in a single pass, along with its expiry.
+// Returns the number of entries that were removed
+func (c *Cache[K, V]) deleteMatching(match func(key K) bool) int {
+	result := make(chan int, 1)
+	c.itemOp(func(items backend[K, V]) {
+		var keys []K
+		for key := range items.all() {
+			if match(key) {
+				keys = append(keys, key)
+			}
+		}
+
+		for _, key := range keys {
+			c.evict(items, key, Manual)
+		}
+
+		c.tryExpiryOp(func(expiries map[K]*expiry[K]) {
+			for _, key := range keys {
+				stopExpiry(expiries, key)
+			}
+		})
+
+		result <- len(keys)
+	})
+
+	return <-result
+}
+
+// GetAndDelete removes an entry from the cache at the specified key and returns it.
+// Returns bool specifying if the entry existed.
+// Since the caller receives the entry, it is not passed to OnEvict; OnDelete is still called. See Evict
+func (c *Cache[K, V]) GetAndDelete(key K) (V, bool) {
+	return c.getAndDelete(key, true)
+}
+
+// Evict removes an entry from the cache at the specified key and returns it, as GetAndDelete does,
+// but also passes it to OnEvict with the reason Manual.
+// Returns bool specifying if the entry existed
+func (c *Cache[K, V]) Evict(key K) (V, bool) {
+	return c.getAndDelete(key, false)
+}
+
+func (c *Cache[K, V]) getAndDelete(key K, taken bool) (V, bool) {
+	result := make(chan V, 1)
+	exists := make(chan bool, 1)
+	c.itemOp(func(items backend[K, V]) {
+		c.purge(items, key)
+		v, ok := c.take(items, key, Manual, taken)
+		c.tryExpiryOp(func(expiries map[K]*expiry[K]) {
+			stopExpiry(expiries, key)
+		})
+
+		result <- v
+		exists <- ok
+	})
+
+	return <-result, <-exists
+}
+
+// CompareAndDelete removes an entry from the cache at the specified key only if
+// it is deeply equal to expected, as reported by reflect.DeepEqual.
+// Returns true if the entry was removed
+func (c *Cache[K, V]) CompareAndDelete(key K, expected V) bool {
+	deleted := make(chan bool, 1)
+	c.itemOp(func(items backend[K, V]) {
+		if v, ok := c.find(items, key); !ok || !reflect.DeepEqual(v, expected) {
+			deleted <- false
+			return
+		}
+
+		c.evict(items, key, Manual)
+		c.tryExpiryOp(func(expiries map[K]*expiry[K]) {
+			stopExpiry(expiries, key)
+		})
+
+		deleted <- true
+	})
+
+	return <-deleted
+}
+
+// fire removes the entry whose expiry timer e has elapsed, then calls e's after func.
+// Nothing happens if e has since been cancelled or replaced, or if the cache has been closed.
+// e is checked and the entry removed with mu held throughout, since a Set in between could otherwise
+// have its new value removed by the expiry it just replaced
+func (c *Cache[K, V]) fire(e *expiry[K]) {
+	current := make(chan bool, 1)
+	ok := c.tryItemOp(func(items backend[K, V]) {
+		var ok bool
+		c.tryExpiryOp(func(expiries map[K]*expiry[K]) {
+			if ok = expiries[e.key] == e; ok {
+				delete(expiries, e.key)
+			}
+		})
+
+		if ok {
+			c.evict(items, e.key, Expired)
 		}
+
+		current <- ok
+	})
+
+	if ok && <-current && e.after != nil {
+		e.after()
 	}
 }
 
-// Get retrieves an entry at the specified key
-func (c *Cache) Get(key string) T {
-	result := make(chan T, 1)
-	c.itemOps <- func(items map[string]T) {
-		result <- items[key]
+// fireBucket removes the entries whose expiries are still in b once its timer has elapsed,
+// then calls their after funcs. Nothing happens if the cache has been closed
+func (c *Cache[K, V]) fireBucket(b *expiryBucket[K]) {
+	removed := make(chan []*expiry[K], 1)
+	ok := c.tryItemOp(func(items backend[K, V]) {
+		var expired []*expiry[K]
+		c.tryExpiryOp(func(expiries map[K]*expiry[K]) {
+			if c.buckets[b.deadline] == b {
+				delete(c.buckets, b.deadline)
+			}
+
+			for e := range b.expiries {
+				if expiries[e.key] == e {
+					delete(expiries, e.key)
+					expired = append(expired, e)
+				}
+
+				e.bucket = nil
+			}
+
+			clear(b.expiries)
+		})
+
+		var evicted []*expiry[K]
+		for _, e := range expired {
+			if c.evict(items, e.key, Expired) {
+				evicted = append(evicted, e)
+			}
+		}
+
+		removed <- evicted
+	})
+
+	if !ok {
+		return
 	}
 
-	return <-result
+	for _, e := range <-removed {
+		if e.after != nil {
+			e.after()
+		}
+	}
+}
+
+// Get retrieves an entry at the specified key.
+// If the cache was created with WithLoader, a missing entry is loaded first
+func (c *Cache[K, V]) Get(key K) V {
+	v, _ := c.GetOK(key)
+	return v
 }
 
 // GetOK retrieves an entry at the specified key.
-// Returns bool specifying if the entry exists
-func (c *Cache) GetOK(key string) (T, bool) {
-	result := make(chan T, 1)
+// Returns bool specifying if the entry exists.
+// If the cache was created with WithLoader, a missing entry is loaded first
+func (c *Cache[K, V]) GetOK(key K) (V, bool) {
+	if c.loader != nil {
+		return c.load(key)
+	}
+
+	result := make(chan V, 1)
 	exists := make(chan bool, 1)
-	c.itemOps <- func(items map[string]T) {
-		v, ok := items[key]
+	c.readOp(func(items backend[K, V]) {
+		v, ok := c.lookup(items, key)
 		result <- v
 		exists <- ok
-	}
+	})
 
 	return <-result, <-exists
 }
 
+// Contains reports whether an entry exists at the specified key, treating an expired entry as missing.
+// Unlike GetOK, the cache's loader is not called, the check is not counted as a hit or a miss,
+// and the entry is not marked as used by the cache's eviction policy
+func (c *Cache[K, V]) Contains(key K) bool {
+	exists := make(chan bool, 1)
+	c.readOp(func(items backend[K, V]) {
+		_, ok := c.live(items, key)
+		exists <- ok
+	})
+
+	return <-exists
+}
+
+// GetMany retrieves the entries at the specified keys.
+// Keys with no entry are absent from the result
+func (c *Cache[K, V]) GetMany(keys []K) map[K]V {
+	found, err := c.MGet(keys)
+	if err != nil {
+		panic(err)
+	}
+
+	return found
+}
+
+// MGet retrieves the entries at the specified keys in a single pass, like Redis's MGET.
+// Keys with no entry are absent from the result.
+// It behaves like GetMany, but returns ErrClosed instead of panicking if the cache has been closed
+func (c *Cache[K, V]) MGet(keys []K) (map[K]V, error) {
+	result := make(chan map[K]V, 1)
+	err := c.ctxReadOp(context.Background(), func(items backend[K, V]) {
+		found := make(map[K]V, len(keys))
+		for _, key := range keys {
+			if val, ok := c.lookup(items, key); ok {
+				found[key] = val
+			}
+		}
+
+		result <- found
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return <-result, nil
+}
+
+// RemainingTTL returns how long the entry at the specified key has left before it expires.
+// Returns false if no entry exists or the entry has no expiry set
+func (c *Cache[K, V]) RemainingTTL(key K) (time.Duration, bool) {
+	result := make(chan time.Duration, 1)
+	c.expiryOp(func(expiries map[K]*expiry[K]) {
+		var ttl time.Duration
+		if e, ok := expiries[key]; ok {
+			ttl = time.Until(e.deadline)
+		}
+
+		result <- ttl
+	})
+
+	if ttl := <-result; ttl > 0 {
+		return ttl, true
+	}
+
+	return 0, false
+}
+
+// The durations TTL returns in place of a remaining time, as Redis's TTL command does
+const (
+	// TTLPersistent means the entry exists but has no expiry
+	TTLPersistent time.Duration = -1
+
+	// TTLMissing means no entry exists
+	TTLMissing time.Duration = -2
+)
+
+// TTL returns how long the entry at the specified key has left before it expires, like Redis's TTL.
+// Returns TTLPersistent if the entry has no expiry, or TTLMissing if no entry exists or it has already expired.
+// Unlike RemainingTTL, the entry and its expiry are read together, so an entry removed concurrently
+// is never reported with a TTL. Returns ErrClosed if the cache has been closed
+func (c *Cache[K, V]) TTL(key K) (time.Duration, error) {
+	result := make(chan time.Duration, 1)
+	err := c.ctxItemOp(context.Background(), func(items backend[K, V]) {
+		ttl := TTLMissing
+		if _, ok := c.find(items, key); ok {
+			ttl = TTLPersistent
+			c.tryExpiryOp(func(expiries map[K]*expiry[K]) {
+				if e, ok := expiries[key]; ok {
+					ttl = max(time.Until(e.deadline), 0)
+				}
+			})
+		}
+
+		if ttl == 0 {
+			ttl = TTLMissing
+		}
+
+		result <- ttl
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return <-result, nil
+}
+
+// KeysExpiringSoon retrieves a sorted list of the keys whose entries expire within the specified duration from now,
+// so they can be refreshed before they do. Entries with no expiry, and entries that have already expired
+// but not yet been removed, are left out
+func (c *Cache[K, V]) KeysExpiringSoon(within time.Duration) []K {
+	result := make(chan []K, 1)
+	c.expiryOp(func(expiries map[K]*expiry[K]) {
+		result <- expiringKeys(expiries, within)
+	})
+
+	keys := <-result
+	sortKeys(keys)
+	return keys
+}
+
+// ExpiredKeys retrieves a sorted list of the keys whose entries have passed their deadline but not yet been removed.
+// In a cache created with WithCleanupInterval, these are the entries waiting for the next sweep,
+// and in one created with WithTTLBuckets, those waiting for their bucket to fire.
+// Otherwise each entry is removed by its own timer at its deadline, so the list is empty
+// except for entries whose timers are firing at that moment
+func (c *Cache[K, V]) ExpiredKeys() []K {
+	result := make(chan []K, 1)
+	c.expiryOp(func(expiries map[K]*expiry[K]) {
+		result <- expiredKeys(expiries)
+	})
+
+	keys := <-result
+	sortKeys(keys)
+	return keys
+}
+
+// expiredKeys returns the keys in expiries whose deadlines have passed. It must only be called with expiryMu held
+func expiredKeys[K comparable](expiries map[K]*expiry[K]) []K {
+	now := time.Now()
+	keys := []K{}
+	for key, e := range expiries {
+		if !e.deadline.After(now) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// expiringKeys returns the keys in expiries whose deadlines fall within the specified duration from now.
+// It must only be called with expiryMu held
+func expiringKeys[K comparable](expiries map[K]*expiry[K], within time.Duration) []K {
+	now := time.Now()
+	cutoff := now.Add(within)
+	keys := []K{}
+	for key, e := range expiries {
+		if e.deadline.After(now) && !e.deadline.After(cutoff) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
 // Items retrieves all entries in the cache
-func (c *Cache) Items() map[string]T {
-	result := make(chan map[string]T, 1)
-	c.itemOps <- func(items map[string]T) {
-		cp := map[string]T{}
-		for key, val := range items {
+func (c *Cache[K, V]) Items() map[K]V {
+	result := make(chan map[K]V, 1)
+	c.readOp(func(items backend[K, V]) {
+		cp := map[K]V{}
+		for key, val := range c.all(items) {
 			cp[key] = val
 		}
 
 		result <- cp
-	}
+	})
+
+	return <-result
+}
+
+// Values retrieves all values in the cache in no particular order
+func (c *Cache[K, V]) Values() []V {
+	result := make(chan []V, 1)
+	c.readOp(func(items backend[K, V]) {
+		vals := make([]V, 0, items.len())
+		for _, val := range c.all(items) {
+			vals = append(vals, val)
+		}
+
+		result <- vals
+	})
+
+	return <-result
+}
+
+// ForEach calls fn for each entry in the cache, without copying the entries first.
+// The cache is locked for the duration of the iteration, so fn must not call back into the cache
+// or it may deadlock.
+func (c *Cache[K, V]) ForEach(fn func(key K, val V)) {
+	done := make(chan bool, 1)
+	c.readOp(func(items backend[K, V]) {
+		for key, val := range c.all(items) {
+			fn(key, val)
+		}
+
+		done <- true
+	})
+
+	<-done
+}
+
+// FilterItems retrieves the entries in the cache for which predicate returns true.
+// Since predicate runs while the cache is locked, it must not call back into the cache.
+func (c *Cache[K, V]) FilterItems(predicate func(K, V) bool) map[K]V {
+	result := make(chan map[K]V, 1)
+	c.readOp(func(items backend[K, V]) {
+		cp := map[K]V{}
+		for key, val := range c.all(items) {
+			if predicate(key, val) {
+				cp[key] = val
+			}
+		}
+
+		result <- cp
+	})
+
+	return <-result
+}
+
+// Count returns the number of entries in the cache for which predicate returns true.
+// Since predicate runs while the cache is locked, it must not call back into the cache.
+func (c *Cache[K, V]) Count(predicate func(K, V) bool) int {
+	result := make(chan int, 1)
+	c.readOp(func(items backend[K, V]) {
+		var count int
+		for key, val := range c.all(items) {
+			if predicate(key, val) {
+				count++
+			}
+		}
+
+		result <- count
+	})
 
 	return <-result
 }
 
 // IsEmpty returns wherever the cache is empty
-func (c *Cache) IsEmpty() bool {
+func (c *Cache[K, V]) IsEmpty() bool {
 	result := make(chan bool, 1)
-	c.itemOps <- func(items map[string]T) {
-		result <- len(items) == 0
-	}
+	c.readOp(func(items backend[K, V]) {
+		result <- c.liveLen(items) == 0
+	})
 
 	return <-result
 }
 
 // Size returns wherever the cache size
-func (c *Cache) Size() int {
+func (c *Cache[K, V]) Size() int {
 	result := make(chan int, 1)
-	c.itemOps <- func(items map[string]T) {
-		result <- len(items)
-	}
+	c.readOp(func(items backend[K, V]) {
+		result <- c.liveLen(items)
+	})
 
 	return <-result
 }
 
 // Keys retrieves a sorted list of all keys in the cache
-func (c *Cache) Keys() []string {
-	result := make(chan []string, 1)
-	c.itemOps <- func(items map[string]T) {
-		keys := make([]string, 0, len(items))
-		for k := range items {
+func (c *Cache[K, V]) Keys() []K {
+	keys := c.UnsortedKeys()
+	sortKeys(keys)
+	return keys
+}
+
+// UnsortedKeys retrieves a list of all keys in the cache in no particular order.
+// It is cheaper than Keys for large caches where ordering does not matter
+func (c *Cache[K, V]) UnsortedKeys() []K {
+	result := make(chan []K, 1)
+	c.readOp(func(items backend[K, V]) {
+		keys := make([]K, 0, items.len())
+		for k := range c.all(items) {
 			keys = append(keys, k)
 		}
 
-		sort.Strings(keys)
 		result <- keys
-	}
+	})
+
+	return <-result
+}
+
+// FilterKeys retrieves a sorted list of the keys in the cache for which predicate returns true.
+// Since predicate runs while the cache is locked, it must not call back into the cache.
+func (c *Cache[K, V]) FilterKeys(predicate func(K) bool) []K {
+	result := make(chan []K, 1)
+	c.readOp(func(items backend[K, V]) {
+		keys := []K{}
+		for k := range c.all(items) {
+			if predicate(k) {
+				keys = append(keys, k)
+			}
+		}
+
+		sortKeys(keys)
+		result <- keys
+	})
 
 	return <-result
 }
+
+// sortKeys sorts keys in ascending order.
+// Keys of string and numeric kinds are compared by value, and others by their fmt.Sprint representation
+func sortKeys[K comparable](keys []K) {
+	if strs, ok := any(keys).([]string); ok {
+		sort.Strings(strs)
+		return
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return lessKey(keys[i], keys[j])
+	})
+}
+
+// lessKey reports if a sorts before b. See sortKeys
+func lessKey[K comparable](a, b K) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Kind() == vb.Kind() {
+		switch va.Kind() {
+		case reflect.String:
+			return va.String() < vb.String()
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return va.Int() < vb.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return va.Uint() < vb.Uint()
+		case reflect.Float32, reflect.Float64:
+			return va.Float() < vb.Float()
+		}
+	}
+
+	return fmt.Sprint(a) < fmt.Sprint(b)
+}
+
+// keyString returns key as a string, for the methods that match keys against strings.
+// Keys that are not strings are formatted with fmt.Sprint
+func keyString[K comparable](key K) string {
+	if s, ok := any(key).(string); ok {
+		return s
+	}
+
+	return fmt.Sprint(key)
+}
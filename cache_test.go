@@ -1,13 +1,437 @@
 package cache
 
 import (
+	"context"
+	"errors"
 	"math/rand"
 	"reflect"
+	"runtime"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
 
+func TestNewWithOptions(t *testing.T) {
+	var applied int
+	option := func(o *cacheOptions) { applied++ }
+
+	c := NewWithOptions(option, option)
+	if applied != 2 {
+		t.Errorf("Options were applied %d times, expected 2", applied)
+	}
+
+	c.Set("1", 1)
+	if result, expected := c.Get("1"), 1; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestNewCache(t *testing.T) {
+	type point struct{ X, Y int }
+
+	c := NewCache[int, point](WithOnSet(func(key int, old, new point, replaced bool) {}))
+	c.Set(2, point{1, 2})
+	c.Set(10, point{3, 4})
+	c.Set(1, point{5, 6})
+
+	if result, ok := c.GetOK(2); !ok || result != (point{1, 2}) {
+		t.Errorf("Result was %#v, expected %#v", result, point{1, 2})
+	}
+
+	if result := c.Get(3); result != (point{}) {
+		t.Errorf("Result was %#v, expected the zero point", result)
+	}
+
+	expected := []int{1, 2, 10}
+	if result := c.Keys(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if removed := c.DeletePrefix("1"); removed != 2 {
+		t.Errorf("Removed %d entries, expected 2", removed)
+	}
+}
+
+func TestNewCacheOptionMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("NewCache should have panicked on an option for another key type")
+		}
+	}()
+
+	NewCache[int, T](WithOnDelete(func(key string, val T) {}))
+}
+
+func TestWithDefaultExpiry(t *testing.T) {
+	c := NewWithOptions(WithDefaultExpiry(time.Millisecond))
+	c.Set("1", 1)
+	c.Set("2", 2, Expire(time.Hour))
+
+	// the entry is removed by a timer, which may not get the lock straight away
+	deadline := time.Now().Add(time.Second)
+	for c.Contains("1") && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, exists := c.GetOK("1"); exists {
+		t.Errorf("Entry for key '1' should have expired by now")
+	}
+
+	if _, exists := c.GetOK("2"); !exists {
+		t.Errorf("Entry for key '2' should not have expired yet")
+	}
+}
+
+func TestWithMaxSize(t *testing.T) {
+	c := NewWithOptions(WithMaxSize(3))
+	c.Set("1", 1)
+	c.Set("2", 2, Expire(time.Hour))
+	c.Set("3", 3)
+	c.Get("1")
+	c.Set("4", 4)
+
+	expected := []string{"1", "3", "4"}
+	if result := c.Keys(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if _, ok := c.RemainingTTL("2"); ok {
+		t.Errorf("Expiry for evicted key '2' should have been removed")
+	}
+
+	c.SetMany(map[string]T{"5": 5, "6": 6})
+	if size := c.Size(); size != 3 {
+		t.Errorf("Cache size was %d, expected 3", size)
+	}
+
+	c.Delete("6")
+	c.Set("7", 7)
+	if _, exists := c.GetOK("5"); !exists {
+		t.Errorf("Entry for key '5' should not have been evicted")
+	}
+}
+
+func TestWithInitialCapacity(t *testing.T) {
+	entries := map[string]T{}
+	for i := 0; i < 1000; i++ {
+		entries[strconv.Itoa(i)] = i
+	}
+
+	warm := func(options ...CacheOption) float64 {
+		return testing.AllocsPerRun(10, func() {
+			NewWithOptions(options...).SetMany(entries)
+		})
+	}
+
+	if with, without := warm(WithInitialCapacity(len(entries))), warm(); with >= without {
+		t.Errorf("Warming allocated %v times with an initial capacity, expected fewer than %v", with, without)
+	}
+}
+
+func TestWithOnSet(t *testing.T) {
+	type set struct {
+		key      string
+		old, new T
+		replaced bool
+	}
+
+	var sets []set
+	c := NewWithOptions(WithOnSet(func(key string, old, new T, replaced bool) {
+		sets = append(sets, set{key, old, new, replaced})
+	}))
+
+	c.Set("1", 1)
+	c.Set("1", 2)
+	c.SetMany(map[string]T{"2": 2})
+	c.SetIfAbsent("2", 3)
+	c.Rename("2", "3")
+
+	expected := []set{
+		{"1", nil, 1, false},
+		{"1", 1, 2, true},
+		{"2", nil, 2, false},
+		{"3", nil, 2, false},
+	}
+
+	if !reflect.DeepEqual(sets, expected) {
+		t.Errorf("Sets were %#v, expected %#v", sets, expected)
+	}
+}
+
+func TestWithOnDelete(t *testing.T) {
+	var mu sync.Mutex
+	deleted := map[string]T{}
+	c := NewWithOptions(WithMaxSize(4), WithOnDelete(func(key string, val T) {
+		mu.Lock()
+		defer mu.Unlock()
+		deleted[key] = val
+	}))
+
+	c.Set("1", 1)
+	c.Set("2", 2)
+	c.Set("3", 3)
+	c.Set("4", 4, Expire(time.Millisecond))
+	c.Set("5", 5)
+
+	c.Delete("2")
+	c.Delete("6")
+	c.DeleteMany([]string{"3", "7"})
+	time.Sleep(time.Millisecond * 2)
+	c.Clear()
+
+	expected := map[string]T{"2": 2, "3": 3, "4": 4, "5": 5}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(deleted, expected) {
+		t.Errorf("Deleted %#v, expected %#v", deleted, expected)
+	}
+}
+
+func TestWithCleanupInterval(t *testing.T) {
+	called := make(chan T, 1)
+	c := NewWithOptions(WithCleanupInterval(time.Millisecond * 20))
+	c.Set("1", 1, Expire(time.Millisecond))
+	c.Set("2", 2, AfterFunc(time.Millisecond, func(val T) { called <- val }))
+	c.Set("3", 3, Expire(time.Hour))
+
+	time.Sleep(time.Millisecond * 2)
+
+	if _, exists := c.GetOK("1"); exists {
+		t.Errorf("Expired entry for key '1' should not be returned before it is swept")
+	}
+
+	if result, expected := c.ExpiredKeys(), []string{"1", "2"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	time.Sleep(time.Millisecond * 40)
+
+	expected := []string{"3"}
+	if result := c.Keys(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	select {
+	case val := <-called:
+		if expected := 2; !reflect.DeepEqual(val, expected) {
+			t.Errorf("AfterFunc was called with %#v, expected %#v", val, expected)
+		}
+	default:
+		t.Errorf("AfterFunc should have been called by the sweep")
+	}
+}
+
+func TestWithCleanupIntervalExpired(t *testing.T) {
+	called := make(chan T, 1)
+	evicted := map[string]EvictionReason{}
+	c := NewWithOptions(
+		WithCleanupInterval(time.Hour),
+		WithOnEvict(func(key string, val T, reason EvictionReason) { evicted[key] = reason }))
+	defer c.Close()
+	c.Set("1", 1, Expire(time.Millisecond))
+	c.Set("2", 2, AfterFunc(time.Millisecond, func(val T) { called <- val }))
+	c.Set("3", 3, Expire(time.Millisecond))
+	c.Set("4", 4)
+
+	time.Sleep(time.Millisecond * 5)
+
+	if c.Contains("1") {
+		t.Errorf("Expired entry for key '1' should not be contained")
+	}
+
+	if result, expected := c.Items(), map[string]T{"4": 4}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if result, expected := c.Size(), 1; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if !c.SetIfAbsent("1", "new") {
+		t.Errorf("SetIfAbsent should have replaced the expired entry for key '1'")
+	}
+
+	if c.SetIfPresent("2", "new") {
+		t.Errorf("SetIfPresent should not have replaced the expired entry for key '2'")
+	}
+
+	select {
+	case val := <-called:
+		if expected := 2; !reflect.DeepEqual(val, expected) {
+			t.Errorf("AfterFunc was called with %#v, expected %#v", val, expected)
+		}
+	default:
+		t.Errorf("AfterFunc should have been called once the expired entry was removed")
+	}
+
+	if result, expected := c.Get("1"), "new"; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if expected := map[string]EvictionReason{"1": Expired, "2": Expired}; !reflect.DeepEqual(evicted, expected) {
+		t.Errorf("Evicted %v, expected %v", evicted, expected)
+	}
+
+	if result, expected := c.ExpiredKeys(), []string{"3"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestWithTTLBuckets(t *testing.T) {
+	called := make(chan T, 1)
+	c := NewWithOptions(WithTTLBuckets(4))
+	for i := 0; i < 100; i++ {
+		c.Set(strconv.Itoa(i), i, Expire(time.Millisecond*40))
+	}
+
+	c.Set("after", "after", AfterFunc(time.Millisecond*40, func(val T) { called <- val }))
+	c.Set("kept", 1, Expire(time.Millisecond*40))
+	c.Set("kept", 2)
+
+	c.expiryOp(func(expiries map[string]*expiry[string]) {
+		if result := len(c.buckets); result > 2 {
+			t.Errorf("Entries were spread over %d buckets, expected at most %d", result, 2)
+		}
+	})
+
+	time.Sleep(time.Millisecond * 80)
+
+	expected := []string{"kept"}
+	if result := c.Keys(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	select {
+	case val := <-called:
+		if expected := "after"; !reflect.DeepEqual(val, expected) {
+			t.Errorf("AfterFunc was called with %#v, expected %#v", val, expected)
+		}
+	default:
+		t.Errorf("AfterFunc should have been called when its bucket fired")
+	}
+
+	c.expiryOp(func(expiries map[string]*expiry[string]) {
+		if result := len(c.buckets); result != 0 {
+			t.Errorf("%d buckets were left after every entry expired", result)
+		}
+	})
+}
+
+func TestClearExpired(t *testing.T) {
+	evicted := map[string]EvictionReason{}
+	c := NewWithOptions(
+		WithCleanupInterval(time.Hour),
+		WithOnEvict(func(key string, val T, reason EvictionReason) { evicted[key] = reason }))
+	c.Set("1", 1, Expire(time.Millisecond))
+	c.Set("2", 2, Expire(time.Millisecond))
+	c.Set("3", 3, Expire(time.Hour))
+	c.Set("4", 4)
+
+	time.Sleep(time.Millisecond * 2)
+
+	if result, expected := c.ClearExpired(), 2; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if result, expected := c.Keys(), []string{"3", "4"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if expected := map[string]EvictionReason{"1": Expired, "2": Expired}; !reflect.DeepEqual(evicted, expected) {
+		t.Errorf("Evicted %v, expected %v", evicted, expected)
+	}
+
+	if result, expected := c.ClearExpired(), 0; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestWithWriteThrough(t *testing.T) {
+	errWrite := errors.New("write failed")
+	written := map[string]T{}
+	c := NewWithOptions(WithWriteThrough(func(key string, val T) error {
+		if val == "bad" {
+			return errWrite
+		}
+
+		written[key] = val
+		return nil
+	}))
+
+	if err := c.SetE("1", 1); err != nil {
+		t.Errorf("Error was %v, expected nil", err)
+	}
+
+	if err := c.SetE("1", "bad", Expire(time.Hour)); err != errWrite {
+		t.Errorf("Error was %v, expected %v", err, errWrite)
+	}
+
+	c.Set("2", "bad")
+	if err := c.SetCtx(context.Background(), "3", "bad"); err != errWrite {
+		t.Errorf("Error was %v, expected %v", err, errWrite)
+	}
+
+	if expected := map[string]T{"1": 1}; !reflect.DeepEqual(c.Items(), expected) || !reflect.DeepEqual(written, expected) {
+		t.Errorf("Result was %#v and %#v written, expected %#v", c.Items(), written, expected)
+	}
+
+	if _, ok := c.RemainingTTL("1"); ok {
+		t.Errorf("A failed write-through should not have set a TTL")
+	}
+}
+
+func TestWithWriteThroughEveryPath(t *testing.T) {
+	errWrite := errors.New("write failed")
+	written := map[string]T{}
+	c := NewWithOptions(
+		WithWriteThrough(func(key string, val T) error {
+			if val == "bad" {
+				return errWrite
+			}
+
+			written[key] = val
+			return nil
+		}),
+		WithLoader(func(key string) (T, error) { return "loaded", nil }))
+
+	c.SetMany(map[string]T{"many": 1, "many-bad": "bad"})
+	c.SetBatch([]BatchEntry[string, T]{{Key: "batch", Value: 1}, {Key: "batch-bad", Value: "bad"}})
+	if err := c.MSet(map[string]T{"mset": "bad"}); err != errWrite {
+		t.Errorf("Error was %v, expected %v", err, errWrite)
+	}
+
+	c.GetAndSet("getandset", 1)
+	c.SetWithTags("tagged", 1, []string{"tag"})
+	c.SetIfAbsent("absent", 1)
+	c.SetIfAbsent("absent-bad", "bad")
+	c.GetOrSet("getorset", func() T { return 1 })
+	c.Increment("counter", 1)
+	c.Append("string", "a")
+	c.Modify("many", func(T) T { return 2 })
+	c.Modify("batch", func(T) T { return "bad" })
+	c.Rename("getandset", "renamed")
+	c.Get("load")
+
+	expected := map[string]T{
+		"many": 2, "getandset": 1, "tagged": 1, "absent": 1, "getorset": 1, "counter": int64(1),
+		"string": "a", "renamed": 1, "load": "loaded", "batch": 1,
+	}
+
+	if !reflect.DeepEqual(written, expected) {
+		t.Errorf("Written was %#v, expected %#v", written, expected)
+	}
+
+	delete(expected, "getandset")
+	delete(expected, "batch")
+	if result := c.Items(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
 func TestSet(t *testing.T) {
 	c := New()
 	c.Set("1", 1)
@@ -17,178 +441,1226 @@ func TestSet(t *testing.T) {
 	}
 }
 
+func TestReadSetOptions(t *testing.T) {
+	tests := []struct {
+		options  []SetOption
+		expected EntryOptions
+	}{
+		{nil, EntryOptions{}},
+		{[]SetOption{Expire(time.Second), ComputeTime(time.Millisecond)}, EntryOptions{Expiry: time.Second, ComputeTime: time.Millisecond}},
+		{[]SetOption{ExpireAtTime(time.Now().Add(-time.Second))}, EntryOptions{Expired: true}},
+		{[]SetOption{ExpireAtTime(time.Now().Add(-time.Second)), Expire(time.Second)}, EntryOptions{Expiry: time.Second}},
+	}
+
+	for _, test := range tests {
+		if result := ReadSetOptions(test.options...); !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("Result was %#v, expected %#v", result, test.expected)
+		}
+	}
+
+	called := false
+	e := ReadSetOptions(AfterFunc(time.Second, func(T) { called = true }))
+	e.AfterFunc(nil)
+	if !called || e.Expiry != time.Second {
+		t.Errorf("AfterFunc was not read")
+	}
+}
+
 func TestSetExpire(t *testing.T) {
 	c := New()
-	c.Set("1", 1, Expire(time.Millisecond))
+	c.Set("1", 1, Expire(time.Millisecond))
+
+	if _, exists := c.GetOK("1"); !exists {
+		t.Errorf("Entry for key '1' should not have expired yet")
+	}
+
+	time.Sleep(time.Millisecond * 2)
+
+	if _, exists := c.GetOK("1"); exists {
+		t.Errorf("Entry for key '1' should have expired by now")
+	}
+}
+
+func TestSetExpireAtTime(t *testing.T) {
+	c := New()
+	c.Set("1", 1, ExpireAtTime(time.Now().Add(time.Millisecond)))
+	c.Set("2", 2, ExpireAtTime(time.Now().Add(-time.Millisecond)))
+
+	if _, exists := c.GetOK("1"); !exists {
+		t.Errorf("Entry for key '1' should not have expired yet")
+	}
+
+	if _, exists := c.GetOK("2"); exists {
+		t.Errorf("Entry for key '2' should have expired immediately")
+	}
+
+	time.Sleep(time.Millisecond * 2)
+
+	if _, exists := c.GetOK("1"); exists {
+		t.Errorf("Entry for key '1' should have expired by now")
+	}
+}
+
+func TestSetAfterFunc(t *testing.T) {
+	c := New()
+	c.Set("1", 1, AfterFunc(time.Millisecond, func(val T) {
+		t.Log("after func executed for ", val)
+	}))
+
+	if _, exists := c.GetOK("1"); !exists {
+		t.Errorf("Entry for key '1' should not have expired yet")
+	}
+
+	time.Sleep(time.Millisecond * 2)
+
+	if _, exists := c.GetOK("1"); exists {
+		t.Errorf("Entry for key '1' should have expired by now")
+	}
+}
+
+func TestTouch(t *testing.T) {
+	c := New()
+	c.Set("1", 1, Expire(time.Millisecond))
+	c.Set("2", 2, Expire(time.Millisecond))
+
+	if !c.Touch("1", time.Hour) {
+		t.Errorf("Touch should have found key '1'")
+	}
+
+	if !c.Touch("2", 0) {
+		t.Errorf("Touch should have found key '2'")
+	}
+
+	if c.Touch("3", time.Hour) {
+		t.Errorf("Touch should not have found key '3'")
+	}
+
+	time.Sleep(time.Millisecond * 2)
+
+	if _, exists := c.GetOK("1"); !exists {
+		t.Errorf("Entry for key '1' should not have expired yet")
+	}
+
+	if _, exists := c.GetOK("2"); !exists {
+		t.Errorf("Entry for key '2' should not expire")
+	}
+
+	if _, ok := c.RemainingTTL("2"); ok {
+		t.Errorf("Entry for key '2' should not have a TTL")
+	}
+
+	if _, ok := c.RemainingTTL("3"); ok {
+		t.Errorf("Touch should not have created a TTL for missing key '3'")
+	}
+}
+
+func TestTouchKeepsAfterFunc(t *testing.T) {
+	c := New()
+	called := make(chan T, 1)
+	c.Set("1", 1, AfterFunc(time.Hour, func(val T) { called <- val }))
+	c.Touch("1", time.Millisecond)
+
+	select {
+	case val := <-called:
+		if expected := 1; !reflect.DeepEqual(val, expected) {
+			t.Errorf("AfterFunc was called with %#v, expected %#v", val, expected)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("AfterFunc should have been called after Touch")
+	}
+}
+
+func TestExpireAt(t *testing.T) {
+	c := New()
+	c.Set("1", 1)
+	c.Set("2", 2)
+
+	if !c.ExpireAt("1", time.Now().Add(time.Millisecond)) {
+		t.Errorf("ExpireAt should have found key '1'")
+	}
+
+	if !c.ExpireAt("2", time.Now().Add(-time.Millisecond)) {
+		t.Errorf("ExpireAt should have found key '2'")
+	}
+
+	if c.ExpireAt("3", time.Now().Add(time.Millisecond)) {
+		t.Errorf("ExpireAt should not have found key '3'")
+	}
+
+	if _, exists := c.GetOK("2"); exists {
+		t.Errorf("Entry for key '2' should have expired immediately")
+	}
+
+	time.Sleep(time.Millisecond * 2)
+
+	if _, exists := c.GetOK("1"); exists {
+		t.Errorf("Entry for key '1' should have expired by now")
+	}
+}
+
+func TestExpireMethod(t *testing.T) {
+	c := New()
+	c.Set("1", 1, Expire(time.Millisecond))
+	c.Set("2", 2, Expire(time.Hour))
+	c.Set("3", 3)
+
+	if !c.Expire("1", time.Hour) {
+		t.Errorf("Expire should have found key '1'")
+	}
+
+	if !c.Expire("2", time.Millisecond) {
+		t.Errorf("Expire should have found key '2'")
+	}
+
+	if !c.Expire("3", 0) {
+		t.Errorf("Expire should have found key '3'")
+	}
+
+	if c.Expire("4", time.Hour) {
+		t.Errorf("Expire should not have found key '4'")
+	}
+
+	if _, exists := c.GetOK("3"); exists {
+		t.Errorf("Entry for key '3' should have been removed immediately")
+	}
+
+	time.Sleep(time.Millisecond * 2)
+
+	if result, expected := c.Keys(), []string{"1"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestPersist(t *testing.T) {
+	c := New()
+	c.Set("1", 1, Expire(time.Millisecond))
+	c.Set("2", 2)
+
+	if !c.Persist("1") {
+		t.Errorf("Persist should have removed the expiry of key '1'")
+	}
+
+	if c.Persist("1") || c.Persist("2") || c.Persist("3") {
+		t.Errorf("Persist should only report entries that had an expiry")
+	}
+
+	time.Sleep(time.Millisecond * 2)
+
+	if _, exists := c.GetOK("1"); !exists {
+		t.Errorf("Entry for key '1' should not have expired")
+	}
+
+	if _, ok := c.RemainingTTL("1"); ok {
+		t.Errorf("Entry for key '1' should have no TTL")
+	}
+}
+
+func TestTTL(t *testing.T) {
+	c := New()
+	c.Set("1", 1, Expire(time.Hour))
+	c.Set("2", 2)
+
+	if ttl, err := c.TTL("1"); err != nil || ttl <= 0 || ttl > time.Hour {
+		t.Errorf("Result was %v, %v, expected a TTL of up to %v", ttl, err, time.Hour)
+	}
+
+	if result, err := c.TTL("2"); err != nil || result != TTLPersistent {
+		t.Errorf("Result was %v, %v, expected %v", result, err, TTLPersistent)
+	}
+
+	if result, err := c.TTL("3"); err != nil || result != TTLMissing {
+		t.Errorf("Result was %v, %v, expected %v", result, err, TTLMissing)
+	}
+
+	c.Close()
+	if _, err := c.TTL("1"); err != ErrClosed {
+		t.Errorf("Error was %v, expected %v", err, ErrClosed)
+	}
+}
+
+func TestTTLUnrounded(t *testing.T) {
+	c := New()
+	c.Set("1", 1, Expire(time.Millisecond*1500))
+	c.Set("2", 2, Expire(time.Millisecond*100))
+
+	if ttl, err := c.TTL("2"); err != nil || ttl <= time.Millisecond*50 || ttl > time.Millisecond*100 {
+		t.Errorf("Result was %v, %v, expected a TTL of up to %v", ttl, err, time.Millisecond*100)
+	}
+
+	if ttl, err := c.TTL("1"); err != nil || ttl%time.Second == 0 {
+		t.Errorf("Result was %v, %v, expected a TTL that is not a whole number of seconds", ttl, err)
+	}
+}
+
+func TestRename(t *testing.T) {
+	c := New()
+	c.Set("1", 1, Expire(time.Millisecond*5))
+	c.Set("2", 2, Expire(time.Hour))
+
+	if c.Rename("3", "4") {
+		t.Errorf("Rename should not have found key '3'")
+	}
+
+	if !c.Rename("1", "2") {
+		t.Errorf("Rename should have found key '1'")
+	}
+
+	if _, exists := c.GetOK("1"); exists {
+		t.Errorf("Entry for key '1' should not exist")
+	}
+
+	if result, expected := c.Get("2"), 1; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Entry for key '2' was %#v, expected %#v", result, expected)
+	}
+
+	if ttl, ok := c.RemainingTTL("2"); !ok || ttl > time.Millisecond*5 {
+		t.Errorf("TTL for key '2' was %v, expected at most %v", ttl, time.Millisecond*5)
+	}
+
+	time.Sleep(time.Millisecond * 10)
+
+	if _, exists := c.GetOK("2"); exists {
+		t.Errorf("Entry for key '2' should have expired by now")
+	}
+}
+
+func TestClear(t *testing.T) {
+	c := New()
+	for i := 0; i < 10; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+
+	c.Clear()
+
+	if keys := c.Keys(); len(keys) != 0 {
+		t.Errorf("Cache should have been empty, had keys: %v", keys)
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	c := New()
+	for i := 0; i < 10; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+	
+	if c.IsEmpty() {
+		t.Errorf("Cache is not empty, IsEmpty() shall return false")
+	}
+
+	c.Clear()
+
+	if !c.IsEmpty() {
+		t.Errorf("Cache is empty, IsEmpty() shall return true")
+	}
+}
+
+func TestSize(t *testing.T) {
+	c := New()
+	for i := 0; i < 10; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+	
+	if c.Size() != 10 {
+		t.Errorf("Cache size is incorrect, Size() shall return 10")
+	}
+
+	c.Clear()
+
+	if c.Size() != 0 {
+		t.Errorf("Cache size is incorrect, Size() shall return 0")
+	}
+}
+
+func TestDelete(t *testing.T) {
+	c := New()
+	c.Set("1", 1)
+	c.Delete("1")
+
+	if _, exists := c.GetOK("1"); exists {
+		t.Errorf("Entry for key '1' should not exist")
+	}
+}
+
+func TestDeleteMany(t *testing.T) {
+	c := New()
+	for i := 0; i < 5; i++ {
+		c.Set(strconv.Itoa(i), i, Expire(time.Hour))
+	}
+
+	if removed := c.DeleteMany([]string{"1", "3", "5", "3"}); removed != 2 {
+		t.Errorf("DeleteMany removed %d entries, expected 2", removed)
+	}
+
+	expected := []string{"0", "2", "4"}
+	if result := c.Keys(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if _, ok := c.RemainingTTL("1"); ok {
+		t.Errorf("Expiry for key '1' should have been removed")
+	}
+}
+
+func TestDeletePrefix(t *testing.T) {
+	c := New()
+	c.Set("user:1:profile", 1, Expire(time.Hour))
+	c.Set("user:1:settings", 2)
+	c.Set("user:2:profile", 3)
+	c.Set("user:10:profile", 4)
+
+	if removed := c.DeletePrefix("user:1:"); removed != 2 {
+		t.Errorf("DeletePrefix removed %d entries, expected 2", removed)
+	}
+
+	expected := []string{"user:10:profile", "user:2:profile"}
+	if result := c.Keys(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if _, ok := c.RemainingTTL("user:1:profile"); ok {
+		t.Errorf("Expiry for key 'user:1:profile' should have been removed")
+	}
+}
+
+func TestDeleteSuffix(t *testing.T) {
+	c := New()
+	c.Set("a:v1", 1, Expire(time.Hour))
+	c.Set("b:v1", 2)
+	c.Set("a:v2", 3)
+
+	if removed := c.DeleteSuffix(":v1"); removed != 2 {
+		t.Errorf("DeleteSuffix removed %d entries, expected 2", removed)
+	}
+
+	expected := []string{"a:v2"}
+	if result := c.Keys(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if _, ok := c.RemainingTTL("a:v1"); ok {
+		t.Errorf("Expiry for key 'a:v1' should have been removed")
+	}
+}
+
+func TestDeleteContains(t *testing.T) {
+	c := New()
+	c.Set("a:tmp:1", 1)
+	c.Set("tmp:2", 2)
+	c.Set("b:3", 3)
+
+	if removed := c.DeleteContains("tmp"); removed != 2 {
+		t.Errorf("DeleteContains removed %d entries, expected 2", removed)
+	}
+
+	expected := []string{"b:3"}
+	if result := c.Keys(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestDeleteMatchingNone(t *testing.T) {
+	c := New()
+	c.Set("a", 1)
+
+	if removed := c.DeletePrefix("b"); removed != 0 {
+		t.Errorf("DeletePrefix removed %d entries, expected 0", removed)
+	}
+
+	if removed := c.DeleteSuffix("b"); removed != 0 {
+		t.Errorf("DeleteSuffix removed %d entries, expected 0", removed)
+	}
+
+	if removed := c.DeleteContains("b"); removed != 0 {
+		t.Errorf("DeleteContains removed %d entries, expected 0", removed)
+	}
+
+	if result, expected := c.Size(), 1; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestGetAndDelete(t *testing.T) {
+	c := New()
+	c.Set("1", 1, Expire(time.Hour))
+
+	result, existed := c.GetAndDelete("1")
+	if !existed {
+		t.Errorf("Entry for key '1' should have existed")
+	}
+
+	if expected := 1; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Entry for key '1' was %#v, expected %#v", result, expected)
+	}
+
+	if _, exists := c.GetOK("1"); exists {
+		t.Errorf("Entry for key '1' should not exist")
+	}
+
+	if _, ok := c.RemainingTTL("1"); ok {
+		t.Errorf("Expiry for key '1' should have been removed")
+	}
+
+	if _, existed := c.GetAndDelete("2"); existed {
+		t.Errorf("Entry for key '2' should not have existed")
+	}
+}
+
+func TestCompareAndDelete(t *testing.T) {
+	c := New()
+	c.Set("1", 1, Expire(time.Hour))
+
+	if c.CompareAndDelete("1", 2) {
+		t.Errorf("CompareAndDelete should have failed for mismatched value")
+	}
+
+	if _, ok := c.RemainingTTL("1"); !ok {
+		t.Errorf("Expiry for key '1' should not have been removed")
+	}
+
+	if c.CompareAndDelete("2", nil) {
+		t.Errorf("CompareAndDelete should have failed for missing key '2'")
+	}
+
+	if !c.CompareAndDelete("1", 1) {
+		t.Errorf("CompareAndDelete should have succeeded for matching value")
+	}
+
+	if _, exists := c.GetOK("1"); exists {
+		t.Errorf("Entry for key '1' should not exist")
+	}
+
+	if _, ok := c.RemainingTTL("1"); ok {
+		t.Errorf("Expiry for key '1' should have been removed")
+	}
+}
+
+func TestClearEvery(t *testing.T) {
+	c := New()
+	for i := 0; i < 10; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+
+	before := runtime.NumGoroutine()
+	stop := c.ClearEvery(time.Millisecond)
+
+	if keys := c.Keys(); len(keys) != 10 {
+		t.Errorf("Cache should have had 10 keys, but had keys: %v", keys)
+	}
+
+	time.Sleep(time.Millisecond * 2)
+
+	if keys := c.Keys(); len(keys) != 0 {
+		t.Errorf("Cache should have been empty, had keys: %v", keys)
+	}
+
+	stop()
+	stop()
+
+	if after := runtime.NumGoroutine(); after != before {
+		t.Errorf("Goroutines went from %d to %d, expected the loop to have exited", before, after)
+	}
+
+	c.Set("1", 1)
+	time.Sleep(time.Millisecond * 2)
+
+	if _, ok := c.GetOK("1"); !ok {
+		t.Errorf("Entry for key '1' should not have been cleared after stop")
+	}
+}
+
+func TestClose(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	c := New()
+	c.Set("1", 1, Expire(time.Hour))
+	c.ClearEvery(time.Millisecond)
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	if err := c.Close(); err != ErrClosed {
+		t.Errorf("Second Close returned %v, expected %v", err, ErrClosed)
+	}
+
+	for i := 0; i < 100 && runtime.NumGoroutine() > before; i++ {
+		time.Sleep(time.Millisecond)
+	}
+
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("Cache leaked goroutines: had %d before New, %d after Close", before, after)
+	}
+}
+
+func TestUseAfterClose(t *testing.T) {
+	c := New()
+	c.Close()
+
+	defer func() {
+		if r := recover(); r != ErrClosed {
+			t.Errorf("Recovered %v, expected %v", r, ErrClosed)
+		}
+	}()
+
+	c.Set("1", 1)
+}
+
+func TestGet(t *testing.T) {
+	c := New()
+	c.Set("1", 1)
+
+	if result, expected := c.Get("1"), 1; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result for entry '1' was %#v, expected %#v", result, expected)
+	}
+
+	if result := c.Get("2"); result != nil {
+		t.Errorf("Result for entry '2' was %#v, expected nil", result)
+	}
+}
+
+func TestGetOK(t *testing.T) {
+	c := New()
+	c.Set("1", 1)
+
+	result, exists := c.GetOK("1")
+	if !exists {
+		t.Error("Entry for key '1' should exist")
+	}
+
+	if expected := 1; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Entry for key '1' was %#v, expected %#v", result, expected)
+	}
+
+	if _, exists := c.GetOK("2"); exists {
+		t.Errorf("Entry for key '2' should not exist")
+	}
+}
+
+func TestContains(t *testing.T) {
+	c := NewWithOptions(WithLoader(func(key string) (T, error) { return key, nil }))
+	c.Set("1", 1)
+
+	if !c.Contains("1") {
+		t.Errorf("Entry for key '1' should exist")
+	}
+
+	if c.Contains("2") {
+		t.Errorf("Entry for key '2' should not exist")
+	}
+
+	if result, expected := c.Size(), 1; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if result := c.Stats(); result.Hits != 0 || result.Misses != 0 {
+		t.Errorf("Result was %#v, expected no hits or misses", result)
+	}
+
+	bounded := NewWithOptions(WithMaxSize(2))
+	bounded.Set("1", 1)
+	bounded.Set("2", 2)
+	bounded.Contains("1")
+	bounded.Set("3", 3)
+
+	if bounded.Contains("1") {
+		t.Errorf("Contains should not have saved the entry for key '1' from eviction")
+	}
+}
+
+func TestSetMany(t *testing.T) {
+	c := New()
+	c.Set("0", 10, Expire(time.Millisecond))
+	c.SetMany(map[string]T{"0": 0, "1": 1, "2": 2})
+
+	time.Sleep(time.Millisecond * 2)
+
+	expected := map[string]T{"0": 0, "1": 1, "2": 2}
+	if result := c.Items(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestSetManyExpire(t *testing.T) {
+	c := New()
+	c.SetMany(map[string]T{"0": 0, "1": 1}, Expire(time.Millisecond))
+
+	if size := c.Size(); size != 2 {
+		t.Errorf("Cache size was %d, expected 2", size)
+	}
+
+	time.Sleep(time.Millisecond * 2)
+
+	if !c.IsEmpty() {
+		t.Errorf("Cache should have been empty, had keys: %v", c.Keys())
+	}
+}
+
+func TestNewFromMap(t *testing.T) {
+	initial := map[string]T{"1": 1, "2": 2, "3": 3}
+	c := NewFromMap(initial, WithDefaultExpiry(time.Hour))
+	initial["4"] = 4
+
+	if result, expected := c.Items(), map[string]T{"1": 1, "2": 2, "3": 3}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if _, ok := c.RemainingTTL("1"); !ok {
+		t.Errorf("Entry for key '1' should have had the default expiry applied")
+	}
+
+	ints := NewCacheFromMap(map[int]string{1: "a", 2: "b"}, WithMaxSize(1))
+	if result, expected := ints.Size(), 1; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestSetBatch(t *testing.T) {
+	called := make(chan T, 1)
+	c := New()
+	c.SetBatch([]BatchEntry[string, T]{
+		{Key: "a", Value: 1, Options: []SetOption{Expire(time.Millisecond * 10)}},
+		{Key: "b", Value: 2, Options: []SetOption{AfterFunc(time.Millisecond*100, func(val T) { called <- val })}},
+		{Key: "c", Value: 3},
+		{Key: "c", Value: 4},
+	})
+
+	if result, expected := c.Items(), map[string]T{"a": 1, "b": 2, "c": 4}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	time.Sleep(time.Millisecond * 50)
+
+	if result, expected := c.Keys(), []string{"b", "c"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	time.Sleep(time.Millisecond * 100)
+
+	if result, expected := c.Keys(), []string{"c"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	select {
+	case val := <-called:
+		if expected := 2; !reflect.DeepEqual(val, expected) {
+			t.Errorf("AfterFunc was called with %#v, expected %#v", val, expected)
+		}
+	default:
+		t.Errorf("AfterFunc for key 'b' should have been called")
+	}
+}
+
+func TestGetOrSet(t *testing.T) {
+	c := New()
+	c.Set("1", 1)
+
+	if result, expected := c.GetOrSet("1", func() T { return 2 }), 1; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result for entry '1' was %#v, expected %#v", result, expected)
+	}
+
+	if result, expected := c.GetOrSet("2", func() T { return 2 }), 2; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result for entry '2' was %#v, expected %#v", result, expected)
+	}
+
+	if result, expected := c.Get("2"), 2; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Entry for key '2' was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestGetOrSetCallsOnce(t *testing.T) {
+	c := New()
+	calls := 0
+
+	done := make(chan bool)
+	for i := 0; i < 100; i++ {
+		go func() {
+			c.GetOrSet("1", func() T {
+				calls++
+				return 1
+			})
+
+			done <- true
+		}()
+	}
+
+	for i := 0; i < 100; i++ {
+		<-done
+	}
+
+	if calls != 1 {
+		t.Errorf("Loader was called %d times, expected 1", calls)
+	}
+}
+
+func TestGetOrSetExpire(t *testing.T) {
+	c := New()
+	c.GetOrSet("1", func() T { return 1 }, Expire(time.Millisecond))
+
+	time.Sleep(time.Millisecond * 2)
+
+	if _, exists := c.GetOK("1"); exists {
+		t.Errorf("Entry for key '1' should have expired by now")
+	}
+}
+
+func TestGetMany(t *testing.T) {
+	c := New()
+	for i := 0; i < 5; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+
+	expected := map[string]T{"1": 1, "3": 3}
+	if result := c.GetMany([]string{"1", "3", "5"}); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestMSetMGet(t *testing.T) {
+	c := New()
+	c.Set("1", 0, Expire(time.Millisecond))
+
+	if err := c.MSet(map[string]T{"1": 1, "2": 2, "3": 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(time.Millisecond * 2)
+
+	result, err := c.MGet([]string{"1", "3", "4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := (map[string]T{"1": 1, "3": 3}); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	c.Close()
+	if err := c.MSet(map[string]T{"4": 4}); err != ErrClosed {
+		t.Errorf("Error was %v, expected %v", err, ErrClosed)
+	}
+
+	if _, err := c.MGet([]string{"1"}); err != ErrClosed {
+		t.Errorf("Error was %v, expected %v", err, ErrClosed)
+	}
+}
+
+func TestRemainingTTL(t *testing.T) {
+	c := New()
+	c.Set("1", 1, Expire(time.Minute))
+	c.Set("2", 2)
+
+	ttl, ok := c.RemainingTTL("1")
+	if !ok {
+		t.Errorf("Entry for key '1' should have a TTL")
+	}
+
+	if ttl <= 0 || ttl > time.Minute {
+		t.Errorf("TTL for key '1' was %v, expected between 0 and %v", ttl, time.Minute)
+	}
+
+	if _, ok := c.RemainingTTL("2"); ok {
+		t.Errorf("Entry for key '2' should not have a TTL")
+	}
+
+	if _, ok := c.RemainingTTL("3"); ok {
+		t.Errorf("Entry for key '3' should not exist")
+	}
+
+	c.Delete("1")
+	if _, ok := c.RemainingTTL("1"); ok {
+		t.Errorf("Entry for key '1' should not have a TTL after being deleted")
+	}
+}
+
+func TestSetIfAbsent(t *testing.T) {
+	c := New()
+	c.Set("1", 1)
+
+	if c.SetIfAbsent("1", 2) {
+		t.Errorf("SetIfAbsent should not have stored over existing key '1'")
+	}
+
+	if result, expected := c.Get("1"), 1; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Entry for key '1' was %#v, expected %#v", result, expected)
+	}
+
+	if !c.SetIfAbsent("2", 2, Expire(time.Millisecond)) {
+		t.Errorf("SetIfAbsent should have stored missing key '2'")
+	}
+
+	if result, expected := c.Get("2"), 2; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Entry for key '2' was %#v, expected %#v", result, expected)
+	}
+
+	time.Sleep(time.Millisecond * 2)
+
+	if _, exists := c.GetOK("2"); exists {
+		t.Errorf("Entry for key '2' should have expired by now")
+	}
+}
+
+func TestSetDefault(t *testing.T) {
+	c := New()
+	c.Set("1", 1)
+
+	if c.SetDefault("1", 2) {
+		t.Errorf("SetDefault should not have stored over existing key '1'")
+	}
+
+	if !c.SetDefault("2", 2) {
+		t.Errorf("SetDefault should have stored missing key '2'")
+	}
+
+	expected := map[string]T{"1": 1, "2": 2}
+	if result := c.Items(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestSetNX(t *testing.T) {
+	c := New()
+
+	if !c.SetNX("lock", "a", Expire(time.Hour)) {
+		t.Errorf("SetNX should have stored missing key 'lock'")
+	}
+
+	if c.SetNX("lock", "b") {
+		t.Errorf("SetNX should not have stored over existing key 'lock'")
+	}
+
+	if result, expected := c.Get("lock"), T("a"); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if _, ok := c.RemainingTTL("lock"); !ok {
+		t.Errorf("Entry for key 'lock' should have kept its expiry")
+	}
+}
+
+func TestSetIfPresent(t *testing.T) {
+	c := New()
+	c.Set("1", 1, Expire(time.Millisecond))
+
+	if c.SetIfPresent("2", 2) {
+		t.Errorf("SetIfPresent should not have stored missing key '2'")
+	}
+
+	if _, exists := c.GetOK("2"); exists {
+		t.Errorf("Entry for key '2' should not exist")
+	}
+
+	if !c.SetIfPresent("1", 10, Expire(time.Hour)) {
+		t.Errorf("SetIfPresent should have stored existing key '1'")
+	}
+
+	time.Sleep(time.Millisecond * 2)
+
+	if result, expected := c.Get("1"), 10; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Entry for key '1' was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestSetXX(t *testing.T) {
+	c := New()
+	c.Set("1", 1, Expire(time.Millisecond))
+	c.Set("2", 2, Expire(time.Hour))
+
+	if c.SetXX("3", 3) {
+		t.Errorf("SetXX should not have stored missing key '3'")
+	}
+
+	if !c.SetXX("1", 10, Expire(time.Hour)) || !c.SetXX("2", 20) {
+		t.Errorf("SetXX should have stored existing keys '1' and '2'")
+	}
+
+	time.Sleep(time.Millisecond * 2)
+
+	expected := map[string]T{"1": 10, "2": 20}
+	if result := c.Items(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if _, ok := c.RemainingTTL("1"); !ok {
+		t.Errorf("Entry for key '1' should have a new expiry")
+	}
+
+	if _, ok := c.RemainingTTL("2"); ok {
+		t.Errorf("Entry for key '2' should have had its expiry cleared")
+	}
+}
+
+func TestGetAndSet(t *testing.T) {
+	c := New()
 
-	if _, exists := c.GetOK("1"); !exists {
-		t.Errorf("Entry for key '1' should not have expired yet")
+	if _, existed := c.GetAndSet("1", 1); existed {
+		t.Errorf("Entry for key '1' should not have existed")
 	}
 
-	time.Sleep(time.Millisecond * 2)
+	old, existed := c.GetAndSet("1", 2)
+	if !existed {
+		t.Errorf("Entry for key '1' should have existed")
+	}
 
-	if _, exists := c.GetOK("1"); exists {
-		t.Errorf("Entry for key '1' should have expired by now")
+	if expected := 1; !reflect.DeepEqual(old, expected) {
+		t.Errorf("Previous entry for key '1' was %#v, expected %#v", old, expected)
+	}
+
+	if result, expected := c.Get("1"), 2; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Entry for key '1' was %#v, expected %#v", result, expected)
 	}
 }
 
-func TestSetAfterFunc(t *testing.T) {
+func TestCompareAndSwap(t *testing.T) {
 	c := New()
-	c.Set("1", 1, AfterFunc(time.Millisecond, func(val T) {
-		t.Log("after func executed for ", val)
-	}))
+	c.Set("1", 1)
 
-	if _, exists := c.GetOK("1"); !exists {
-		t.Errorf("Entry for key '1' should not have expired yet")
+	if c.CompareAndSwap("1", 2, 3) {
+		t.Errorf("CompareAndSwap should have failed for mismatched value")
 	}
 
-	time.Sleep(time.Millisecond * 2)
+	if c.CompareAndSwap("2", nil, 3) {
+		t.Errorf("CompareAndSwap should have failed for missing key '2'")
+	}
 
-	if _, exists := c.GetOK("1"); exists {
-		t.Errorf("Entry for key '1' should have expired by now")
+	if !c.CompareAndSwap("1", 1, 3) {
+		t.Errorf("CompareAndSwap should have succeeded for matching value")
+	}
+
+	if result, expected := c.Get("1"), 3; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Entry for key '1' was %#v, expected %#v", result, expected)
 	}
 }
 
-func TestClear(t *testing.T) {
+func TestItems(t *testing.T) {
 	c := New()
-	for i := 0; i < 10; i++ {
+	for i := 0; i < 5; i++ {
 		c.Set(strconv.Itoa(i), i)
 	}
 
-	c.Clear()
+	expected := map[string]T{
+		"0": 0,
+		"1": 1,
+		"2": 2,
+		"3": 3,
+		"4": 4,
+	}
 
-	if keys := c.Keys(); len(keys) != 0 {
-		t.Errorf("Cache should have been empty, had keys: %v", keys)
+	if result := c.Items(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
 	}
 }
 
-func TestEmpty(t *testing.T) {
+func TestValues(t *testing.T) {
 	c := New()
-	for i := 0; i < 10; i++ {
+	for i := 0; i < 5; i++ {
 		c.Set(strconv.Itoa(i), i)
 	}
-	
-	if c.IsEmpty() {
-		t.Errorf("Cache is not empty, IsEmpty() shall return false")
-	}
 
-	c.Clear()
+	result := c.Values()
+	sort.Slice(result, func(i, j int) bool { return result[i].(int) < result[j].(int) })
 
-	if !c.IsEmpty() {
-		t.Errorf("Cache is empty, IsEmpty() shall return true")
+	expected := []T{0, 1, 2, 3, 4}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
 	}
 }
 
-func TestSize(t *testing.T) {
+func TestForEach(t *testing.T) {
 	c := New()
-	for i := 0; i < 10; i++ {
+	for i := 0; i < 5; i++ {
 		c.Set(strconv.Itoa(i), i)
 	}
-	
-	if c.Size() != 10 {
-		t.Errorf("Cache size is incorrect, Size() shall return 10")
-	}
 
-	c.Clear()
+	result := map[string]T{}
+	c.ForEach(func(key string, val T) {
+		result[key] = val
+	})
 
-	if c.Size() != 0 {
-		t.Errorf("Cache size is incorrect, Size() shall return 0")
+	if expected := c.Items(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
 	}
 }
 
-func TestDelete(t *testing.T) {
+func TestFilterItems(t *testing.T) {
 	c := New()
-	c.Set("1", 1)
-	c.Delete("1")
+	for i := 0; i < 5; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
 
-	if _, exists := c.GetOK("1"); exists {
-		t.Errorf("Entry for key '1' should not exist")
+	expected := map[string]T{"0": 0, "2": 2, "4": 4}
+	result := c.FilterItems(func(key string, val T) bool { return val.(int)%2 == 0 })
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
 	}
 }
 
-func TestClearEvery(t *testing.T) {
+func TestCount(t *testing.T) {
 	c := New()
-	for i := 0; i < 10; i++ {
+	for i := 0; i < 5; i++ {
 		c.Set(strconv.Itoa(i), i)
 	}
 
-	c.ClearEvery(time.Millisecond)
+	if result := c.Count(func(key string, val T) bool { return val.(int)%2 == 0 }); result != 3 {
+		t.Errorf("Count was %d, expected 3", result)
+	}
+}
 
-	if keys := c.Keys(); len(keys) != 10 {
-		t.Errorf("Cache should have had 10 keys, but had keys: %v", keys)
+func TestKeys(t *testing.T) {
+	// keys sort by their bytes, so "10" comes before "2", shorter prefixes come first
+	// and non-ASCII keys after every ASCII one
+	tests := []struct {
+		name     string
+		keys     []string
+		expected []string
+	}{
+		{"numbers", []string{"2", "10", "1", "20", "3"}, []string{"1", "10", "2", "20", "3"}},
+		{"unicode", []string{"ñandú", "日本語", "a", "Ω", "😀"}, []string{"a", "ñandú", "Ω", "日本語", "😀"}},
+		{"empty", []string{"a", ""}, []string{"", "a"}},
+		{
+			"mixed",
+			[]string{"日本", "b", "10", "é", "ab", "Z", "", "9", "z", "a", "e\u0301"},
+			[]string{"", "10", "9", "Z", "a", "ab", "b", "e\u0301", "z", "é", "日本"},
+		},
 	}
 
-	time.Sleep(time.Millisecond * 2)
+	for _, test := range tests {
+		c := New()
+		for _, key := range test.keys {
+			c.Set(key, key)
+		}
 
-	if keys := c.Keys(); len(keys) != 0 {
-		t.Errorf("Cache should have been empty, had keys: %v", keys)
+		if result := c.Keys(); !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("%s: Result was %#v, expected %#v", test.name, result, test.expected)
+		}
+
+		for _, key := range test.keys {
+			if result := c.Get(key); result != T(key) {
+				t.Errorf("%s: Entry for key %q was %#v, expected %#v", test.name, key, result, key)
+			}
+		}
+	}
+
+	ints := NewCache[int, T]()
+	for _, key := range []int{10, -1, 2, 0, 100} {
+		ints.Set(key, 1)
+	}
+
+	if result, expected := ints.Keys(), []int{-1, 0, 2, 10, 100}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
 	}
 }
 
-func TestGet(t *testing.T) {
+
+func TestKeysPathSeparators(t *testing.T) {
 	c := New()
-	c.Set("1", 1)
+	for _, key := range []string{"a/b/c", "a\\b", "a/b", "a", "a.b", "/a"} {
+		c.Set(key, 1)
+	}
 
-	if result, expected := c.Get("1"), 1; !reflect.DeepEqual(result, expected) {
-		t.Errorf("Result for entry '1' was %#v, expected %#v", result, expected)
+	expected := []string{"/a", "a", "a.b", "a/b", "a/b/c", "a\\b"}
+	if result := c.Keys(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
 	}
 
-	if result := c.Get("2"); result != nil {
-		t.Errorf("Result for entry '2' was %#v, expected nil", result)
+	if result, expected := c.DeletePrefix("a/"), 2; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	expected = []string{"/a", "a", "a.b", "a\\b"}
+	if result := c.Keys(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
 	}
 }
 
-func TestGetOK(t *testing.T) {
+func TestKeysExpiringSoon(t *testing.T) {
 	c := New()
-	c.Set("1", 1)
+	c.Set("1", 1, Expire(time.Minute))
+	c.Set("2", 2, Expire(time.Hour))
+	c.Set("3", 3, Expire(time.Second))
+	c.Set("4", 4)
 
-	result, exists := c.GetOK("1")
-	if !exists {
-		t.Error("Entry for key '1' should exist")
+	if result, expected := c.KeysExpiringSoon(time.Minute*2), []string{"1", "3"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
 	}
 
-	if expected := 1; !reflect.DeepEqual(result, expected) {
-		t.Errorf("Entry for key '1' was %#v, expected %#v", result, expected)
+	if result, expected := c.KeysExpiringSoon(0), []string{}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
 	}
 
-	if _, exists := c.GetOK("2"); exists {
-		t.Errorf("Entry for key '2' should not exist")
+	s := NewSharded(WithShards(4))
+	for i := 0; i < 10; i++ {
+		s.Set(strconv.Itoa(i), i, Expire(time.Minute*time.Duration(i+1)))
+	}
+
+	if result, expected := s.KeysExpiringSoon(time.Minute*3), []string{"0", "1", "2"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
 	}
 }
 
-func TestItems(t *testing.T) {
+func TestExpiredKeys(t *testing.T) {
 	c := New()
-	for i := 0; i < 5; i++ {
-		c.Set(strconv.Itoa(i), i)
+	c.Set("1", 1, Expire(time.Millisecond))
+	c.Set("2", 2, Expire(time.Hour))
+
+	time.Sleep(time.Millisecond * 5)
+
+	if result, expected := c.ExpiredKeys(), []string{}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
 	}
 
-	expected := map[string]T{
-		"0": 0,
-		"1": 1,
-		"2": 2,
-		"3": 3,
-		"4": 4,
+	lazy := NewWithOptions(WithCleanupInterval(time.Hour))
+	defer lazy.Close()
+	lazy.Set("1", 1, Expire(time.Millisecond))
+	lazy.Set("2", 2, Expire(time.Hour))
+	lazy.Set("3", 3)
+
+	time.Sleep(time.Millisecond * 2)
+
+	if result, expected := lazy.ExpiredKeys(), []string{"1"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
 	}
 
-	if result := c.Items(); !reflect.DeepEqual(result, expected) {
+	lazy.ClearExpired()
+	if result, expected := lazy.ExpiredKeys(), []string{}; !reflect.DeepEqual(result, expected) {
 		t.Errorf("Result was %#v, expected %#v", result, expected)
 	}
 }
 
-func TestKeys(t *testing.T) {
+func TestUnsortedKeys(t *testing.T) {
 	c := New()
 	for i := 0; i < 5; i++ {
 		c.Set(strconv.Itoa(i), i)
 	}
 
+	result := c.UnsortedKeys()
+	sort.Strings(result)
+
 	expected := []string{"0", "1", "2", "3", "4"}
-	if result := c.Keys(); !reflect.DeepEqual(result, expected) {
+	if !reflect.DeepEqual(result, expected) {
 		t.Errorf("Result was %#v, expected %#v", result, expected)
 	}
 }
 
+func TestFilterKeys(t *testing.T) {
+	c := New()
+	c.Set("user:2", 2)
+	c.Set("user:1", 1)
+	c.Set("session:1", 3)
+
+	expected := []string{"user:1", "user:2"}
+	result := c.FilterKeys(func(key string) bool { return strings.HasPrefix(key, "user:") })
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if result := c.FilterKeys(func(string) bool { return false }); len(result) != 0 {
+		t.Errorf("Result was %#v, expected no keys", result)
+	}
+}
+
 func TestStressConcurrentAccess(t *testing.T) {
 	c := New()
+	defer c.Close()
 	c.ClearEvery(time.Nanosecond * 10)
 
 	done := make(chan bool)
@@ -196,7 +1668,7 @@ func TestStressConcurrentAccess(t *testing.T) {
 		go func() {
 			key := strconv.Itoa(rand.Int())
 
-			switch rand.Intn(8) {
+			switch rand.Intn(10) {
 			case 0:
 				c.Set(key, rand.Int())
 			case 1:
@@ -213,6 +1685,10 @@ func TestStressConcurrentAccess(t *testing.T) {
 				c.Items()
 			case 7:
 				c.Keys()
+			case 8:
+				c.Set(key, rand.Int(), AfterFunc(time.Nanosecond*5, func(T) {}))
+			case 9:
+				c.GetAndSet(key, rand.Int(), Expire(time.Nanosecond*5))
 			}
 
 			done <- true
@@ -222,6 +1698,44 @@ func TestStressConcurrentAccess(t *testing.T) {
 	for i := 0; i < 1000; i++ {
 		<-done
 	}
+
+	// a Set racing another Set with an expiry must never leave that expiry attached to its own value
+	d := New()
+	defer d.Close()
+
+	var wg sync.WaitGroup
+	start := make(chan bool)
+	for i := 0; i < 1000; i++ {
+		key := strconv.Itoa(i)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			<-start
+			d.Set(key, "expiring", Expire(time.Millisecond*10))
+		}()
+		go func() {
+			defer wg.Done()
+			<-start
+			d.Set(key, "kept")
+		}()
+	}
+
+	close(start)
+	wg.Wait()
+
+	var kept []string
+	for i := 0; i < 1000; i++ {
+		if v, ok := d.GetOK(strconv.Itoa(i)); ok && v == "kept" {
+			kept = append(kept, strconv.Itoa(i))
+		}
+	}
+
+	time.Sleep(time.Millisecond * 20)
+	for _, key := range kept {
+		if _, ok := d.GetOK(key); !ok {
+			t.Errorf("Entry for key '%s' was removed by the expiry of the value it replaced", key)
+		}
+	}
 }
 
 func benchmarkSet(count int, b *testing.B) {
@@ -240,6 +1754,44 @@ func BenchmarkSet100(b *testing.B)   { benchmarkSet(100, b) }
 func BenchmarkSet1000(b *testing.B)  { benchmarkSet(1000, b) }
 func BenchmarkSet10000(b *testing.B) { benchmarkSet(10000, b) }
 
+func benchmarkSetMany(count int, b *testing.B) {
+	c := New()
+	entries := map[string]T{}
+	for i := 0; i < count; i++ {
+		entries[strconv.Itoa(i)] = i
+	}
+
+	for n := 0; n < b.N; n++ {
+		c.SetMany(entries)
+	}
+}
+
+func BenchmarkSetMany1(b *testing.B)     { benchmarkSetMany(1, b) }
+func BenchmarkSetMany10(b *testing.B)    { benchmarkSetMany(10, b) }
+func BenchmarkSetMany100(b *testing.B)   { benchmarkSetMany(100, b) }
+func BenchmarkSetMany1000(b *testing.B)  { benchmarkSetMany(1000, b) }
+func BenchmarkSetMany10000(b *testing.B) { benchmarkSetMany(10000, b) }
+
+func benchmarkGetMany(count int, b *testing.B) {
+	c := New()
+	keys := make([]string, count)
+	for i := 0; i < count; i++ {
+		keys[i] = strconv.Itoa(i)
+		c.Set(keys[i], i)
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		c.GetMany(keys)
+	}
+}
+
+func BenchmarkGetMany1(b *testing.B)     { benchmarkGetMany(1, b) }
+func BenchmarkGetMany10(b *testing.B)    { benchmarkGetMany(10, b) }
+func BenchmarkGetMany100(b *testing.B)   { benchmarkGetMany(100, b) }
+func BenchmarkGetMany1000(b *testing.B)  { benchmarkGetMany(1000, b) }
+func BenchmarkGetMany10000(b *testing.B) { benchmarkGetMany(10000, b) }
+
 func benchmarkDelete(count int, b *testing.B) {
 	c := New()
 
@@ -256,6 +1808,83 @@ func BenchmarkDelete100(b *testing.B)   { benchmarkDelete(100, b) }
 func BenchmarkDelete1000(b *testing.B)  { benchmarkDelete(1000, b) }
 func BenchmarkDelete10000(b *testing.B) { benchmarkDelete(10000, b) }
 
+func benchmarkDeleteMany(count int, b *testing.B) {
+	c := New()
+	keys := make([]string, count)
+	for i := 0; i < count; i++ {
+		keys[i] = strconv.Itoa(i)
+	}
+
+	for n := 0; n < b.N; n++ {
+		c.DeleteMany(keys)
+	}
+}
+
+func BenchmarkDeleteMany1(b *testing.B)     { benchmarkDeleteMany(1, b) }
+func BenchmarkDeleteMany10(b *testing.B)    { benchmarkDeleteMany(10, b) }
+func BenchmarkDeleteMany100(b *testing.B)   { benchmarkDeleteMany(100, b) }
+func BenchmarkDeleteMany1000(b *testing.B)  { benchmarkDeleteMany(1000, b) }
+func BenchmarkDeleteMany10000(b *testing.B) { benchmarkDeleteMany(10000, b) }
+
+func benchmarkDeletePrefix(count int, multiCall bool, b *testing.B) {
+	c := New()
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		for i := 0; i < count; i++ {
+			c.Set("a:"+strconv.Itoa(i), i)
+			c.Set("b:"+strconv.Itoa(i), i)
+		}
+
+		b.StartTimer()
+		if multiCall {
+			c.DeleteMany(c.FilterKeys(func(key string) bool { return strings.HasPrefix(key, "a:") }))
+		} else {
+			c.DeletePrefix("a:")
+		}
+	}
+}
+
+func BenchmarkDeletePrefix100(b *testing.B)            { benchmarkDeletePrefix(100, false, b) }
+func BenchmarkDeletePrefix10000(b *testing.B)          { benchmarkDeletePrefix(10000, false, b) }
+func BenchmarkDeletePrefixMultiCall100(b *testing.B)   { benchmarkDeletePrefix(100, true, b) }
+func BenchmarkDeletePrefixMultiCall10000(b *testing.B) { benchmarkDeletePrefix(10000, true, b) }
+
+func benchmarkDeleteSuffix(count int, b *testing.B) {
+	c := New()
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		for i := 0; i < count; i++ {
+			c.Set(strconv.Itoa(i)+":v1", i)
+			c.Set(strconv.Itoa(i)+":v2", i)
+		}
+
+		b.StartTimer()
+		c.DeleteSuffix(":v1")
+	}
+}
+
+func BenchmarkDeleteSuffix100(b *testing.B)    { benchmarkDeleteSuffix(100, b) }
+func BenchmarkDeleteSuffix10000(b *testing.B)  { benchmarkDeleteSuffix(10000, b) }
+func BenchmarkDeleteSuffix100000(b *testing.B) { benchmarkDeleteSuffix(100000, b) }
+
+func benchmarkDeleteContains(count int, b *testing.B) {
+	c := New()
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		for i := 0; i < count; i++ {
+			c.Set("a:"+strconv.Itoa(i)+":tmp", i)
+			c.Set("a:"+strconv.Itoa(i), i)
+		}
+
+		b.StartTimer()
+		c.DeleteContains(":tmp")
+	}
+}
+
+func BenchmarkDeleteContains100(b *testing.B)    { benchmarkDeleteContains(100, b) }
+func BenchmarkDeleteContains10000(b *testing.B)  { benchmarkDeleteContains(10000, b) }
+func BenchmarkDeleteContains100000(b *testing.B) { benchmarkDeleteContains(100000, b) }
+
 func benchmarkGet(count int, b *testing.B) {
 	c := New()
 
@@ -271,3 +1900,76 @@ func BenchmarkGet10(b *testing.B)    { benchmarkGet(10, b) }
 func BenchmarkGet100(b *testing.B)   { benchmarkGet(100, b) }
 func BenchmarkGet1000(b *testing.B)  { benchmarkGet(1000, b) }
 func BenchmarkGet10000(b *testing.B) { benchmarkGet(10000, b) }
+
+func BenchmarkGetParallel(b *testing.B) {
+	c := New()
+	for i := 0; i < 1000; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			c.Get(strconv.Itoa(i % 1000))
+			i++
+		}
+	})
+}
+
+func benchmarkKeys(count int, sorted bool, b *testing.B) {
+	c := New()
+	for i := 0; i < count; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if sorted {
+			c.Keys()
+		} else {
+			c.UnsortedKeys()
+		}
+	}
+}
+
+func BenchmarkKeys100000(b *testing.B)         { benchmarkKeys(100000, true, b) }
+func BenchmarkUnsortedKeys100000(b *testing.B) { benchmarkKeys(100000, false, b) }
+
+func benchmarkValues(count int, b *testing.B) {
+	c := New()
+	for i := 0; i < count; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		c.Values()
+	}
+}
+
+func BenchmarkValues1(b *testing.B)     { benchmarkValues(1, b) }
+func BenchmarkValues10(b *testing.B)    { benchmarkValues(10, b) }
+func BenchmarkValues100(b *testing.B)   { benchmarkValues(100, b) }
+func BenchmarkValues1000(b *testing.B)  { benchmarkValues(1000, b) }
+func BenchmarkValues10000(b *testing.B) { benchmarkValues(10000, b) }
+
+func benchmarkWarm(count int, capacity bool, b *testing.B) {
+	entries := map[string]T{}
+	for i := 0; i < count; i++ {
+		entries[strconv.Itoa(i)] = i
+	}
+
+	var options []CacheOption
+	if capacity {
+		options = append(options, WithInitialCapacity(count))
+	}
+
+	for n := 0; n < b.N; n++ {
+		c := NewWithOptions(options...)
+		c.SetMany(entries)
+	}
+}
+
+func BenchmarkWarm100000(b *testing.B)                    { benchmarkWarm(100000, false, b) }
+func BenchmarkWarmWithInitialCapacity100000(b *testing.B) { benchmarkWarm(100000, true, b) }
@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// A Cacher is implemented by caches holding values of type V at keys of type K.
+// Code that depends on a Cacher rather than a *Cache can be handed a ShardedCache, a no-op cache from NewNoop,
+// or a test double instead. Clone and Merge are left out, since they operate on the concrete cache type
+type Cacher[K comparable, V any] interface {
+	CacheMetrics
+	ResetStats()
+
+	Set(key K, val V, options ...SetOption)
+	SetE(key K, val V, options ...SetOption) error
+	SetMany(entries map[K]V, options ...SetOption)
+	MSet(entries map[K]V) error
+	SetBatch(entries []BatchEntry[K, V])
+	SetCtx(ctx context.Context, key K, val V, options ...SetOption) error
+	SetWithTags(key K, val V, tags []string, options ...SetOption)
+	GetOrSet(key K, fn func() V, options ...SetOption) V
+	GetOrLoad(key K, loader func() (V, time.Duration, error)) (V, error)
+	GetOrSetMany(keys []K, loader func(keys []K) map[K]V, options ...SetOption) map[K]V
+	SetIfAbsent(key K, val V, options ...SetOption) bool
+	SetDefault(key K, val V, options ...SetOption) bool
+	SetNX(key K, val V, options ...SetOption) bool
+	SetIfPresent(key K, val V, options ...SetOption) bool
+	SetXX(key K, val V, options ...SetOption) bool
+	GetAndSet(key K, val V, options ...SetOption) (V, bool)
+	CompareAndSwap(key K, oldVal, newVal V, options ...SetOption) bool
+	Increment(key K, delta int64) (int64, error)
+	Decrement(key K, delta int64) (int64, error)
+	Append(key K, suffix string) (string, error)
+	Modify(key K, fn func(current V) V) bool
+
+	Touch(key K, d time.Duration) bool
+	ExpireAt(key K, t time.Time) bool
+	Expire(key K, d time.Duration) bool
+	Persist(key K) bool
+	Rename(oldKey, newKey K) bool
+	RemainingTTL(key K) (time.Duration, bool)
+	TTL(key K) (time.Duration, error)
+
+	Clear()
+	ClearExpired() int
+	ClearEvery(d time.Duration) (stop func())
+	Delete(key K)
+	DeleteCtx(ctx context.Context, key K) error
+	DeleteMany(keys []K) int
+	DeletePrefix(prefix string) int
+	DeleteSuffix(suffix string) int
+	DeleteContains(substr string) int
+	DeleteByTag(tag string) int
+	GetAndDelete(key K) (V, bool)
+	Evict(key K) (V, bool)
+	CompareAndDelete(key K, expected V) bool
+
+	Get(key K) V
+	GetOK(key K) (V, bool)
+	Contains(key K) bool
+	Probabilistic(key K, beta float64) (V, bool)
+	GetCtx(ctx context.Context, key K) (V, error)
+	GetMany(keys []K) map[K]V
+	MGet(keys []K) (map[K]V, error)
+	WaitForKey(ctx context.Context, key K) (V, error)
+	Watch(key K) (<-chan WatchEvent[K, V], func())
+	WatchPrefix(prefix string) (<-chan WatchEvent[K, V], func())
+	Items() map[K]V
+	Dump() map[K]CacheEntry[V]
+	Values() []V
+	ForEach(fn func(key K, val V))
+	FilterItems(predicate func(K, V) bool) map[K]V
+	Count(predicate func(K, V) bool) int
+	IsEmpty() bool
+	Size() int
+	Keys() []K
+	UnsortedKeys() []K
+	KeysExpiringSoon(within time.Duration) []K
+	ExpiredKeys() []K
+	Scan(cursor, count int) (int, []K)
+	FilterKeys(predicate func(K) bool) []K
+
+	ExportJSON(w io.Writer) error
+	ImportJSON(r io.Reader) error
+	ExportGob(w io.Writer) error
+	ImportGob(r io.Reader) error
+	SaveToFile(path string) error
+	LoadFromFile(path string) error
+	Snapshot() ([]byte, error)
+	Restore(data []byte) error
+
+	Close() error
+}
+
+// A StringCacher is a Cacher with string keys and values of any type, as implemented by a StringCache
+type StringCacher = Cacher[string, T]
+
+var (
+	_ Cacher[string, T] = (*Cache[string, T])(nil)
+	_ Cacher[string, T] = (*ShardedCache[string, T])(nil)
+	_ Cacher[string, T] = noopCache[string, T]{}
+	_ Cacher[string, T] = (*TwoLevelCache[string, T])(nil)
+)
@@ -0,0 +1,761 @@
+// Package cachetest provides test doubles for code that depends on a cache.Cacher
+package cachetest
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	cache "github.com/robotsrulz/go-cache"
+)
+
+// A Call records a method called on a MockCache.
+// Key and Value are the zero values for methods that take no key or no value.
+// Methods that take several keys or entries, such as GetMany and SetMany, record a Call for each one
+type Call[K comparable, V any] struct {
+	Method string
+	Key    K
+	Value  V
+}
+
+// A MockCache is a cache.Cacher that records every call made to it, and returns the results programmed with On.
+// Calls that have not been programmed behave as on a cache created by cache.NewNoopCache, which is always empty.
+// It is safe for concurrent use
+type MockCache[K comparable, V any] struct {
+	mu           sync.Mutex
+	calls        []Call[K, V]
+	expectations []*Expectation[K]
+	noop         cache.Cacher[K, V]
+}
+
+// NewMockCache returns a MockCache with no calls recorded and no results programmed
+func NewMockCache[K comparable, V any]() *MockCache[K, V] {
+	return &MockCache[K, V]{noop: cache.NewNoopCache[K, V]()}
+}
+
+// NewMock returns a MockCache with string keys and values of any type, standing in for a cache.StringCache
+func NewMock() *MockCache[string, cache.T] {
+	return NewMockCache[string, cache.T]()
+}
+
+// An Expectation holds the results programmed for calls to a method, as returned by MockCache.On
+type Expectation[K comparable] struct {
+	method  string
+	key     K
+	anyKey  bool
+	results []any
+}
+
+// Return sets the results returned by the calls the expectation matches, in the order the method returns them.
+// A result that is nil or of the wrong type is returned as the zero value
+func (e *Expectation[K]) Return(results ...any) *Expectation[K] {
+	e.results = results
+	return e
+}
+
+// On programs the results of calls to the named method at the specified key, which are set by Return.
+// Without a key, it matches calls at any key, as well as calls to methods that take no key.
+// When several expectations match a call, the one added last wins
+func (m *MockCache[K, V]) On(method string, key ...K) *Expectation[K] {
+	e := &Expectation[K]{method: method, anyKey: len(key) == 0}
+	if len(key) > 0 {
+		e.key = key[0]
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expectations = append(m.expectations, e)
+	return e
+}
+
+// Calls returns the calls made to the mock so far, in the order they were made
+func (m *MockCache[K, V]) Calls() []Call[K, V] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Call[K, V](nil), m.calls...)
+}
+
+// CallsTo returns the calls made to the named method so far, in the order they were made
+func (m *MockCache[K, V]) CallsTo(method string) []Call[K, V] {
+	var calls []Call[K, V]
+	for _, c := range m.Calls() {
+		if c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+
+	return calls
+}
+
+// Reset forgets the recorded calls and the programmed results
+func (m *MockCache[K, V]) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = nil
+	m.expectations = nil
+}
+
+// record records a call, and returns the results programmed for it, or nil if there are none
+func (m *MockCache[K, V]) record(method string, key K, val V) []any {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, Call[K, V]{Method: method, Key: key, Value: val})
+	return m.results(method, key, true)
+}
+
+// results returns the results programmed for a call, or nil if there are none.
+// If hasKey is false, only expectations for any key match.
+// It must only be called with mu held
+func (m *MockCache[K, V]) results(method string, key K, hasKey bool) []any {
+	for i := len(m.expectations) - 1; i >= 0; i-- {
+		e := m.expectations[i]
+		if e.method == method && (e.anyKey || hasKey && e.key == key) && e.results != nil {
+			return e.results
+		}
+	}
+
+	return nil
+}
+
+// recordEach records a call for each key, and returns the results programmed for the method at any key
+func (m *MockCache[K, V]) recordEach(method string, keys []K) []any {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range keys {
+		m.calls = append(m.calls, Call[K, V]{Method: method, Key: key})
+	}
+
+	return m.results(method, *new(K), false)
+}
+
+// recordNone records a call that takes no key, and returns the results programmed for it
+func (m *MockCache[K, V]) recordNone(method string) []any {
+	var key K
+	var val V
+	return m.record(method, key, val)
+}
+
+// result returns results[i] as an X, or the zero X if it is missing, nil or of another type
+func result[X any](results []any, i int) X {
+	var x X
+	if i < len(results) {
+		x, _ = results[i].(X)
+	}
+
+	return x
+}
+
+func (m *MockCache[K, V]) Stats() cache.CacheStats {
+	if r := m.recordNone("Stats"); r != nil {
+		return result[cache.CacheStats](r, 0)
+	}
+
+	return m.noop.Stats()
+}
+
+func (m *MockCache[K, V]) ResetStats() {
+	m.recordNone("ResetStats")
+}
+
+func (m *MockCache[K, V]) Set(key K, val V, options ...cache.SetOption) {
+	m.record("Set", key, val)
+}
+
+func (m *MockCache[K, V]) SetMany(entries map[K]V, options ...cache.SetOption) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, val := range entries {
+		m.calls = append(m.calls, Call[K, V]{Method: "SetMany", Key: key, Value: val})
+	}
+}
+
+func (m *MockCache[K, V]) MSet(entries map[K]V) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, val := range entries {
+		m.calls = append(m.calls, Call[K, V]{Method: "MSet", Key: key, Value: val})
+	}
+
+	return result[error](m.results("MSet", *new(K), false), 0)
+}
+
+func (m *MockCache[K, V]) SetBatch(entries []cache.BatchEntry[K, V]) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range entries {
+		m.calls = append(m.calls, Call[K, V]{Method: "SetBatch", Key: e.Key, Value: e.Value})
+	}
+}
+
+func (m *MockCache[K, V]) SetE(key K, val V, options ...cache.SetOption) error {
+	if r := m.record("SetE", key, val); r != nil {
+		return result[error](r, 0)
+	}
+
+	return m.noop.SetE(key, val, options...)
+}
+
+func (m *MockCache[K, V]) SetCtx(ctx context.Context, key K, val V, options ...cache.SetOption) error {
+	if r := m.record("SetCtx", key, val); r != nil {
+		return result[error](r, 0)
+	}
+
+	return m.noop.SetCtx(ctx, key, val, options...)
+}
+
+func (m *MockCache[K, V]) SetWithTags(key K, val V, tags []string, options ...cache.SetOption) {
+	m.record("SetWithTags", key, val)
+}
+
+func (m *MockCache[K, V]) GetOrSet(key K, fn func() V, options ...cache.SetOption) V {
+	var zero V
+	if r := m.record("GetOrSet", key, zero); r != nil {
+		return result[V](r, 0)
+	}
+
+	return m.noop.GetOrSet(key, fn, options...)
+}
+
+func (m *MockCache[K, V]) GetOrLoad(key K, loader func() (V, time.Duration, error)) (V, error) {
+	var zero V
+	if r := m.record("GetOrLoad", key, zero); r != nil {
+		return result[V](r, 0), result[error](r, 1)
+	}
+
+	return m.noop.GetOrLoad(key, loader)
+}
+
+func (m *MockCache[K, V]) GetOrSetMany(keys []K, loader func(keys []K) map[K]V, options ...cache.SetOption) map[K]V {
+	if r := m.recordEach("GetOrSetMany", keys); r != nil {
+		return result[map[K]V](r, 0)
+	}
+
+	return m.noop.GetOrSetMany(keys, loader, options...)
+}
+
+func (m *MockCache[K, V]) SetIfAbsent(key K, val V, options ...cache.SetOption) bool {
+	if r := m.record("SetIfAbsent", key, val); r != nil {
+		return result[bool](r, 0)
+	}
+
+	return m.noop.SetIfAbsent(key, val, options...)
+}
+
+func (m *MockCache[K, V]) SetDefault(key K, val V, options ...cache.SetOption) bool {
+	if r := m.record("SetDefault", key, val); r != nil {
+		return result[bool](r, 0)
+	}
+
+	return m.noop.SetDefault(key, val, options...)
+}
+
+func (m *MockCache[K, V]) SetNX(key K, val V, options ...cache.SetOption) bool {
+	if r := m.record("SetNX", key, val); r != nil {
+		return result[bool](r, 0)
+	}
+
+	return m.noop.SetNX(key, val, options...)
+}
+
+func (m *MockCache[K, V]) SetIfPresent(key K, val V, options ...cache.SetOption) bool {
+	if r := m.record("SetIfPresent", key, val); r != nil {
+		return result[bool](r, 0)
+	}
+
+	return m.noop.SetIfPresent(key, val, options...)
+}
+
+func (m *MockCache[K, V]) SetXX(key K, val V, options ...cache.SetOption) bool {
+	if r := m.record("SetXX", key, val); r != nil {
+		return result[bool](r, 0)
+	}
+
+	return m.noop.SetXX(key, val, options...)
+}
+
+func (m *MockCache[K, V]) GetAndSet(key K, val V, options ...cache.SetOption) (V, bool) {
+	if r := m.record("GetAndSet", key, val); r != nil {
+		return result[V](r, 0), result[bool](r, 1)
+	}
+
+	return m.noop.GetAndSet(key, val, options...)
+}
+
+func (m *MockCache[K, V]) CompareAndSwap(key K, oldVal, newVal V, options ...cache.SetOption) bool {
+	if r := m.record("CompareAndSwap", key, newVal); r != nil {
+		return result[bool](r, 0)
+	}
+
+	return m.noop.CompareAndSwap(key, oldVal, newVal, options...)
+}
+
+func (m *MockCache[K, V]) Increment(key K, delta int64) (int64, error) {
+	var zero V
+	if r := m.record("Increment", key, zero); r != nil {
+		return result[int64](r, 0), result[error](r, 1)
+	}
+
+	return m.noop.Increment(key, delta)
+}
+
+func (m *MockCache[K, V]) Decrement(key K, delta int64) (int64, error) {
+	var zero V
+	if r := m.record("Decrement", key, zero); r != nil {
+		return result[int64](r, 0), result[error](r, 1)
+	}
+
+	return m.noop.Decrement(key, delta)
+}
+
+func (m *MockCache[K, V]) Append(key K, suffix string) (string, error) {
+	var zero V
+	if r := m.record("Append", key, zero); r != nil {
+		return result[string](r, 0), result[error](r, 1)
+	}
+
+	return m.noop.Append(key, suffix)
+}
+
+func (m *MockCache[K, V]) Modify(key K, fn func(current V) V) bool {
+	var zero V
+	if r := m.record("Modify", key, zero); r != nil {
+		return result[bool](r, 0)
+	}
+
+	return m.noop.Modify(key, fn)
+}
+
+func (m *MockCache[K, V]) Touch(key K, d time.Duration) bool {
+	var zero V
+	if r := m.record("Touch", key, zero); r != nil {
+		return result[bool](r, 0)
+	}
+
+	return m.noop.Touch(key, d)
+}
+
+func (m *MockCache[K, V]) ExpireAt(key K, t time.Time) bool {
+	var zero V
+	if r := m.record("ExpireAt", key, zero); r != nil {
+		return result[bool](r, 0)
+	}
+
+	return m.noop.ExpireAt(key, t)
+}
+
+func (m *MockCache[K, V]) Expire(key K, d time.Duration) bool {
+	var zero V
+	if r := m.record("Expire", key, zero); r != nil {
+		return result[bool](r, 0)
+	}
+
+	return m.noop.Expire(key, d)
+}
+
+func (m *MockCache[K, V]) Persist(key K) bool {
+	var zero V
+	if r := m.record("Persist", key, zero); r != nil {
+		return result[bool](r, 0)
+	}
+
+	return m.noop.Persist(key)
+}
+
+// Rename records a call at oldKey
+func (m *MockCache[K, V]) Rename(oldKey, newKey K) bool {
+	var zero V
+	if r := m.record("Rename", oldKey, zero); r != nil {
+		return result[bool](r, 0)
+	}
+
+	return m.noop.Rename(oldKey, newKey)
+}
+
+func (m *MockCache[K, V]) RemainingTTL(key K) (time.Duration, bool) {
+	var zero V
+	if r := m.record("RemainingTTL", key, zero); r != nil {
+		return result[time.Duration](r, 0), result[bool](r, 1)
+	}
+
+	return m.noop.RemainingTTL(key)
+}
+
+func (m *MockCache[K, V]) TTL(key K) (time.Duration, error) {
+	var zero V
+	if r := m.record("TTL", key, zero); r != nil {
+		return result[time.Duration](r, 0), result[error](r, 1)
+	}
+
+	return m.noop.TTL(key)
+}
+
+func (m *MockCache[K, V]) Clear() {
+	m.recordNone("Clear")
+}
+
+func (m *MockCache[K, V]) ClearEvery(d time.Duration) (stop func()) {
+	m.recordNone("ClearEvery")
+	return m.noop.ClearEvery(d)
+}
+
+func (m *MockCache[K, V]) Delete(key K) {
+	var zero V
+	m.record("Delete", key, zero)
+}
+
+func (m *MockCache[K, V]) DeleteCtx(ctx context.Context, key K) error {
+	var zero V
+	if r := m.record("DeleteCtx", key, zero); r != nil {
+		return result[error](r, 0)
+	}
+
+	return m.noop.DeleteCtx(ctx, key)
+}
+
+func (m *MockCache[K, V]) DeleteMany(keys []K) int {
+	if r := m.recordEach("DeleteMany", keys); r != nil {
+		return result[int](r, 0)
+	}
+
+	return m.noop.DeleteMany(keys)
+}
+
+func (m *MockCache[K, V]) DeletePrefix(prefix string) int {
+	if r := m.recordNone("DeletePrefix"); r != nil {
+		return result[int](r, 0)
+	}
+
+	return m.noop.DeletePrefix(prefix)
+}
+
+func (m *MockCache[K, V]) DeleteSuffix(suffix string) int {
+	if r := m.recordNone("DeleteSuffix"); r != nil {
+		return result[int](r, 0)
+	}
+
+	return m.noop.DeleteSuffix(suffix)
+}
+
+func (m *MockCache[K, V]) DeleteContains(substr string) int {
+	if r := m.recordNone("DeleteContains"); r != nil {
+		return result[int](r, 0)
+	}
+
+	return m.noop.DeleteContains(substr)
+}
+
+func (m *MockCache[K, V]) DeleteByTag(tag string) int {
+	if r := m.recordNone("DeleteByTag"); r != nil {
+		return result[int](r, 0)
+	}
+
+	return m.noop.DeleteByTag(tag)
+}
+
+func (m *MockCache[K, V]) GetAndDelete(key K) (V, bool) {
+	var zero V
+	if r := m.record("GetAndDelete", key, zero); r != nil {
+		return result[V](r, 0), result[bool](r, 1)
+	}
+
+	return m.noop.GetAndDelete(key)
+}
+
+func (m *MockCache[K, V]) Evict(key K) (V, bool) {
+	var zero V
+	if r := m.record("Evict", key, zero); r != nil {
+		return result[V](r, 0), result[bool](r, 1)
+	}
+
+	return m.noop.Evict(key)
+}
+
+func (m *MockCache[K, V]) CompareAndDelete(key K, expected V) bool {
+	if r := m.record("CompareAndDelete", key, expected); r != nil {
+		return result[bool](r, 0)
+	}
+
+	return m.noop.CompareAndDelete(key, expected)
+}
+
+func (m *MockCache[K, V]) Get(key K) V {
+	var zero V
+	if r := m.record("Get", key, zero); r != nil {
+		return result[V](r, 0)
+	}
+
+	return m.noop.Get(key)
+}
+
+func (m *MockCache[K, V]) GetOK(key K) (V, bool) {
+	var zero V
+	if r := m.record("GetOK", key, zero); r != nil {
+		return result[V](r, 0), result[bool](r, 1)
+	}
+
+	return m.noop.GetOK(key)
+}
+
+func (m *MockCache[K, V]) Contains(key K) bool {
+	var zero V
+	if r := m.record("Contains", key, zero); r != nil {
+		return result[bool](r, 0)
+	}
+
+	return m.noop.Contains(key)
+}
+
+func (m *MockCache[K, V]) Probabilistic(key K, beta float64) (V, bool) {
+	var zero V
+	if r := m.record("Probabilistic", key, zero); r != nil {
+		return result[V](r, 0), result[bool](r, 1)
+	}
+
+	return m.noop.Probabilistic(key, beta)
+}
+
+func (m *MockCache[K, V]) GetCtx(ctx context.Context, key K) (V, error) {
+	var zero V
+	if r := m.record("GetCtx", key, zero); r != nil {
+		return result[V](r, 0), result[error](r, 1)
+	}
+
+	return m.noop.GetCtx(ctx, key)
+}
+
+func (m *MockCache[K, V]) GetMany(keys []K) map[K]V {
+	if r := m.recordEach("GetMany", keys); r != nil {
+		return result[map[K]V](r, 0)
+	}
+
+	return m.noop.GetMany(keys)
+}
+
+func (m *MockCache[K, V]) MGet(keys []K) (map[K]V, error) {
+	if r := m.recordEach("MGet", keys); r != nil {
+		return result[map[K]V](r, 0), result[error](r, 1)
+	}
+
+	return m.noop.MGet(keys)
+}
+
+func (m *MockCache[K, V]) WaitForKey(ctx context.Context, key K) (V, error) {
+	var zero V
+	if r := m.record("WaitForKey", key, zero); r != nil {
+		return result[V](r, 0), result[error](r, 1)
+	}
+
+	return m.noop.WaitForKey(ctx, key)
+}
+
+func (m *MockCache[K, V]) Watch(key K) (<-chan cache.WatchEvent[K, V], func()) {
+	var zero V
+	if r := m.record("Watch", key, zero); r != nil {
+		return result[<-chan cache.WatchEvent[K, V]](r, 0), result[func()](r, 1)
+	}
+
+	return m.noop.Watch(key)
+}
+
+func (m *MockCache[K, V]) WatchPrefix(prefix string) (<-chan cache.WatchEvent[K, V], func()) {
+	if r := m.recordNone("WatchPrefix"); r != nil {
+		return result[<-chan cache.WatchEvent[K, V]](r, 0), result[func()](r, 1)
+	}
+
+	return m.noop.WatchPrefix(prefix)
+}
+
+func (m *MockCache[K, V]) ClearExpired() int {
+	if r := m.recordNone("ClearExpired"); r != nil {
+		return result[int](r, 0)
+	}
+
+	return m.noop.ClearExpired()
+}
+
+func (m *MockCache[K, V]) Items() map[K]V {
+	if r := m.recordNone("Items"); r != nil {
+		return result[map[K]V](r, 0)
+	}
+
+	return m.noop.Items()
+}
+
+func (m *MockCache[K, V]) Values() []V {
+	if r := m.recordNone("Values"); r != nil {
+		return result[[]V](r, 0)
+	}
+
+	return m.noop.Values()
+}
+
+// ForEach calls fn for each entry of the map programmed as its result, if any
+func (m *MockCache[K, V]) ForEach(fn func(key K, val V)) {
+	for key, val := range result[map[K]V](m.recordNone("ForEach"), 0) {
+		fn(key, val)
+	}
+}
+
+func (m *MockCache[K, V]) Dump() map[K]cache.CacheEntry[V] {
+	if r := m.recordNone("Dump"); r != nil {
+		return result[map[K]cache.CacheEntry[V]](r, 0)
+	}
+
+	return m.noop.Dump()
+}
+
+func (m *MockCache[K, V]) FilterItems(predicate func(K, V) bool) map[K]V {
+	if r := m.recordNone("FilterItems"); r != nil {
+		return result[map[K]V](r, 0)
+	}
+
+	return m.noop.FilterItems(predicate)
+}
+
+func (m *MockCache[K, V]) Count(predicate func(K, V) bool) int {
+	if r := m.recordNone("Count"); r != nil {
+		return result[int](r, 0)
+	}
+
+	return m.noop.Count(predicate)
+}
+
+func (m *MockCache[K, V]) IsEmpty() bool {
+	if r := m.recordNone("IsEmpty"); r != nil {
+		return result[bool](r, 0)
+	}
+
+	return m.noop.IsEmpty()
+}
+
+func (m *MockCache[K, V]) Size() int {
+	if r := m.recordNone("Size"); r != nil {
+		return result[int](r, 0)
+	}
+
+	return m.noop.Size()
+}
+
+func (m *MockCache[K, V]) Keys() []K {
+	if r := m.recordNone("Keys"); r != nil {
+		return result[[]K](r, 0)
+	}
+
+	return m.noop.Keys()
+}
+
+func (m *MockCache[K, V]) KeysExpiringSoon(within time.Duration) []K {
+	if r := m.recordNone("KeysExpiringSoon"); r != nil {
+		return result[[]K](r, 0)
+	}
+
+	return m.noop.KeysExpiringSoon(within)
+}
+
+func (m *MockCache[K, V]) ExpiredKeys() []K {
+	if r := m.recordNone("ExpiredKeys"); r != nil {
+		return result[[]K](r, 0)
+	}
+
+	return m.noop.ExpiredKeys()
+}
+
+func (m *MockCache[K, V]) UnsortedKeys() []K {
+	if r := m.recordNone("UnsortedKeys"); r != nil {
+		return result[[]K](r, 0)
+	}
+
+	return m.noop.UnsortedKeys()
+}
+
+func (m *MockCache[K, V]) Scan(cursor, count int) (int, []K) {
+	if r := m.recordNone("Scan"); r != nil {
+		return result[int](r, 0), result[[]K](r, 1)
+	}
+
+	return m.noop.Scan(cursor, count)
+}
+
+func (m *MockCache[K, V]) FilterKeys(predicate func(K) bool) []K {
+	if r := m.recordNone("FilterKeys"); r != nil {
+		return result[[]K](r, 0)
+	}
+
+	return m.noop.FilterKeys(predicate)
+}
+
+func (m *MockCache[K, V]) ExportJSON(w io.Writer) error {
+	if r := m.recordNone("ExportJSON"); r != nil {
+		return result[error](r, 0)
+	}
+
+	return m.noop.ExportJSON(w)
+}
+
+func (m *MockCache[K, V]) ImportJSON(r io.Reader) error {
+	if results := m.recordNone("ImportJSON"); results != nil {
+		return result[error](results, 0)
+	}
+
+	return m.noop.ImportJSON(r)
+}
+
+func (m *MockCache[K, V]) ExportGob(w io.Writer) error {
+	if r := m.recordNone("ExportGob"); r != nil {
+		return result[error](r, 0)
+	}
+
+	return m.noop.ExportGob(w)
+}
+
+func (m *MockCache[K, V]) ImportGob(r io.Reader) error {
+	if results := m.recordNone("ImportGob"); results != nil {
+		return result[error](results, 0)
+	}
+
+	return m.noop.ImportGob(r)
+}
+
+// SaveToFile never writes the file
+func (m *MockCache[K, V]) SaveToFile(path string) error {
+	if r := m.recordNone("SaveToFile"); r != nil {
+		return result[error](r, 0)
+	}
+
+	return nil
+}
+
+func (m *MockCache[K, V]) LoadFromFile(path string) error {
+	if r := m.recordNone("LoadFromFile"); r != nil {
+		return result[error](r, 0)
+	}
+
+	return m.noop.LoadFromFile(path)
+}
+
+func (m *MockCache[K, V]) Snapshot() ([]byte, error) {
+	if r := m.recordNone("Snapshot"); r != nil {
+		return result[[]byte](r, 0), result[error](r, 1)
+	}
+
+	return m.noop.Snapshot()
+}
+
+func (m *MockCache[K, V]) Restore(data []byte) error {
+	if r := m.recordNone("Restore"); r != nil {
+		return result[error](r, 0)
+	}
+
+	return m.noop.Restore(data)
+}
+
+func (m *MockCache[K, V]) Close() error {
+	if r := m.recordNone("Close"); r != nil {
+		return result[error](r, 0)
+	}
+
+	return m.noop.Close()
+}
+
+var _ cache.Cacher[string, cache.T] = (*MockCache[string, cache.T])(nil)
@@ -0,0 +1,97 @@
+package cachetest
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	cache "github.com/robotsrulz/go-cache"
+)
+
+func TestMockCache(t *testing.T) {
+	m := NewMock()
+	m.On("Get", "key").Return(1)
+	m.On("GetOK", "key").Return(2, true)
+	m.On("Size").Return(3)
+
+	var c cache.StringCacher = m
+	c.Set("key", "val")
+
+	if result, expected := c.Get("key"), cache.T(1); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if result, ok := c.GetOK("key"); result != 2 || !ok {
+		t.Errorf("Result was %#v, %v, expected 2, true", result, ok)
+	}
+
+	if result, ok := c.GetOK("other"); result != nil || ok {
+		t.Errorf("Result was %#v, %v, expected nil, false", result, ok)
+	}
+
+	if result := c.Size(); result != 3 {
+		t.Errorf("Result was %d, expected 3", result)
+	}
+
+	expected := []Call[string, cache.T]{
+		{Method: "Set", Key: "key", Value: "val"},
+		{Method: "Get", Key: "key"},
+		{Method: "GetOK", Key: "key"},
+		{Method: "GetOK", Key: "other"},
+		{Method: "Size"},
+	}
+
+	if result := m.Calls(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestMockCacheOverride(t *testing.T) {
+	errClosed := errors.New("closed")
+
+	m := NewMockCache[int, string]()
+	m.On("GetAndDelete").Return("any", true)
+	m.On("GetAndDelete", 1).Return("one", true)
+	m.On("Close").Return(errClosed)
+
+	if result, _ := m.GetAndDelete(1); result != "one" {
+		t.Errorf("Result was %#v, expected %#v", result, "one")
+	}
+
+	if result, _ := m.GetAndDelete(2); result != "any" {
+		t.Errorf("Result was %#v, expected %#v", result, "any")
+	}
+
+	if err := m.Close(); err != errClosed {
+		t.Errorf("Error was %v, expected %v", err, errClosed)
+	}
+
+	m.Reset()
+	if result, ok := m.GetAndDelete(1); result != "" || ok {
+		t.Errorf("Result was %#v, %v, expected an empty string, false", result, ok)
+	}
+
+	if calls := m.CallsTo("GetAndDelete"); len(calls) != 1 {
+		t.Errorf("Recorded %d calls, expected 1", len(calls))
+	}
+}
+
+func TestMockCacheConcurrent(t *testing.T) {
+	m := NewMock()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.Set("key", i)
+			m.Get("key")
+		}()
+	}
+
+	wg.Wait()
+	if calls := m.Calls(); len(calls) != 20 {
+		t.Errorf("Recorded %d calls, expected 20", len(calls))
+	}
+}
@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is logged in place of calling the loader while the circuit breaker set by WithCircuitBreaker is open
+var ErrCircuitOpen = errors.New("cache: circuit open")
+
+// A breaker counts the consecutive failures of the loader.
+// Once failures reaches the circuit's maxFailures the breaker is open, until resetAfter has passed since openedAt
+// and a single probe call is let through
+type breaker struct {
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// A circuit decides whether the loader may be called, using one breaker for the whole cache or one per key
+type circuit[K comparable] struct {
+	mu          sync.Mutex
+	maxFailures int
+	resetAfter  time.Duration
+	perKey      bool
+	all         breaker
+	keys        map[K]*breaker
+}
+
+func newCircuit[K comparable](opts cacheOptions) *circuit[K] {
+	return &circuit[K]{
+		maxFailures: opts.maxFailures,
+		resetAfter:  opts.resetAfter,
+		perKey:      opts.circuitPerKey,
+		keys:        map[K]*breaker{},
+	}
+}
+
+// breaker returns the breaker for the specified key. It must only be called with mu held
+func (cb *circuit[K]) breaker(key K) *breaker {
+	if !cb.perKey {
+		return &cb.all
+	}
+
+	b, ok := cb.keys[key]
+	if !ok {
+		b = &breaker{}
+		cb.keys[key] = b
+	}
+
+	return b
+}
+
+// allow reports if the loader may be called for the specified key.
+// While the breaker is open it returns false, except for the first call after resetAfter, which is the probe
+func (cb *circuit[K]) allow(key K) bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	b := cb.breaker(key)
+	if b.failures < cb.maxFailures {
+		return true
+	}
+
+	if b.probing || time.Since(b.openedAt) < cb.resetAfter {
+		return false
+	}
+
+	b.probing = true
+	return true
+}
+
+// record counts the result of a loader call for the specified key.
+// ErrNoValue is not a failure, since the loader has answered
+func (cb *circuit[K]) record(key K, err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if err == nil || errors.Is(err, ErrNoValue) {
+		if cb.perKey {
+			delete(cb.keys, key)
+		} else {
+			cb.all = breaker{}
+		}
+
+		return
+	}
+
+	b := cb.breaker(key)
+	b.failures++
+	b.probing = false
+	if b.failures >= cb.maxFailures {
+		b.openedAt = time.Now()
+	}
+}
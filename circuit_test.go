@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithCircuitBreaker(t *testing.T) {
+	calls := 0
+	failing := true
+	c := NewWithOptions(
+		WithCircuitBreaker(2, time.Millisecond*50),
+		WithLoader(func(key string) (T, error) {
+			calls++
+			if failing {
+				return nil, errors.New("failed")
+			}
+
+			return "loaded " + key, nil
+		}))
+
+	for _, key := range []string{"1", "2", "3", "4"} {
+		if _, ok := c.GetOK(key); ok {
+			t.Errorf("Entry %s was loaded", key)
+		}
+	}
+
+	if result, expected := calls, 2; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	time.Sleep(time.Millisecond * 60)
+	c.Get("1")
+	c.Get("2")
+	if result, expected := calls, 3; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	failing = false
+	time.Sleep(time.Millisecond * 60)
+	for _, key := range []string{"1", "2"} {
+		if result, expected := c.Get(key), T("loaded "+key); result != expected {
+			t.Errorf("Result was %#v, expected %#v", result, expected)
+		}
+	}
+
+	if result, expected := calls, 5; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestWithCircuitBreakerPerKey(t *testing.T) {
+	calls := map[string]int{}
+	c := NewWithOptions(
+		WithCircuitBreaker(1, time.Hour),
+		WithCircuitBreakerPerKey(),
+		WithLoader(func(key string) (T, error) {
+			calls[key]++
+			if key == "bad" {
+				return nil, errors.New("failed")
+			}
+
+			return nil, ErrNoValue
+		}))
+
+	for i := 0; i < 3; i++ {
+		c.Get("bad")
+		c.Get("good")
+	}
+
+	if result, expected := calls["bad"], 1; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if result, expected := calls["good"], 3; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestWithCircuitBreakerFallback(t *testing.T) {
+	c := NewWithOptions(
+		WithCircuitBreaker(1, time.Hour),
+		WithCircuitBreakerFallback(func(key string) T { return "fallback " + key }),
+		WithLoader(func(key string) (T, error) {
+			return nil, errors.New("failed")
+		}))
+
+	if _, ok := c.GetOK("1"); ok {
+		t.Errorf("Entry was loaded")
+	}
+
+	result, ok := c.GetOK("1")
+	if !ok {
+		t.Errorf("Fallback was not returned")
+	}
+
+	if expected := T("fallback 1"); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if result, expected := c.Size(), 0; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestShardedCircuitBreaker(t *testing.T) {
+	calls := 0
+	c := NewSharded(
+		WithShards(4),
+		WithCircuitBreaker(1, time.Hour),
+		WithLoader(func(key string) (T, error) {
+			calls++
+			return nil, errors.New("failed")
+		}))
+
+	for _, key := range []string{"1", "2", "3", "4", "5", "6", "7", "8"} {
+		c.Get(key)
+	}
+
+	if result, expected := calls, 1; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
@@ -0,0 +1,83 @@
+package cache
+
+import "sort"
+
+// Clone returns an independent cache configured like c and holding the same entries.
+// Entries keep their remaining expiry, and the order a built-in EvictionPolicy evicts in is preserved.
+// Values are copied by reference. AfterFunc callbacks are not copied, and a bounded cache using a custom
+// EvictionPolicy, which cannot be copied, falls back to evicting the least recently used entry.
+func (c *Cache[K, V]) Clone() *Cache[K, V] {
+	// Resize changes opts, so it is read with the cache locked
+	current := make(chan cacheOptions, 1)
+	c.itemOp(func(backend[K, V]) { current <- c.opts })
+
+	opts := <-current
+	switch opts.policy.(type) {
+	case *fifo[K]:
+		opts.policy = NewFIFOOf[K]()
+	default:
+		opts.policy = nil
+	}
+
+	clone := newCache[K, V](opts)
+	clone.restore(c.orderedEntries())
+	return clone
+}
+
+// orderedEntries returns the entries that have not expired, starting with the next to be evicted
+// if the cache's policy can report its order
+func (c *Cache[K, V]) orderedEntries() []entry[K, V] {
+	entries := c.entries()
+
+	result := make(chan []K, 1)
+	c.readOp(func(backend[K, V]) {
+		if p, ok := c.policy.(orderedPolicy[K]); ok {
+			result <- p.keys()
+			return
+		}
+
+		result <- nil
+	})
+
+	order := <-result
+	if order == nil {
+		return entries
+	}
+
+	rank := make(map[K]int, len(order))
+	for i, key := range order {
+		rank[key] = i
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		ri, ok := rank[entries[i].Key]
+		if !ok {
+			ri = len(order)
+		}
+
+		rj, ok := rank[entries[j].Key]
+		if !ok {
+			rj = len(order)
+		}
+
+		return ri < rj
+	})
+
+	return entries
+}
+
+// Clone returns an independent sharded cache holding the same entries. See Cache.Clone
+func (c *ShardedCache[K, V]) Clone() *ShardedCache[K, V] {
+	clone := &ShardedCache[K, V]{
+		shards: make([]*Cache[K, V], len(c.shards)),
+		done:   make(chan struct{}),
+	}
+
+	for i, shard := range c.shards {
+		clone.shards[i] = shard.Clone()
+	}
+
+	// every shard is created with the same options, and the clone is not shared yet
+	clone.shareCircuit(clone.shards[0].opts)
+	return clone
+}
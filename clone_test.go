@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestClone(t *testing.T) {
+	c := New()
+	c.Set("1", 1)
+	c.Set("2", 2, Expire(time.Hour))
+
+	clone := c.Clone()
+	if result, expected := clone.Items(), c.Items(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if _, ok := clone.RemainingTTL("2"); !ok {
+		t.Errorf("Expiry was not cloned")
+	}
+
+	c.Set("3", 3)
+	clone.Delete("1")
+
+	if result, expected := c.Keys(), []string{"1", "2", "3"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if result, expected := clone.Keys(), []string{"2"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestCloneEvictionOrder(t *testing.T) {
+	c := NewWithOptions(WithMaxSize(3))
+	c.Set("1", 1)
+	c.Set("2", 2)
+	c.Set("3", 3)
+	c.Get("1")
+
+	clone := c.Clone()
+	clone.Set("4", 4)
+
+	if result, expected := clone.Keys(), []string{"1", "3", "4"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestCloneResized(t *testing.T) {
+	c := NewWithOptions(WithMaxSize(10))
+	c.Resize(2)
+
+	clone := c.Clone()
+	for _, key := range []string{"1", "2", "3", "4", "5"} {
+		clone.Set(key, key)
+	}
+
+	if result, expected := clone.Keys(), []string{"4", "5"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	c = New()
+	c.Resize(2)
+	clone = c.Clone()
+	for _, key := range []string{"1", "2", "3"} {
+		clone.Set(key, key)
+	}
+
+	if result, expected := clone.Size(), 2; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestShardedClone(t *testing.T) {
+	c := NewSharded(WithShards(4))
+	c.SetMany(map[string]T{"1": 1, "2": 2, "3": 3})
+
+	clone := c.Clone()
+	c.Delete("1")
+
+	if result, expected := clone.Keys(), []string{"1", "2", "3"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestShardedCloneCircuitBreaker(t *testing.T) {
+	calls := 0
+	c := NewSharded(
+		WithShards(4),
+		WithCircuitBreaker(1, time.Hour),
+		WithLoader(func(key string) (T, error) {
+			calls++
+			return nil, errors.New("failed")
+		}))
+
+	clone := c.Clone()
+	for _, key := range []string{"1", "2", "3", "4", "5", "6", "7", "8"} {
+		clone.Get(key)
+	}
+
+	if result, expected := calls, 1; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
@@ -0,0 +1,140 @@
+package cache
+
+import "context"
+
+// SetCtx behaves like Set, but gives up if ctx is done before the cache can store the entry.
+// Returns ctx.Err() if ctx was done first, in which case the cache is left unchanged,
+// or ErrClosed if the cache has been closed. A failed write-through is returned as by SetE.
+func (c *Cache[K, V]) SetCtx(ctx context.Context, key K, val V, options ...SetOption) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := c.writeThrough(key, val); err != nil {
+		return err
+	}
+
+	return c.ctxItemOp(ctx, func(items backend[K, V]) {
+		c.placeWritten(items, key, val, options)
+	})
+}
+
+// GetCtx behaves like Get, but gives up if ctx is done before the entry has been read.
+// Returns ctx.Err() if ctx was done first, or ErrClosed if the cache has been closed.
+func (c *Cache[K, V]) GetCtx(ctx context.Context, key K) (V, error) {
+	result := make(chan V, 1)
+	err := c.ctxReadOp(ctx, func(items backend[K, V]) {
+		v, _ := c.lookup(items, key)
+		result <- v
+	})
+
+	var zero V
+	if err != nil {
+		return zero, err
+	}
+
+	select {
+	case v := <-result:
+		return v, nil
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+// DeleteCtx behaves like Delete, but gives up if ctx is done before the cache can remove the entry.
+// Returns ctx.Err() if ctx was done first, in which case the cache is left unchanged,
+// or ErrClosed if the cache has been closed.
+func (c *Cache[K, V]) DeleteCtx(ctx context.Context, key K) error {
+	return c.ctxItemOp(ctx, func(items backend[K, V]) {
+		c.evict(items, key, Manual)
+		c.tryExpiryOp(func(expiries map[K]*expiry[K]) {
+			stopExpiry(expiries, key)
+		})
+	})
+}
+
+// SetCtx behaves like Set, but gives up if ctx is done first. See Cache.SetCtx
+func (c *ShardedCache[K, V]) SetCtx(ctx context.Context, key K, val V, options ...SetOption) error {
+	return c.shard(key).SetCtx(ctx, key, val, options...)
+}
+
+// GetCtx behaves like Get, but gives up if ctx is done first. See Cache.GetCtx
+func (c *ShardedCache[K, V]) GetCtx(ctx context.Context, key K) (V, error) {
+	return c.shard(key).GetCtx(ctx, key)
+}
+
+// DeleteCtx behaves like Delete, but gives up if ctx is done first. See Cache.DeleteCtx
+func (c *ShardedCache[K, V]) DeleteCtx(ctx context.Context, key K) error {
+	return c.shard(key).DeleteCtx(ctx, key)
+}
+
+// A waiter is shared by the WaitForKey callers blocked on the same key
+type waiter struct {
+	ch    chan struct{}
+	count int
+}
+
+// wake unblocks any WaitForKey callers waiting on the specified key. It must only be called with mu held
+func (c *Cache[K, V]) wake(key K) {
+	if w, ok := c.waiters[key]; ok {
+		close(w.ch)
+		delete(c.waiters, key)
+	}
+}
+
+// WaitForKey retrieves the entry at the specified key, blocking until one is stored if none exists.
+// Waiters are woken as soon as the entry is stored rather than by polling.
+// Returns ctx.Err() if ctx is done first, or ErrClosed if the cache is closed first.
+func (c *Cache[K, V]) WaitForKey(ctx context.Context, key K) (V, error) {
+	var zero V
+	for {
+		result := make(chan V, 1)
+		wait := make(chan *waiter, 1)
+		err := c.ctxItemOp(ctx, func(items backend[K, V]) {
+			if v, ok := c.lookup(items, key); ok {
+				result <- v
+				wait <- nil
+				return
+			}
+
+			w, ok := c.waiters[key]
+			if !ok {
+				w = &waiter{ch: make(chan struct{})}
+				c.waiters[key] = w
+			}
+
+			w.count++
+			wait <- w
+		})
+
+		if err != nil {
+			return zero, err
+		}
+
+		w := <-wait
+		if w == nil {
+			return <-result, nil
+		}
+
+		select {
+		case <-w.ch:
+			// the entry may have been removed again before we could read it, so look it up again
+		case <-c.done:
+			return zero, ErrClosed
+		case <-ctx.Done():
+			c.tryItemOp(func(backend[K, V]) {
+				w.count--
+				if w.count == 0 && c.waiters[key] == w {
+					delete(c.waiters, key)
+				}
+			})
+
+			return zero, ctx.Err()
+		}
+	}
+}
+
+// WaitForKey retrieves the entry at the specified key, blocking until one is stored. See Cache.WaitForKey
+func (c *ShardedCache[K, V]) WaitForKey(ctx context.Context, key K) (V, error) {
+	return c.shard(key).WaitForKey(ctx, key)
+}
@@ -0,0 +1,181 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSetCtx(t *testing.T) {
+	c := New()
+	if err := c.SetCtx(context.Background(), "1", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if result, expected := c.Get("1"), T(1); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestGetCtx(t *testing.T) {
+	c := New()
+	c.Set("1", 1)
+
+	result, err := c.GetCtx(context.Background(), "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := T(1); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestDeleteCtx(t *testing.T) {
+	c := New()
+	c.Set("1", 1, Expire(time.Hour))
+	if err := c.DeleteCtx(context.Background(), "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := c.GetOK("1"); ok {
+		t.Errorf("Entry was not deleted")
+	}
+
+	if _, ok := c.RemainingTTL("1"); ok {
+		t.Errorf("Expiry was not cancelled")
+	}
+}
+
+func TestCtxBusy(t *testing.T) {
+	c := New()
+	c.Set("1", 1)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go c.GetOrSet("2", func() T {
+		close(started)
+		<-release
+		return 2
+	})
+
+	<-started
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+
+	if err := c.SetCtx(ctx, "1", 2); err != context.DeadlineExceeded {
+		t.Errorf("Result was %#v, expected %#v", err, context.DeadlineExceeded)
+	}
+
+	if _, err := c.GetCtx(ctx, "1"); err != context.DeadlineExceeded {
+		t.Errorf("Result was %#v, expected %#v", err, context.DeadlineExceeded)
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := c.DeleteCtx(canceled, "1"); err != context.Canceled {
+		t.Errorf("Result was %#v, expected %#v", err, context.Canceled)
+	}
+}
+
+func TestCtxClosed(t *testing.T) {
+	c := New()
+	c.Close()
+
+	if err := c.SetCtx(context.Background(), "1", 1); err != ErrClosed {
+		t.Errorf("Result was %#v, expected %#v", err, ErrClosed)
+	}
+}
+
+func TestWaitForKey(t *testing.T) {
+	c := New()
+	c.Set("1", 1)
+
+	result, err := c.WaitForKey(context.Background(), "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := T(1); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestWaitForKeyWakeup(t *testing.T) {
+	c := New()
+
+	const waiters = 5
+	results := make(chan T, waiters)
+	for i := 0; i < waiters; i++ {
+		go func() {
+			v, err := c.WaitForKey(context.Background(), "1")
+			if err != nil {
+				t.Error(err)
+			}
+
+			results <- v
+		}()
+	}
+
+	// wait until every waiter is blocked on the key
+	for {
+		count := make(chan int, 1)
+		c.itemOp(func(backend[string, T]) {
+			if w, ok := c.waiters["1"]; ok {
+				count <- w.count
+				return
+			}
+
+			count <- 0
+		})
+
+		if <-count == waiters {
+			break
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	start := time.Now()
+	c.Set("1", 1)
+	for i := 0; i < waiters; i++ {
+		if result, expected := <-results, T(1); result != expected {
+			t.Errorf("Result was %#v, expected %#v", result, expected)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Millisecond*50 {
+		t.Errorf("Waiters took %v to wake up", elapsed)
+	}
+}
+
+func TestWaitForKeyCancel(t *testing.T) {
+	c := New()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*5)
+	defer cancel()
+
+	if _, err := c.WaitForKey(ctx, "1"); err != context.DeadlineExceeded {
+		t.Errorf("Result was %#v, expected %#v", err, context.DeadlineExceeded)
+	}
+
+	count := make(chan int, 1)
+	c.itemOp(func(backend[string, T]) { count <- len(c.waiters) })
+	if result, expected := <-count, 0; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestWaitForKeyClose(t *testing.T) {
+	c := New()
+	go func() {
+		time.Sleep(time.Millisecond * 5)
+		c.Close()
+	}()
+
+	if _, err := c.WaitForKey(context.Background(), "1"); err != ErrClosed {
+		t.Errorf("Result was %#v, expected %#v", err, ErrClosed)
+	}
+}
@@ -0,0 +1,229 @@
+package cache
+
+import "container/list"
+
+// An EvictionReason describes why an entry was removed from the cache
+type EvictionReason int
+
+const (
+	// Expired means the entry's expiry elapsed
+	Expired EvictionReason = iota
+	// Capacity means the entry was evicted to make room in a cache created with WithMaxSize
+	Capacity
+	// Manual means the entry was removed by Delete, Clear or a similar call
+	Manual
+)
+
+func (r EvictionReason) String() string {
+	switch r {
+	case Expired:
+		return "expired"
+	case Capacity:
+		return "capacity"
+	case Manual:
+		return "manual"
+	default:
+		return "unknown"
+	}
+}
+
+// A Policy decides which entry a cache created with WithMaxSize evicts when it is full.
+// K is the cache's key type.
+// The cache only calls a policy from one goroutine at a time,
+// so a policy must not be shared between caches.
+type Policy[K comparable] interface {
+	// Record is called whenever the entry at key is stored or read
+	Record(key K)
+	// Remove is called whenever the entry at key leaves the cache
+	Remove(key K)
+	// Evict returns the key to evict, chosen from keys, the keys currently in the cache
+	Evict(keys []K) K
+}
+
+// An EvictionPolicy is a Policy for caches with string keys
+type EvictionPolicy = Policy[string]
+
+// newPolicy returns the policy set by WithEvictionPolicy, or a new FIFO policy if WithFIFO was passed,
+// or a new LRU policy otherwise
+func newPolicy[K comparable](opts cacheOptions) Policy[K] {
+	switch p := option[Policy[K]](opts.policy, "WithEvictionPolicy"); {
+	case p != nil:
+		return p
+	case opts.fifo:
+		return NewFIFOOf[K]()
+	default:
+		return NewLRUOf[K]()
+	}
+}
+
+// Resize changes the number of entries the cache can hold, as set by WithMaxSize, evicting entries
+// as chosen by its policy straight away if it holds more than newMax. Entries evicted this way
+// are reported to OnEvict as Capacity. A non-positive newMax removes the limit, though a limit set by
+// WithMaxMemory or WithMaxWeight still applies. Resizing a cache created without a limit starts
+// tracking its entries with the policy set by WithEvictionPolicy or WithFIFO, or an LRU policy,
+// in no particular order for the entries it already holds
+func (c *Cache[K, V]) Resize(newMax int) {
+	// Reads must lock the cache for writing before the policy they record keys with is added
+	if newMax > 0 && !c.bounded.Load() {
+		c.gate.Lock()
+		c.bounded.Store(true)
+		c.gate.Unlock()
+	}
+
+	c.itemOp(func(items backend[K, V]) {
+		c.maxSize = max(newMax, 0)
+		c.opts.maxSize = c.maxSize
+		if c.policy == nil {
+			if c.maxSize == 0 {
+				return
+			}
+
+			c.policy = newPolicy[K](c.opts)
+			for key := range items.all() {
+				c.policy.Record(key)
+			}
+		}
+
+		c.trim(items)
+	})
+}
+
+// Resize changes the number of entries the cache can hold, dividing newMax evenly between the shards.
+// See Cache.Resize
+func (c *ShardedCache[K, V]) Resize(newMax int) {
+	n := len(c.shards)
+	for _, shard := range c.shards {
+		shard.Resize((max(newMax, 0) + n - 1) / n)
+	}
+}
+
+// NewLRU returns an EvictionPolicy that evicts the least recently used entry
+func NewLRU() EvictionPolicy {
+	return NewLRUOf[string]()
+}
+
+// NewLRUOf returns a Policy for keys of type K that evicts the least recently used entry
+func NewLRUOf[K comparable]() Policy[K] {
+	return &lru[K]{
+		order: list.New(),
+		elems: map[K]*list.Element{},
+	}
+}
+
+// NewFIFO returns an EvictionPolicy that evicts the entry that was first inserted.
+// Unlike NewLRU, reading or overwriting an entry does not affect when it is evicted
+func NewFIFO() EvictionPolicy {
+	return NewFIFOOf[string]()
+}
+
+// NewFIFOOf returns a Policy for keys of type K that evicts the entry that was first inserted. See NewFIFO
+func NewFIFOOf[K comparable]() Policy[K] {
+	return &fifo[K]{
+		order: list.New(),
+		elems: map[K]*list.Element{},
+	}
+}
+
+type lru[K comparable] struct {
+	order *list.List
+	elems map[K]*list.Element
+}
+
+func (l *lru[K]) Record(key K) {
+	if elem, ok := l.elems[key]; ok {
+		l.order.MoveToFront(elem)
+		return
+	}
+
+	l.elems[key] = l.order.PushFront(key)
+}
+
+func (l *lru[K]) Remove(key K) {
+	if elem, ok := l.elems[key]; ok {
+		l.order.Remove(elem)
+		delete(l.elems, key)
+	}
+}
+
+func (l *lru[K]) Evict(keys []K) K {
+	if key, ok := l.next(); ok {
+		return key
+	}
+
+	return keys[0]
+}
+
+type fifo[K comparable] struct {
+	order *list.List
+	elems map[K]*list.Element
+}
+
+func (f *fifo[K]) Record(key K) {
+	if _, ok := f.elems[key]; !ok {
+		f.elems[key] = f.order.PushFront(key)
+	}
+}
+
+func (f *fifo[K]) Remove(key K) {
+	if elem, ok := f.elems[key]; ok {
+		f.order.Remove(elem)
+		delete(f.elems, key)
+	}
+}
+
+func (f *fifo[K]) Evict(keys []K) K {
+	if key, ok := f.next(); ok {
+		return key
+	}
+
+	return keys[0]
+}
+
+// listPolicy is implemented by the built-in policies, which keep their keys in eviction order,
+// so the cache can evict without building a slice of every key to pass to Evict
+type listPolicy[K comparable] interface {
+	// next returns the key to evict next, or false if no keys are recorded
+	next() (K, bool)
+}
+
+func (l *lru[K]) next() (K, bool) {
+	return back[K](l.order)
+}
+
+func (f *fifo[K]) next() (K, bool) {
+	return back[K](f.order)
+}
+
+// back returns the key at the back of order, or false if order is empty
+func back[K comparable](order *list.List) (K, bool) {
+	if elem := order.Back(); elem != nil {
+		return elem.Value.(K), true
+	}
+
+	var zero K
+	return zero, false
+}
+
+// orderedPolicy is implemented by the built-in policies so Clone can preserve the order they evict in
+type orderedPolicy[K comparable] interface {
+	// keys returns the recorded keys, starting with the next to be evicted
+	keys() []K
+}
+
+func (l *lru[K]) keys() []K {
+	return listKeys[K](l.order)
+}
+
+func (f *fifo[K]) keys() []K {
+	return listKeys[K](f.order)
+}
+
+// listKeys returns the keys held in order from back to front
+func listKeys[K comparable](order *list.List) []K {
+	keys := make([]K, 0, order.Len())
+	for elem := order.Back(); elem != nil; elem = elem.Prev() {
+		keys = append(keys, elem.Value.(K))
+	}
+
+	return keys
+}
@@ -0,0 +1,282 @@
+package cache
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLRU(t *testing.T) {
+	p := NewLRU()
+	p.Record("1")
+	p.Record("2")
+	p.Record("3")
+	p.Record("1")
+
+	keys := []string{"1", "2", "3"}
+	if result, expected := p.Evict(keys), "2"; result != expected {
+		t.Errorf("Evicted %#v, expected %#v", result, expected)
+	}
+
+	p.Remove("2")
+	if result, expected := p.Evict(keys), "3"; result != expected {
+		t.Errorf("Evicted %#v, expected %#v", result, expected)
+	}
+}
+
+func TestFIFO(t *testing.T) {
+	p := NewFIFO()
+	p.Record("1")
+	p.Record("2")
+	p.Record("3")
+	p.Record("1")
+
+	keys := []string{"1", "2", "3"}
+	if result, expected := p.Evict(keys), "1"; result != expected {
+		t.Errorf("Evicted %#v, expected %#v", result, expected)
+	}
+
+	p.Remove("1")
+	if result, expected := p.Evict(keys), "2"; result != expected {
+		t.Errorf("Evicted %#v, expected %#v", result, expected)
+	}
+}
+
+func TestWithEvictionPolicy(t *testing.T) {
+	policies := map[string]struct {
+		policy   EvictionPolicy
+		expected []string
+	}{
+		"LRU":  {NewLRU(), []string{"1", "3", "4"}},
+		"FIFO": {NewFIFO(), []string{"2", "3", "4"}},
+	}
+
+	for name, p := range policies {
+		c := NewWithOptions(WithMaxSize(3), WithEvictionPolicy(p.policy))
+		c.Set("1", 1)
+		c.Set("2", 2)
+		c.Set("3", 3)
+		c.Get("1")
+		c.Set("4", 4)
+
+		if result := c.Keys(); !reflect.DeepEqual(result, p.expected) {
+			t.Errorf("%s: Result was %#v, expected %#v", name, result, p.expected)
+		}
+	}
+}
+
+func TestWithFIFO(t *testing.T) {
+	c := NewWithOptions(WithFIFO(3))
+	c.Set("1", 1)
+	c.Set("2", 2)
+	c.Set("3", 3)
+	c.Get("1")
+	c.Set("1", 10)
+	c.Set("4", 4)
+
+	if result, expected := c.Keys(), []string{"2", "3", "4"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	c.Set("5", 5)
+	if result, expected := c.Keys(), []string{"3", "4", "5"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestResize(t *testing.T) {
+	evicted := map[string]EvictionReason{}
+	c := NewWithOptions(WithMaxSize(4), WithOnEvict(func(key string, val T, reason EvictionReason) {
+		evicted[key] = reason
+	}))
+
+	for i := 1; i <= 4; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+
+	c.Get("1")
+	c.Resize(2)
+
+	if result, expected := c.Keys(), []string{"1", "4"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if expected := map[string]EvictionReason{"2": Capacity, "3": Capacity}; !reflect.DeepEqual(evicted, expected) {
+		t.Errorf("Result was %#v, expected %#v", evicted, expected)
+	}
+
+	c.Resize(0)
+	c.Set("5", 5)
+	c.Set("6", 6)
+	if result, expected := c.Size(), 4; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestResizeUnbounded(t *testing.T) {
+	c := New()
+	for i := 0; i < 10; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+
+	c.Resize(3)
+	if result, expected := c.Size(), 3; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	c.Set("new", 1)
+	if result, expected := c.Size(), 3; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if _, ok := c.GetOK("new"); !ok {
+		t.Errorf("Entry for key 'new' should not have been evicted")
+	}
+}
+
+func TestResizeConcurrent(t *testing.T) {
+	for _, c := range []*StringCache{New(), NewWithOptions(WithSyncMap())} {
+		for i := 0; i < 100; i++ {
+			c.Set(strconv.Itoa(i), i)
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 1000; j++ {
+					c.Get(strconv.Itoa(j % 100))
+				}
+			}()
+		}
+
+		c.Resize(50)
+		wg.Wait()
+
+		if result, expected := c.Size(), 50; result != expected {
+			t.Errorf("Result was %#v, expected %#v", result, expected)
+		}
+	}
+}
+
+func TestShardedResize(t *testing.T) {
+	c := NewSharded(WithShards(4))
+	for i := 0; i < 100; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+
+	c.Resize(8)
+	if result := c.Size(); result > 8 {
+		t.Errorf("Result was %#v, expected at most %#v", result, 8)
+	}
+}
+
+func TestWithOnEvict(t *testing.T) {
+	var mu sync.Mutex
+	evicted := map[string]EvictionReason{}
+	c := NewWithOptions(WithMaxSize(2), WithOnEvict(func(key string, val T, reason EvictionReason) {
+		mu.Lock()
+		defer mu.Unlock()
+		evicted[key] = reason
+	}))
+
+	c.Set("1", 1)
+	c.Set("2", 2, Expire(time.Millisecond))
+	c.Set("3", 3)
+
+	time.Sleep(time.Millisecond * 2)
+	c.Delete("3")
+	c.Delete("4")
+
+	expected := map[string]EvictionReason{
+		"1": Capacity,
+		"2": Expired,
+		"3": Manual,
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(evicted, expected) {
+		t.Errorf("Evicted %v, expected %v", evicted, expected)
+	}
+}
+
+func TestEvict(t *testing.T) {
+	evicted := map[string]T{}
+	deleted := map[string]T{}
+	c := NewWithOptions(
+		WithOnEvict(func(key string, val T, reason EvictionReason) {
+			if reason == Manual {
+				evicted[key] = val
+			}
+		}),
+		WithOnDelete(func(key string, val T) { deleted[key] = val }),
+	)
+
+	c.Set("1", 1)
+	c.Set("2", 2, Expire(time.Hour))
+
+	if result, ok := c.GetAndDelete("1"); !ok || result != T(1) {
+		t.Errorf("Result was %#v, %v, expected %#v", result, ok, 1)
+	}
+
+	if result, ok := c.Evict("2"); !ok || result != T(2) {
+		t.Errorf("Result was %#v, %v, expected %#v", result, ok, 2)
+	}
+
+	if _, ok := c.Evict("3"); ok {
+		t.Errorf("Entry for key '3' should not have existed")
+	}
+
+	if _, ok := c.RemainingTTL("2"); ok {
+		t.Errorf("Expiry for key '2' should have been removed")
+	}
+
+	if expected := map[string]T{"2": 2}; !reflect.DeepEqual(evicted, expected) {
+		t.Errorf("Evicted %v, expected %v", evicted, expected)
+	}
+
+	if expected := map[string]T{"1": 1, "2": 2}; !reflect.DeepEqual(deleted, expected) {
+		t.Errorf("Deleted %v, expected %v", deleted, expected)
+	}
+}
+
+func TestWithOnEvictClear(t *testing.T) {
+	var evicted []string
+	var c *StringCache
+	c = NewWithOptions(WithOnEvict(func(key string, val T, reason EvictionReason) {
+		evicted = append(evicted, key)
+
+		// the callback may call back into the cache without deadlocking
+		c.Size()
+	}))
+
+	c.Set("1", 1)
+	c.Set("2", 2)
+	c.Clear()
+
+	sort.Strings(evicted)
+	if expected := []string{"1", "2"}; !reflect.DeepEqual(evicted, expected) {
+		t.Errorf("Evicted %#v, expected %#v", evicted, expected)
+	}
+}
+
+func benchmarkSetEvicting(size int, b *testing.B) {
+	c := NewWithOptions(WithMaxSize(size))
+	for i := 0; i < size; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		c.Set(strconv.Itoa(size+n), n)
+	}
+}
+
+func BenchmarkSetEvicting10(b *testing.B)    { benchmarkSetEvicting(10, b) }
+func BenchmarkSetEvicting1000(b *testing.B)  { benchmarkSetEvicting(1000, b) }
+func BenchmarkSetEvicting10000(b *testing.B) { benchmarkSetEvicting(10000, b) }
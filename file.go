@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ErrFileNotFound is returned by LoadFromFile when the file does not exist
+var ErrFileNotFound = errors.New("cache: file not found")
+
+// saveToFile writes the output of export to path.
+// It writes to a temporary file in the same directory first and renames it over path once complete,
+// so a crash part way through never leaves a partially written file at path
+func saveToFile(path string, export func(io.Writer) error) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("cache: cannot save to %s: %w", path, err)
+	}
+
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	if err := export(f); err != nil {
+		return fmt.Errorf("cache: cannot save to %s: %w", path, err)
+	}
+
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("cache: cannot save to %s: %w", path, err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("cache: cannot save to %s: %w", path, err)
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("cache: cannot save to %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// loadFromFile passes the contents of path to load
+func loadFromFile(path string, load func(io.Reader) error) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrFileNotFound, path)
+	}
+
+	if err != nil {
+		return fmt.Errorf("cache: cannot load from %s: %w", path, err)
+	}
+
+	defer f.Close()
+
+	if err := load(f); err != nil {
+		return fmt.Errorf("cache: cannot load from %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// SaveToFile writes the cache to the file at path using ExportGob, replacing the file atomically
+func (c *Cache[K, V]) SaveToFile(path string) error {
+	return saveToFile(path, c.ExportGob)
+}
+
+// LoadFromFile imports the file at path written by SaveToFile using ImportGob.
+// If the file does not exist, the returned error wraps ErrFileNotFound
+func (c *Cache[K, V]) LoadFromFile(path string) error {
+	return loadFromFile(path, c.ImportGob)
+}
+
+// SaveToFile writes the cache to the file at path. See Cache.SaveToFile
+func (c *ShardedCache[K, V]) SaveToFile(path string) error {
+	return saveToFile(path, c.ExportGob)
+}
+
+// LoadFromFile imports the file at path written by SaveToFile. See Cache.LoadFromFile
+func (c *ShardedCache[K, V]) LoadFromFile(path string) error {
+	return loadFromFile(path, c.ImportGob)
+}
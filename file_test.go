@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSaveLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache")
+
+	c := New()
+	c.Set("1", 1)
+	c.Set("2", "a")
+	if err := c.SaveToFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := New()
+	if err := loaded.LoadFromFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if result, expected := loaded.Items(), c.Items(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result, expected := len(entries), 1; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestSaveToFileFailure(t *testing.T) {
+	type unregistered struct{ X int }
+
+	path := filepath.Join(t.TempDir(), "cache")
+	if err := os.WriteFile(path, []byte("previous"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := New()
+	c.Set("1", unregistered{})
+	if err := c.SaveToFile(path); err == nil {
+		t.Errorf("Saving an unregistered type did not fail")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result, expected := string(data), "previous"; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result, expected := len(entries), 1; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestLoadFromFileNotFound(t *testing.T) {
+	c := New()
+	err := c.LoadFromFile(filepath.Join(t.TempDir(), "missing"))
+	if !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("Result was %#v, expected %#v", err, ErrFileNotFound)
+	}
+}
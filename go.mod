@@ -0,0 +1,3 @@
+module github.com/robotsrulz/go-cache
+
+go 1.23.0
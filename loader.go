@@ -0,0 +1,371 @@
+package cache
+
+import (
+	"errors"
+	"reflect"
+	"time"
+)
+
+// ErrNoValue is returned by a loader passed to WithLoader when the requested key has no value.
+// Like any other loader error, it causes the miss to be returned to the caller without caching anything
+var ErrNoValue = errors.New("cache: no value")
+
+// errLoadPanicked is the error shared by the callers waiting on a load whose fn panicked
+var errLoadPanicked = errors.New("cache: load panicked")
+
+// A negativeEntry marks a key the loader had no value for, remembered until expires by WithNegativeCaching
+type negativeEntry struct {
+	expires time.Time
+}
+
+// A load is a call to the cache's loader, or to a GetOrSet fn, shared by every concurrent miss on the same key.
+// val, ok and err are written before done is closed
+type load[V any] struct {
+	done chan struct{}
+	val  V
+	ok   bool
+	err  error
+}
+
+// getOrLoad retrieves the entry at the specified key, calling fn to load it if it is missing.
+// fn runs without the cache locked, and only the first of several concurrent misses on a key calls it;
+// the others wait for its result. The options param is applied if the loaded value is stored.
+// The error fn returned is returned with the miss.
+// If refresh is true, an entry due for a refresh under WithRefreshAfterWrite is returned at once
+// and reloaded with fn in the background
+func (c *Cache[K, V]) getOrLoad(key K, fn func() (V, error), options []SetOption, refresh bool) (V, bool, error) {
+	type found struct {
+		val     V
+		ok      bool
+		l       *load[V]
+		leader  bool
+		written time.Time
+	}
+
+	result := make(chan found, 1)
+	c.itemOp(func(items backend[K, V]) {
+		if v, ok := c.lookup(items, key); ok {
+			r := found{val: v, ok: true}
+			if written := c.writeTimes[key]; refresh && time.Since(written) > c.refreshAfter && c.loads[key] == nil {
+				r.l = &load[V]{done: make(chan struct{})}
+				r.written = written
+				c.loads[key] = r.l
+			}
+
+			result <- r
+			return
+		}
+
+		if l, ok := c.loads[key]; ok {
+			result <- found{l: l}
+			return
+		}
+
+		l := &load[V]{done: make(chan struct{})}
+		c.loads[key] = l
+		result <- found{l: l, leader: true}
+	})
+
+	r := <-result
+	if r.ok {
+		if r.l != nil {
+			go c.runRefresh(key, r.l, fn, r.written)
+		}
+
+		return r.val, true, nil
+	}
+
+	if r.l == nil {
+		return r.val, r.ok, nil
+	}
+
+	if r.leader {
+		c.runLoad(key, r.l, fn, options)
+	}
+
+	<-r.l.done
+	return r.l.val, r.l.ok, r.l.err
+}
+
+// runLoad calls fn, stores the value it returns at the specified key,
+// and then wakes every caller waiting on l. If fn panics, the load is still finished with errLoadPanicked
+// before the panic carries on up to the caller, so later misses on the key do not wait on it forever
+func (c *Cache[K, V]) runLoad(key K, l *load[V], fn func() (V, error), options []SetOption) {
+	var val V
+	var computeTime time.Duration
+	err := errLoadPanicked
+	defer func() { c.finishLoad(key, l, val, err, computeTime, options) }()
+
+	start := time.Now()
+	val, err = fn()
+	computeTime = time.Since(start)
+}
+
+// finishLoad stores val at the specified key unless the load failed with err, removes the load,
+// and then wakes every caller waiting on l
+func (c *Cache[K, V]) finishLoad(key K, l *load[V], val V, err error, computeTime time.Duration, options []SetOption) {
+	defer close(l.done)
+
+	l.err = err
+	if err != nil && !errors.Is(err, ErrNoValue) {
+		c.log(LevelError, "load failed", key, map[string]interface{}{"error": err})
+	}
+
+	c.tryItemOp(func(items backend[K, V]) {
+		delete(c.loads, key)
+		if err != nil {
+			if c.negativeTTL > 0 && errors.Is(err, ErrNoValue) {
+				c.negatives[key] = negativeEntry{expires: time.Now().Add(c.negativeTTL)}
+			}
+
+			return
+		}
+
+		// an entry set while the loader was running is newer than the loaded value, so keep it
+		if v, ok := c.find(items, key); ok {
+			l.val, l.ok = v, true
+			return
+		}
+
+		c.computeTimes[key] = computeTime
+		c.place(items, key, val, options)
+		l.val, l.ok = val, true
+	})
+}
+
+// runRefresh calls fn to reload the entry at the specified key, stored at written, and then wakes every caller
+// waiting on l. The entry is replaced by the reloaded value unless fn fails or the entry was written or removed
+// in the meantime, in which case it is left alone. Since it runs in its own goroutine, a panic in fn is recovered
+// and logged at LevelError, and counts as a failed refresh
+func (c *Cache[K, V]) runRefresh(key K, l *load[V], fn func() (V, error), written time.Time) {
+	var val V
+	err := errLoadPanicked
+	defer func() {
+		if r := recover(); r != nil {
+			c.log(LevelError, "refresh panicked", key, map[string]interface{}{"panic": r})
+		}
+
+		c.finishRefresh(key, l, val, err, written)
+	}()
+
+	val, err = fn()
+}
+
+// finishRefresh replaces the entry at the specified key with val unless the refresh failed with err
+// or the entry has changed since written, removes the load, and then wakes every caller waiting on l
+func (c *Cache[K, V]) finishRefresh(key K, l *load[V], val V, err error, written time.Time) {
+	defer close(l.done)
+
+	l.err = err
+	if err != nil && !errors.Is(err, ErrNoValue) {
+		c.log(LevelError, "refresh failed", key, map[string]interface{}{"error": err})
+	}
+
+	c.tryItemOp(func(items backend[K, V]) {
+		delete(c.loads, key)
+		if t, ok := c.writeTimes[key]; err != nil || !ok || !t.Equal(written) {
+			l.val, l.ok = c.find(items, key)
+			return
+		}
+
+		c.place(items, key, val, nil)
+		l.val, l.ok = val, true
+	})
+}
+
+// GetOrLoad retrieves the entry at the specified key, calling loader to load it if it is missing.
+// loader returns the value along with how long it should live, for a backing store that dictates expiry;
+// a non-positive TTL falls back to the cache's default expiry, if it has one.
+// loader runs without the cache locked, and only the first of several concurrent misses on a key calls it;
+// the others wait for and share its result. If loader returns an error, nothing is stored and the error is returned
+func (c *Cache[K, V]) GetOrLoad(key K, loader func() (V, time.Duration, error)) (V, error) {
+	var ttl time.Duration
+	expire := func(e setEntry) {
+		if ttl > 0 {
+			e.expire(ttl, nil)
+		}
+	}
+
+	v, _, err := c.getOrLoad(key, func() (V, error) {
+		v, d, err := loader()
+		ttl = d
+		return v, err
+	}, []SetOption{expire}, false)
+
+	return v, err
+}
+
+// GetOrSetMany retrieves the entries at the specified keys, calling loader once with the keys that are missing
+// and storing the entries it returns in a single pass. loader runs without the cache locked.
+// It need not return an entry for every key it is passed; keys it leaves out are missing from the result,
+// and entries for keys it was not passed are ignored. Keys already being loaded by a concurrent call,
+// including GetOrSet and GetOrLoad, are not passed to loader, but waited for instead.
+// The options param is applied to each entry loader returns
+func (c *Cache[K, V]) GetOrSetMany(keys []K, loader func(keys []K) map[K]V, options ...SetOption) map[K]V {
+	p := c.claimMany(keys)
+	if len(p.missed) > 0 {
+		var loaded map[K]V
+		func() {
+			// fill even if loader panics, so the keys it was passed are not left loading forever
+			defer func() { c.fillMany(p, loaded, options) }()
+			loaded = loader(p.missed)
+		}()
+	}
+
+	return p.wait()
+}
+
+// A pendingMany holds the progress of a GetOrSetMany call:
+// the entries found, the keys it must load, and the loads for every missing key, its own and others'
+type pendingMany[K comparable, V any] struct {
+	found  map[K]V
+	missed []K
+	loads  map[K]*load[V]
+}
+
+// claimMany looks up the specified keys, and registers a load for each key that is missing
+// and not already being loaded
+func (c *Cache[K, V]) claimMany(keys []K) pendingMany[K, V] {
+	result := make(chan pendingMany[K, V], 1)
+	c.itemOp(func(items backend[K, V]) {
+		p := pendingMany[K, V]{found: make(map[K]V, len(keys)), loads: map[K]*load[V]{}}
+		for _, key := range keys {
+			if _, ok := p.found[key]; ok {
+				continue
+			}
+
+			if _, ok := p.loads[key]; ok {
+				continue
+			}
+
+			if v, ok := c.lookup(items, key); ok {
+				p.found[key] = v
+				continue
+			}
+
+			l, ok := c.loads[key]
+			if !ok {
+				l = &load[V]{done: make(chan struct{})}
+				c.loads[key] = l
+				p.missed = append(p.missed, key)
+			}
+
+			p.loads[key] = l
+		}
+
+		result <- p
+	})
+
+	return <-result
+}
+
+// fillMany stores the loaded entries for the keys p claimed in a single pass,
+// and then wakes every caller waiting on their loads
+func (c *Cache[K, V]) fillMany(p pendingMany[K, V], loaded map[K]V, options []SetOption) {
+	c.tryItemOp(func(items backend[K, V]) {
+		for _, key := range p.missed {
+			delete(c.loads, key)
+
+			// an entry set while the loader was running is newer than the loaded value, so keep it
+			l := p.loads[key]
+			if v, ok := c.find(items, key); ok {
+				l.val, l.ok = v, true
+				continue
+			}
+
+			if v, ok := loaded[key]; ok {
+				c.place(items, key, v, options)
+				l.val, l.ok = v, true
+			}
+		}
+	})
+
+	for _, key := range p.missed {
+		close(p.loads[key].done)
+	}
+}
+
+// wait waits for every load p is waiting on, and returns the entries found along with those loaded
+func (p pendingMany[K, V]) wait() map[K]V {
+	for key, l := range p.loads {
+		<-l.done
+		if l.ok {
+			p.found[key] = l.val
+		}
+	}
+
+	return p.found
+}
+
+// load calls the cache's loader for the specified key, unless WithNegativeCaching remembers it has no value
+// or the circuit breaker set by WithCircuitBreaker is open
+func (c *Cache[K, V]) load(key K) (V, bool) {
+	if c.negativeTTL > 0 && c.negative(key) {
+		c.stats.misses.Add(1)
+		c.log(LevelDebug, "miss", key, nil)
+		var zero V
+		return zero, false
+	}
+
+	v, ok, err := c.getOrLoad(key, func() (V, error) {
+		if c.circuit != nil && !c.circuit.allow(key) {
+			var zero V
+			return zero, ErrCircuitOpen
+		}
+
+		v, err := c.loader(key)
+		if c.circuit != nil {
+			c.circuit.record(key, err)
+		}
+
+		if c.negativeTTL > 0 && err == nil && isNil(v) {
+			return v, ErrNoValue
+		}
+
+		return v, err
+	}, nil, c.refreshAfter > 0)
+
+	if !ok && c.fallback != nil && errors.Is(err, ErrCircuitOpen) {
+		return c.fallback(key), true
+	}
+
+	return v, ok
+}
+
+// negative reports if the specified key has no entry and is remembered as having no value,
+// forgetting it once its negativeEntry has expired
+func (c *Cache[K, V]) negative(key K) bool {
+	result := make(chan bool, 1)
+	c.itemOp(func(items backend[K, V]) {
+		n, ok := c.negatives[key]
+		if !ok {
+			result <- false
+			return
+		}
+
+		if _, exists := c.find(items, key); exists || !time.Now().Before(n.expires) {
+			delete(c.negatives, key)
+			result <- false
+			return
+		}
+
+		result <- true
+	})
+
+	return <-result
+}
+
+// isNil reports if v is nil, or a nil pointer, slice, map, channel or func
+func isNil[V any](v V) bool {
+	rv := reflect.ValueOf(any(v))
+	if !rv.IsValid() {
+		return true
+	}
+
+	switch rv.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Pointer, reflect.Slice:
+		return rv.IsNil()
+	}
+
+	return false
+}
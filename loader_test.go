@@ -0,0 +1,464 @@
+package cache
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithLoader(t *testing.T) {
+	calls := 0
+	c := NewWithOptions(WithLoader(func(key string) (T, error) {
+		calls++
+		return "loaded " + key, nil
+	}))
+
+	c.Set("1", 1)
+	if result, expected := c.Get("1"), T(1); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	for i := 0; i < 2; i++ {
+		result, ok := c.GetOK("2")
+		if !ok {
+			t.Errorf("Entry was not loaded")
+		}
+
+		if expected := T("loaded 2"); result != expected {
+			t.Errorf("Result was %#v, expected %#v", result, expected)
+		}
+	}
+
+	if result, expected := calls, 1; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestWithLoaderError(t *testing.T) {
+	c := NewWithOptions(WithLoader(func(key string) (T, error) {
+		if key == "1" {
+			return nil, ErrNoValue
+		}
+
+		return nil, errors.New("failed")
+	}))
+
+	for _, key := range []string{"1", "2"} {
+		if _, ok := c.GetOK(key); ok {
+			t.Errorf("Entry %s was loaded", key)
+		}
+	}
+
+	if result, expected := c.Size(), 0; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestWithLoaderDefaultExpiry(t *testing.T) {
+	c := NewWithOptions(
+		WithDefaultExpiry(time.Hour),
+		WithLoader(func(key string) (T, error) { return 1, nil }))
+
+	c.Get("1")
+	if _, ok := c.RemainingTTL("1"); !ok {
+		t.Errorf("Loaded entry has no expiry")
+	}
+}
+
+func TestWithLoaderConcurrent(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	release := make(chan struct{})
+	c := NewWithOptions(WithLoader(func(key string) (T, error) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+
+		<-release
+		return 1, nil
+	}))
+
+	const callers = 10
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if result, expected := c.Get("1"), T(1); result != expected {
+				t.Errorf("Result was %#v, expected %#v", result, expected)
+			}
+		}()
+	}
+
+	time.Sleep(time.Millisecond * 10)
+	close(release)
+	wg.Wait()
+
+	if result, expected := calls, 1; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestWithSingleFlight(t *testing.T) {
+	c := NewWithOptions(WithSingleFlight())
+
+	var mu sync.Mutex
+	calls := 0
+	release := make(chan struct{})
+	fn := func() T {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+
+		<-release
+		return 1
+	}
+
+	const callers = 10
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if result, expected := c.GetOrSet("1", fn, Expire(time.Hour)), T(1); result != expected {
+				t.Errorf("Result was %#v, expected %#v", result, expected)
+			}
+		}()
+	}
+
+	time.Sleep(time.Millisecond * 10)
+
+	// fn runs without the cache locked, so other operations are not held up
+	c.Set("2", 2)
+	if result, expected := c.Get("2"), T(2); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	close(release)
+	wg.Wait()
+
+	if result, expected := calls, 1; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if _, ok := c.RemainingTTL("1"); !ok {
+		t.Errorf("Options were not applied")
+	}
+}
+
+func TestWithNegativeCaching(t *testing.T) {
+	calls := map[string]int{}
+	c := NewWithOptions(
+		WithNegativeCaching(time.Millisecond*50),
+		WithLoader(func(key string) (T, error) {
+			calls[key]++
+			if key == "nil" {
+				return nil, nil
+			}
+
+			return nil, ErrNoValue
+		}))
+
+	for i := 0; i < 3; i++ {
+		for _, key := range []string{"missing", "nil"} {
+			if _, ok := c.GetOK(key); ok {
+				t.Errorf("Entry %s was loaded", key)
+			}
+		}
+	}
+
+	if expected := map[string]int{"missing": 1, "nil": 1}; !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Result was %#v, expected %#v", calls, expected)
+	}
+
+	c.Set("missing", 1)
+	if result, expected := c.Get("missing"), T(1); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	time.Sleep(time.Millisecond * 60)
+	c.Get("nil")
+	if result, expected := calls["nil"], 2; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestWithNegativeCachingErrors(t *testing.T) {
+	calls := 0
+	c := NewWithOptions(
+		WithNegativeCaching(time.Hour),
+		WithLoader(func(key string) (T, error) {
+			calls++
+			return nil, errors.New("failed")
+		}))
+
+	c.Get("1")
+	c.Get("1")
+	if result, expected := calls, 2; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestGetOrLoad(t *testing.T) {
+	c := NewWithOptions(WithDefaultExpiry(time.Hour))
+
+	v, err := c.GetOrLoad("1", func() (T, time.Duration, error) { return 1, time.Minute, nil })
+	if err != nil || v != T(1) {
+		t.Errorf("Result was %#v, %v, expected %#v", v, err, 1)
+	}
+
+	if ttl, ok := c.RemainingTTL("1"); !ok || ttl > time.Minute {
+		t.Errorf("Result was %v, expected a TTL of up to %v", ttl, time.Minute)
+	}
+
+	v, err = c.GetOrLoad("1", func() (T, time.Duration, error) { return 2, 0, nil })
+	if err != nil || v != T(1) {
+		t.Errorf("Result was %#v, %v, expected %#v", v, err, 1)
+	}
+
+	c.GetOrLoad("2", func() (T, time.Duration, error) { return 2, 0, nil })
+	if ttl, ok := c.RemainingTTL("2"); !ok || ttl <= time.Minute {
+		t.Errorf("Result was %v, expected the default expiry of %v", ttl, time.Hour)
+	}
+
+	errLoad := errors.New("load failed")
+	if _, err := c.GetOrLoad("3", func() (T, time.Duration, error) { return 3, 0, errLoad }); err != errLoad {
+		t.Errorf("Error was %v, expected %v", err, errLoad)
+	}
+
+	if _, ok := c.GetOK("3"); ok {
+		t.Errorf("A failed load should not have stored an entry")
+	}
+}
+
+func TestGetOrLoadConcurrent(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	release := make(chan struct{})
+	c := New()
+	loader := func() (T, time.Duration, error) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+
+		<-release
+		return 1, time.Hour, nil
+	}
+
+	const callers = 10
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if result, err := c.GetOrLoad("1", loader); err != nil || result != T(1) {
+				t.Errorf("Result was %#v, %v, expected %#v", result, err, 1)
+			}
+		}()
+	}
+
+	time.Sleep(time.Millisecond * 10)
+	close(release)
+	wg.Wait()
+
+	if result, expected := calls, 1; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestGetOrSetMany(t *testing.T) {
+	c := New()
+	c.Set("1", 1)
+
+	var passed []string
+	loader := func(keys []string) map[string]T {
+		passed = keys
+		return map[string]T{"2": 2, "4": 4}
+	}
+
+	result := c.GetOrSetMany([]string{"1", "2", "3", "2"}, loader, Expire(time.Hour))
+	if expected := map[string]T{"1": 1, "2": 2}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if expected := []string{"2", "3"}; !reflect.DeepEqual(passed, expected) {
+		t.Errorf("Loader was passed %#v, expected %#v", passed, expected)
+	}
+
+	if result, expected := c.Keys(), []string{"1", "2"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if _, ok := c.RemainingTTL("2"); !ok {
+		t.Errorf("Loaded entry for key '2' should have had the Expire option applied")
+	}
+
+	passed = nil
+	c.GetOrSetMany([]string{"1", "2"}, loader)
+	if passed != nil {
+		t.Errorf("Loader should not have been called, but was passed %#v", passed)
+	}
+}
+
+func TestGetOrSetManyConcurrent(t *testing.T) {
+	var mu sync.Mutex
+	calls := map[string]int{}
+	release := make(chan struct{})
+	c := NewSharded(WithShards(4))
+	loader := func(keys []string) map[string]T {
+		mu.Lock()
+		loaded := map[string]T{}
+		for _, key := range keys {
+			calls[key]++
+			loaded[key] = key
+		}
+
+		mu.Unlock()
+
+		<-release
+		return loaded
+	}
+
+	const callers = 10
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			expected := map[string]T{"1": "1", "2": "2", "3": "3"}
+			if result := c.GetOrSetMany([]string{"1", "2", "3"}, loader); !reflect.DeepEqual(result, expected) {
+				t.Errorf("Result was %#v, expected %#v", result, expected)
+			}
+		}()
+	}
+
+	time.Sleep(time.Millisecond * 10)
+	close(release)
+	wg.Wait()
+
+	if expected := map[string]int{"1": 1, "2": 1, "3": 1}; !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Result was %#v, expected %#v", calls, expected)
+	}
+}
+
+func TestWithRefreshAfterWrite(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	failing := false
+	c := NewWithOptions(
+		WithRefreshAfterWrite(time.Millisecond*20),
+		WithLoader(func(key string) (T, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls++
+			if failing {
+				return nil, errors.New("failed")
+			}
+
+			return calls, nil
+		}))
+
+	callCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls
+	}
+
+	if result, expected := c.Get("1"), T(1); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	time.Sleep(time.Millisecond * 30)
+	if result, expected := c.Get("1"), T(1); result != expected {
+		t.Errorf("Stale result was %#v, expected %#v", result, expected)
+	}
+
+	time.Sleep(time.Millisecond * 10)
+	if result, expected := c.Get("1"), T(2); result != expected {
+		t.Errorf("Refreshed result was %#v, expected %#v", result, expected)
+	}
+
+	mu.Lock()
+	failing = true
+	mu.Unlock()
+
+	time.Sleep(time.Millisecond * 30)
+	c.Get("1")
+	time.Sleep(time.Millisecond * 10)
+	if result, expected := c.Get("1"), T(2); result != expected {
+		t.Errorf("Result was %#v after a failed refresh, expected %#v", result, expected)
+	}
+
+	time.Sleep(time.Millisecond * 10)
+	if result, expected := callCount(), 4; result != expected {
+		t.Errorf("Loader was called %d times, expected %d", result, expected)
+	}
+}
+
+func TestWithLoaderPanic(t *testing.T) {
+	panicking := true
+	c := NewWithOptions(WithLoader(func(key string) (T, error) {
+		if panicking {
+			panic("boom")
+		}
+
+		return "loaded " + key, nil
+	}))
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("Recovered %v, expected %v", r, "boom")
+			}
+		}()
+
+		c.Get("1")
+	}()
+
+	panicking = false
+	if result, expected := c.Get("1"), T("loaded 1"); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestWithRefreshAfterWritePanic(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	c := NewWithOptions(
+		WithRefreshAfterWrite(time.Millisecond*10),
+		WithLoader(func(key string) (T, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls++
+			if calls == 2 {
+				panic("boom")
+			}
+
+			return calls, nil
+		}))
+
+	c.Get("1")
+	time.Sleep(time.Millisecond * 20)
+	if result, expected := c.Get("1"), T(1); result != expected {
+		t.Errorf("Stale result was %#v, expected %#v", result, expected)
+	}
+
+	time.Sleep(time.Millisecond * 10)
+	c.Get("1")
+	time.Sleep(time.Millisecond * 10)
+	if result, expected := c.Get("1"), T(3); result != expected {
+		t.Errorf("Result was %#v after a panicked refresh, expected %#v", result, expected)
+	}
+}
+
+func TestWithRefreshAfterWriteWithoutLoader(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("NewWithOptions should have panicked without WithLoader")
+		}
+	}()
+
+	NewWithOptions(WithRefreshAfterWrite(time.Second))
+}
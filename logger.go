@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Levels a Logger is called with
+const (
+	// LevelDebug is used for reads, logged as "hit" or "miss"
+	LevelDebug = "debug"
+	// LevelInfo is used for entries that leave the cache, logged as "evict" with the EvictionReason
+	LevelInfo = "info"
+	// LevelError is used for errors the cache cannot return to a caller, such as a failed load
+	LevelError = "error"
+)
+
+// A Logger is passed to WithLogger to receive cache events.
+// fields holds details such as the entry's "key". A Logger must be safe for concurrent use
+type Logger interface {
+	Log(level, msg string, fields map[string]interface{})
+}
+
+// NewStdLogger returns a Logger that writes each event to os.Stderr using the standard log package,
+// with lines starting with prefix
+func NewStdLogger(prefix string) Logger {
+	return stdLogger{log.New(os.Stderr, prefix, log.LstdFlags)}
+}
+
+type stdLogger struct {
+	l *log.Logger
+}
+
+func (s stdLogger) Log(level, msg string, fields map[string]interface{}) {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s", level, msg)
+	for _, name := range names {
+		fmt.Fprintf(&b, " %s=%v", name, fields[name])
+	}
+
+	s.l.Print(b.String())
+}
+
+// log passes an event about the entry at key to the cache's Logger, if it has one
+func (c *Cache[K, V]) log(level, msg string, key K, fields map[string]interface{}) {
+	if c.logger == nil {
+		return
+	}
+
+	if fields == nil {
+		fields = map[string]interface{}{}
+	}
+
+	fields["key"] = key
+	c.logger.Log(level, msg, fields)
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type logEntry struct {
+	level, msg string
+	fields     map[string]interface{}
+}
+
+type testLogger struct {
+	mu      sync.Mutex
+	entries []logEntry
+}
+
+func (l *testLogger) Log(level, msg string, fields map[string]interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, logEntry{level, msg, fields})
+}
+
+func TestWithLogger(t *testing.T) {
+	l := &testLogger{}
+	c := NewWithOptions(WithLogger(l))
+	c.Set("1", 1)
+	c.Get("1")
+	c.Get("2")
+	c.Delete("1")
+
+	expected := []logEntry{
+		{LevelDebug, "hit", map[string]interface{}{"key": "1"}},
+		{LevelDebug, "miss", map[string]interface{}{"key": "2"}},
+		{LevelInfo, "evict", map[string]interface{}{"key": "1", "reason": "manual"}},
+	}
+
+	if result := l.entries; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestWithLoggerLoadError(t *testing.T) {
+	errLoad := errors.New("load")
+
+	l := &testLogger{}
+	c := NewWithOptions(WithLogger(l), WithLoader(func(key string) (T, error) {
+		if key == "missing" {
+			return nil, ErrNoValue
+		}
+
+		return nil, errLoad
+	}))
+
+	c.Get("missing")
+	c.Get("1")
+
+	var errs []logEntry
+	for _, e := range l.entries {
+		if e.level == LevelError {
+			errs = append(errs, e)
+		}
+	}
+
+	expected := []logEntry{{LevelError, "load failed", map[string]interface{}{"key": "1", "error": errLoad}}}
+	if !reflect.DeepEqual(errs, expected) {
+		t.Errorf("Result was %#v, expected %#v", errs, expected)
+	}
+}
+
+func TestStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := stdLogger{log.New(&buf, "cache: ", 0)}
+	l.Log(LevelInfo, "evict", map[string]interface{}{"reason": "manual", "key": "1"})
+
+	if result, expected := buf.String(), "cache: info evict key=1 reason=manual\n"; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
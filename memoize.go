@@ -0,0 +1,14 @@
+package cache
+
+// Memoize returns a function that caches the results of fn, keyed by its argument, in a cache created with options.
+// fn is called at most once per key while its result is cached: concurrent calls with the same missing key
+// share a single call to fn, as misses on a cache created with WithLoader do. Results are kept until they expire,
+// as set by WithDefaultExpiry, or are evicted to keep within a limit such as WithMaxSize.
+// The cache is never closed, so options starting a goroutine, such as WithCleanupInterval, keep it running
+func Memoize[K comparable, V any](fn func(key K) V, options ...CacheOption) func(K) V {
+	c := NewCache[K, V](options...)
+	return func(key K) V {
+		v, _, _ := c.getOrLoad(key, func() (V, error) { return fn(key), nil }, nil, false)
+		return v
+	}
+}
@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoize(t *testing.T) {
+	var mu sync.Mutex
+	calls := map[string]int{}
+	square := Memoize(func(key string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		calls[key]++
+
+		n, _ := strconv.Atoi(key)
+		return n * n
+	}, WithDefaultExpiry(time.Millisecond*50))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, key := range []string{"2", "3"} {
+				square(key)
+			}
+		}()
+	}
+
+	wg.Wait()
+	if result, expected := square("3"), 9; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	for key, n := range calls {
+		if n != 1 {
+			t.Errorf("fn was called %d times for key %s, expected once", n, key)
+		}
+	}
+
+	time.Sleep(time.Millisecond * 60)
+	square("2")
+	if result, expected := calls["2"], 2; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
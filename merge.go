@@ -0,0 +1,39 @@
+package cache
+
+// Merge copies every entry in other that has not expired into the cache.
+// Where both caches hold an entry at the same key, conflict is called with both values and its result is stored;
+// a nil conflict keeps the value from other. Entries that expire in other expire at the same time in the cache,
+// while those that never expire in other lose any expiry they had in the cache, and get its default expiry instead.
+// other is read in a single pass before anything is stored, so concurrent changes to it are either fully
+// merged or not at all. Since conflict runs while the cache is locked, it must not call back into the cache.
+func (c *Cache[K, V]) Merge(other *Cache[K, V], conflict func(key K, mine, theirs V) V) {
+	c.merge(other.entries(), conflict)
+}
+
+// merge stores entries in the cache along with their expiries in a single pass,
+// resolving conflicts with existing entries by calling conflict
+func (c *Cache[K, V]) merge(entries []entry[K, V], conflict func(key K, mine, theirs V) V) {
+	c.itemOp(func(items backend[K, V]) {
+		for _, e := range entries {
+			if mine, ok := c.find(items, e.Key); ok && conflict != nil {
+				e.Value = conflict(e.Key, mine, e.Value)
+			}
+
+			c.placeEntry(items, e)
+		}
+	})
+}
+
+// Merge copies every entry in other that has not expired into the cache. See Cache.Merge.
+// Each shard of other is read in a single pass, and entries are stored one shard at a time
+func (c *ShardedCache[K, V]) Merge(other *ShardedCache[K, V], conflict func(key K, mine, theirs V) V) {
+	groups := map[*Cache[K, V]][]entry[K, V]{}
+	for _, e := range other.entries() {
+		shard := c.shard(e.Key)
+		groups[shard] = append(groups[shard], e)
+	}
+
+	for shard, group := range groups {
+		shard.merge(group, conflict)
+	}
+}
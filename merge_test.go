@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMerge(t *testing.T) {
+	mine := New()
+	mine.Set("1", 1)
+	mine.Set("2", 2)
+
+	theirs := New()
+	theirs.Set("2", 20)
+	theirs.Set("3", 30, Expire(time.Hour))
+
+	mine.Merge(theirs, func(key string, mine, theirs T) T {
+		return mine.(int) + theirs.(int)
+	})
+
+	if result, expected := mine.Items(), map[string]T{"1": 1, "2": 22, "3": 30}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if _, ok := mine.RemainingTTL("3"); !ok {
+		t.Errorf("Expiry was not merged")
+	}
+}
+
+func TestMergeClearsExpiry(t *testing.T) {
+	mine := New()
+	mine.Set("1", 1, Expire(time.Millisecond*10))
+
+	theirs := New()
+	theirs.Set("1", 10)
+
+	mine.Merge(theirs, nil)
+	if _, ok := mine.RemainingTTL("1"); ok {
+		t.Errorf("Expiry for key '1' should have been cleared")
+	}
+
+	time.Sleep(time.Millisecond * 20)
+
+	if result, expected := mine.Get("1"), T(10); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestMergeNilConflict(t *testing.T) {
+	mine := New()
+	mine.Set("1", 1)
+
+	theirs := New()
+	theirs.Set("1", 10)
+
+	mine.Merge(theirs, nil)
+	if result, expected := mine.Get("1"), T(10); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
@@ -0,0 +1,358 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// NewNoop returns a StringCacher that discards every entry stored in it, so every key is always missing.
+// It is useful for disabling caching, for example in tests, without changing the code that uses the cache.
+// Unlike a cache from New, it holds no state and never starts a goroutine or a timer
+func NewNoop() StringCacher {
+	return NewNoopCache[string, T]()
+}
+
+// NewNoopCache returns a Cacher holding values of type V at keys of type K that discards every entry stored in it.
+// See NewNoop
+func NewNoopCache[K comparable, V any]() Cacher[K, V] {
+	return noopCache[K, V]{}
+}
+
+// noopCache is a Cacher that behaves as a cache which is always empty.
+// Calls that would store an entry report success where a missing entry would let them succeed,
+// and return the value that would have been stored
+type noopCache[K comparable, V any] struct{}
+
+func (noopCache[K, V]) Stats() CacheStats {
+	return CacheStats{}
+}
+
+func (noopCache[K, V]) ResetStats() {}
+
+func (noopCache[K, V]) Set(key K, val V, options ...SetOption) {}
+
+func (noopCache[K, V]) SetE(key K, val V, options ...SetOption) error {
+	return nil
+}
+
+func (noopCache[K, V]) SetMany(entries map[K]V, options ...SetOption) {}
+
+func (noopCache[K, V]) MSet(entries map[K]V) error {
+	return nil
+}
+
+func (noopCache[K, V]) SetBatch(entries []BatchEntry[K, V]) {}
+
+func (noopCache[K, V]) SetCtx(ctx context.Context, key K, val V, options ...SetOption) error {
+	return ctx.Err()
+}
+
+func (noopCache[K, V]) SetWithTags(key K, val V, tags []string, options ...SetOption) {}
+
+func (noopCache[K, V]) GetOrSet(key K, fn func() V, options ...SetOption) V {
+	return fn()
+}
+
+func (noopCache[K, V]) GetOrLoad(key K, loader func() (V, time.Duration, error)) (V, error) {
+	v, _, err := loader()
+	return v, err
+}
+
+// GetOrSetMany calls loader with every key, since every key is missing, and returns the entries it returns for them
+func (noopCache[K, V]) GetOrSetMany(keys []K, loader func(keys []K) map[K]V, options ...SetOption) map[K]V {
+	found := make(map[K]V, len(keys))
+	if len(keys) == 0 {
+		return found
+	}
+
+	loaded := loader(keys)
+	for _, key := range keys {
+		if v, ok := loaded[key]; ok {
+			found[key] = v
+		}
+	}
+
+	return found
+}
+
+func (noopCache[K, V]) SetIfAbsent(key K, val V, options ...SetOption) bool {
+	return true
+}
+
+func (noopCache[K, V]) SetDefault(key K, val V, options ...SetOption) bool {
+	return true
+}
+
+func (noopCache[K, V]) SetNX(key K, val V, options ...SetOption) bool {
+	return true
+}
+
+func (noopCache[K, V]) SetIfPresent(key K, val V, options ...SetOption) bool {
+	return false
+}
+
+func (noopCache[K, V]) SetXX(key K, val V, options ...SetOption) bool {
+	return false
+}
+
+func (noopCache[K, V]) GetAndSet(key K, val V, options ...SetOption) (V, bool) {
+	var zero V
+	return zero, false
+}
+
+func (noopCache[K, V]) CompareAndSwap(key K, oldVal, newVal V, options ...SetOption) bool {
+	return false
+}
+
+func (noopCache[K, V]) Increment(key K, delta int64) (int64, error) {
+	return delta, nil
+}
+
+func (noopCache[K, V]) Decrement(key K, delta int64) (int64, error) {
+	return -delta, nil
+}
+
+func (noopCache[K, V]) Append(key K, suffix string) (string, error) {
+	return suffix, nil
+}
+
+func (noopCache[K, V]) Modify(key K, fn func(current V) V) bool {
+	return false
+}
+
+func (noopCache[K, V]) Touch(key K, d time.Duration) bool {
+	return false
+}
+
+func (noopCache[K, V]) ExpireAt(key K, t time.Time) bool {
+	return false
+}
+
+func (noopCache[K, V]) Expire(key K, d time.Duration) bool {
+	return false
+}
+
+func (noopCache[K, V]) Persist(key K) bool {
+	return false
+}
+
+func (noopCache[K, V]) Rename(oldKey, newKey K) bool {
+	return false
+}
+
+func (noopCache[K, V]) RemainingTTL(key K) (time.Duration, bool) {
+	return 0, false
+}
+
+func (noopCache[K, V]) TTL(key K) (time.Duration, error) {
+	return TTLMissing, nil
+}
+
+func (noopCache[K, V]) Clear() {}
+
+// ClearEvery starts no loop, since there is never anything to clear, and returns a stop func that does nothing
+func (noopCache[K, V]) ClearEvery(d time.Duration) (stop func()) {
+	return func() {}
+}
+
+func (noopCache[K, V]) Delete(key K) {}
+
+func (noopCache[K, V]) DeleteCtx(ctx context.Context, key K) error {
+	return ctx.Err()
+}
+
+func (noopCache[K, V]) DeleteMany(keys []K) int {
+	return 0
+}
+
+func (noopCache[K, V]) DeletePrefix(prefix string) int {
+	return 0
+}
+
+func (noopCache[K, V]) DeleteSuffix(suffix string) int {
+	return 0
+}
+
+func (noopCache[K, V]) DeleteContains(substr string) int {
+	return 0
+}
+
+func (noopCache[K, V]) DeleteByTag(tag string) int {
+	return 0
+}
+
+func (noopCache[K, V]) GetAndDelete(key K) (V, bool) {
+	var zero V
+	return zero, false
+}
+
+func (noopCache[K, V]) Evict(key K) (V, bool) {
+	var zero V
+	return zero, false
+}
+
+func (noopCache[K, V]) CompareAndDelete(key K, expected V) bool {
+	return false
+}
+
+func (noopCache[K, V]) Get(key K) V {
+	var zero V
+	return zero
+}
+
+func (noopCache[K, V]) GetOK(key K) (V, bool) {
+	var zero V
+	return zero, false
+}
+
+func (noopCache[K, V]) Contains(key K) bool {
+	return false
+}
+
+func (noopCache[K, V]) Probabilistic(key K, beta float64) (V, bool) {
+	var zero V
+	return zero, false
+}
+
+func (noopCache[K, V]) GetCtx(ctx context.Context, key K) (V, error) {
+	var zero V
+	return zero, ctx.Err()
+}
+
+func (noopCache[K, V]) GetMany(keys []K) map[K]V {
+	return map[K]V{}
+}
+
+func (noopCache[K, V]) MGet(keys []K) (map[K]V, error) {
+	return map[K]V{}, nil
+}
+
+// Watch returns a channel that never receives an event, since no entry is ever stored
+func (noopCache[K, V]) Watch(key K) (<-chan WatchEvent[K, V], func()) {
+	ch := make(chan WatchEvent[K, V])
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() { close(ch) })
+	}
+}
+
+// ClearExpired returns 0, since no entry is ever stored
+func (noopCache[K, V]) ClearExpired() int {
+	return 0
+}
+
+// WatchPrefix returns a channel that never receives an event, since no entry is ever stored
+func (n noopCache[K, V]) WatchPrefix(prefix string) (<-chan WatchEvent[K, V], func()) {
+	var zero K
+	return n.Watch(zero)
+}
+
+// WaitForKey blocks until ctx is done, since no entry is ever stored
+func (noopCache[K, V]) WaitForKey(ctx context.Context, key K) (V, error) {
+	<-ctx.Done()
+
+	var zero V
+	return zero, ctx.Err()
+}
+
+func (noopCache[K, V]) Items() map[K]V {
+	return map[K]V{}
+}
+
+func (noopCache[K, V]) Dump() map[K]CacheEntry[V] {
+	return map[K]CacheEntry[V]{}
+}
+
+func (noopCache[K, V]) Values() []V {
+	return []V{}
+}
+
+func (noopCache[K, V]) ForEach(fn func(key K, val V)) {}
+
+func (noopCache[K, V]) FilterItems(predicate func(K, V) bool) map[K]V {
+	return map[K]V{}
+}
+
+func (noopCache[K, V]) Count(predicate func(K, V) bool) int {
+	return 0
+}
+
+func (noopCache[K, V]) IsEmpty() bool {
+	return true
+}
+
+func (noopCache[K, V]) Size() int {
+	return 0
+}
+
+func (noopCache[K, V]) Keys() []K {
+	return []K{}
+}
+
+func (noopCache[K, V]) UnsortedKeys() []K {
+	return []K{}
+}
+
+func (noopCache[K, V]) KeysExpiringSoon(within time.Duration) []K {
+	return []K{}
+}
+
+func (noopCache[K, V]) ExpiredKeys() []K {
+	return []K{}
+}
+
+func (noopCache[K, V]) Scan(cursor, count int) (int, []K) {
+	return 0, nil
+}
+
+func (noopCache[K, V]) FilterKeys(predicate func(K) bool) []K {
+	return []K{}
+}
+
+// ExportJSON writes an empty cache to w
+func (noopCache[K, V]) ExportJSON(w io.Writer) error {
+	return exportJSON(w, []entry[K, V]{})
+}
+
+func (noopCache[K, V]) ImportJSON(r io.Reader) error {
+	return nil
+}
+
+// ExportGob writes an empty cache to w
+func (noopCache[K, V]) ExportGob(w io.Writer) error {
+	return exportGob(w, []entry[K, V]{})
+}
+
+func (noopCache[K, V]) ImportGob(r io.Reader) error {
+	return nil
+}
+
+// SaveToFile writes an empty cache to the file at path
+func (c noopCache[K, V]) SaveToFile(path string) error {
+	return saveToFile(path, c.ExportGob)
+}
+
+func (noopCache[K, V]) LoadFromFile(path string) error {
+	return nil
+}
+
+// Snapshot returns a snapshot of an empty cache
+func (noopCache[K, V]) Snapshot() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := exportGob(&buf, []entry[K, V]{}); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (noopCache[K, V]) Restore(data []byte) error {
+	return nil
+}
+
+func (noopCache[K, V]) Close() error {
+	return nil
+}
@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestNoop(t *testing.T) {
+	c := NewNoop()
+	c.Set("1", 1)
+	c.SetMany(map[string]T{"2": 2})
+
+	if result, ok := c.GetOK("1"); ok || result != nil {
+		t.Errorf("Result was %#v, %v, expected nil, false", result, ok)
+	}
+
+	if result := c.GetOrSet("1", func() T { return 3 }); result != 3 {
+		t.Errorf("Result was %#v, expected 3", result)
+	}
+
+	if result, expected := c.Keys(), []string{}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if !c.IsEmpty() || c.Size() != 0 {
+		t.Errorf("Cache should be empty")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	if _, err := c.WaitForKey(ctx, "1"); err != context.DeadlineExceeded {
+		t.Errorf("Error was %v, expected %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestNoopNoGoroutines(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	c := NewNoop()
+	c.Set("1", 1, Expire(time.Millisecond))
+	c.ClearEvery(time.Millisecond)
+	c.Close()
+
+	if after := runtime.NumGoroutine(); after != before {
+		t.Errorf("Goroutines went from %d to %d, expected no change", before, after)
+	}
+}
+
+func TestNoopExport(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewNoop().ExportGob(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	c := New()
+	if err := c.ImportGob(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if size := c.Size(); size != 0 {
+		t.Errorf("Cache size was %d, expected 0", size)
+	}
+}
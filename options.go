@@ -2,34 +2,397 @@ package cache
 
 import "time"
 
+// A CacheOption configures a cache created by NewWithOptions
+type CacheOption func(o *cacheOptions)
+
+// cacheOptions holds the configuration collected from a list of CacheOptions.
+// Since CacheOptions do not depend on a cache's key and value types, policy, onEvict, onSet, onDelete
+// and loader are held as any, and checked against those types when the cache is created
+type cacheOptions struct {
+	defaultExpiry time.Duration
+	maxSize       int
+	maxMemory     int64
+	maxWeight     int
+	weigher       any
+	policy        any
+	fifo          bool
+	onEvict       any
+	onSet         any
+	onDelete      any
+
+	cleanupInterval time.Duration
+	ttlBuckets      int
+	shards          int
+	loader          any
+	singleFlight    bool
+	syncMap         bool
+	logger          Logger
+	initialCapacity int
+	negativeTTL     time.Duration
+	refreshAfter    time.Duration
+	maxFailures     int
+	resetAfter      time.Duration
+	circuitPerKey   bool
+	fallback        any
+
+	writeBehind      any
+	writeBehindDelay time.Duration
+	writeThrough     any
+}
+
+func newCacheOptions(options []CacheOption) cacheOptions {
+	opts := cacheOptions{}
+	for _, option := range options {
+		option(&opts)
+	}
+
+	return opts
+}
+
+// WithDefaultExpiry is a CacheOption that causes every entry to expire after the specified duration
+// unless an Expire or AfterFunc option is passed when it is set
+func WithDefaultExpiry(expiry time.Duration) CacheOption {
+	return func(o *cacheOptions) {
+		o.defaultExpiry = expiry
+	}
+}
+
+// WithMaxSize is a CacheOption that limits the cache to n entries.
+// When storing an entry would exceed the limit, an entry is evicted as chosen by the cache's
+// EvictionPolicy, which defaults to evicting the least recently used entry.
+// A non-positive n leaves the cache unbounded
+func WithMaxSize(n int) CacheOption {
+	return func(o *cacheOptions) {
+		o.maxSize = n
+	}
+}
+
+// WithFIFO is a CacheOption that limits the cache to maxSize entries, like WithMaxSize,
+// but evicts the entry that was inserted first rather than the least recently used one.
+// Reading or overwriting an entry does not affect when it is evicted.
+// Unlike WithEvictionPolicy(NewFIFO()), each shard of a sharded cache gets its own policy.
+// A policy passed to WithEvictionPolicy takes precedence
+func WithFIFO(maxSize int) CacheOption {
+	return func(o *cacheOptions) {
+		o.maxSize = maxSize
+		o.fifo = true
+	}
+}
+
+// WithMaxMemory is a CacheOption that limits the cache to roughly the specified number of bytes.
+// When storing an entry takes the cache over the limit, entries are evicted as chosen by the cache's
+// EvictionPolicy, as with WithMaxSize, until it is back under it. An entry larger than the limit on its own
+// is evicted as soon as it is stored, leaving the other entries in place.
+// The size of each entry is estimated when it is stored by walking its key and value with reflection,
+// counting the strings, slices, maps and pointers they reference. The estimate is approximate:
+// it ignores allocator and map overhead, counts memory shared between entries once per entry,
+// and does not notice values modified after they were stored. Pass WithWeigher to size entries exactly instead.
+// A non-positive bytes leaves the cache unbounded
+func WithMaxMemory(bytes int64) CacheOption {
+	return func(o *cacheOptions) {
+		o.maxMemory = bytes
+	}
+}
+
+// WithMaxWeight is a CacheOption that limits the total weight of the entries in the cache,
+// as assigned by the function passed to WithWeigher, evicting entries as chosen by the cache's
+// EvictionPolicy while it is over the limit. An entry heavier than the limit on its own is evicted
+// as soon as it is stored, leaving the other entries in place. Without WithWeigher every entry weighs 1.
+// It takes precedence over WithMaxMemory. A non-positive max leaves the cache unbounded
+func WithMaxWeight(max int) CacheOption {
+	return func(o *cacheOptions) {
+		o.maxWeight = max
+	}
+}
+
+// WithWeigher is a CacheOption that sets the function giving the weight of each entry
+// for WithMaxWeight, or its size in bytes for WithMaxMemory. Weights can be in any unit,
+// such as bytes or the cost of recomputing the value; negative weights count as 0.
+// fn is called once when the entry is stored, so changes to a stored value do not change its weight
+func WithWeigher[K comparable, V any](fn func(key K, val V) int) CacheOption {
+	return func(o *cacheOptions) {
+		o.weigher = fn
+	}
+}
+
+// WithEvictionPolicy is a CacheOption that sets the policy used to choose which entry to evict
+// once the cache reaches the limit set by WithMaxSize, WithMaxMemory or WithMaxWeight. It has no effect on unbounded caches
+func WithEvictionPolicy[K comparable](p Policy[K]) CacheOption {
+	return func(o *cacheOptions) {
+		o.policy = p
+	}
+}
+
+// WithOnEvict is a CacheOption that causes fn to be called whenever an entry is removed from the cache,
+// whether it expired, was evicted to make room, or was removed by Delete, Evict, Clear or a similar call.
+// The reason param says which of these happened. Entries removed by GetAndDelete are not passed to fn.
+// fn is called once the operation that removed the entry has completed, so it may call back into the cache.
+func WithOnEvict[K comparable, V any](fn func(key K, val V, reason EvictionReason)) CacheOption {
+	return func(o *cacheOptions) {
+		o.onEvict = fn
+	}
+}
+
+// WithOnSet is a CacheOption that causes fn to be called whenever an entry is stored in the cache.
+// The replaced param specifies if the entry overwrote an existing one, in which case old holds
+// the previous value; otherwise old is the zero value.
+// fn is called once the operation that stored the entry has completed, so it may call back into the cache.
+func WithOnSet[K comparable, V any](fn func(key K, old, new V, replaced bool)) CacheOption {
+	return func(o *cacheOptions) {
+		o.onSet = fn
+	}
+}
+
+// WithOnDelete is a CacheOption that causes fn to be called with the removed value whenever
+// an entry is removed by Delete, Clear or a similar call, or because it expired.
+// Unlike WithOnEvict, fn is not called for entries evicted to make room in a bounded cache.
+// fn is called once the operation that removed the entry has completed, so it may call back into the cache.
+func WithOnDelete[K comparable, V any](fn func(key K, val V)) CacheOption {
+	return func(o *cacheOptions) {
+		o.onDelete = fn
+	}
+}
+
+// WithWriteBehind is a CacheOption that passes every entry stored in the cache to fn, for writing to a backing store,
+// from a separate goroutine instead of the call that stored it. Stored entries are queued and flushed in batches,
+// at most once per maxDelay, and an entry stored several times before its batch is flushed is passed to fn only
+// with its latest value. Removing an entry does not remove it from the queue.
+// Close flushes the entries still queued before it returns. fn is called after the cache may have been closed,
+// so it must not call back into the cache
+func WithWriteBehind[K comparable, V any](fn func(key K, val V), maxDelay time.Duration) CacheOption {
+	return func(o *cacheOptions) {
+		o.writeBehind = fn
+		o.writeBehindDelay = maxDelay
+	}
+}
+
+// WithWriteThrough is a CacheOption that passes every entry stored in the cache to fn, for writing to a backing store.
+// Calls given the value to store, such as Set, SetMany, SetBatch, GetAndSet, SetWithTags and Pipeline.Execute,
+// pass each entry to fn before storing it, and if fn returns an error, the entry is not stored: SetE, SetCtx and MSet
+// return the error, while the others log it at LevelError. Calls that decide what to store while the cache is locked,
+// such as GetOrSet, SetIfAbsent, SetIfPresent, CompareAndSwap, Rename, Increment, Append, Modify, Merge, imports
+// and loads, pass the entry to fn once the cache has been unlocked instead; if fn returns an error, it is logged
+// at LevelError and the entry is removed, unless it has been replaced since. See Transaction for transactions.
+// fn is called without the cache locked, so it may call back into the cache
+func WithWriteThrough[K comparable, V any](fn func(key K, val V) error) CacheOption {
+	return func(o *cacheOptions) {
+		o.writeThrough = fn
+	}
+}
+
+// WithCleanupInterval is a CacheOption that switches the cache to lazy expiry.
+// Rather than starting a timer for each entry, Expire and AfterFunc record a deadline, and a background
+// sweep removes entries whose deadline has passed once every d. This is much cheaper for caches holding
+// many short-lived entries, though an expired entry is only removed, and its OnEvict and AfterFunc callbacks called,
+// by the next sweep or by a write at its key. Until then, reads and conditional writes treat it as missing.
+func WithCleanupInterval(d time.Duration) CacheOption {
+	return func(o *cacheOptions) {
+		o.cleanupInterval = d
+	}
+}
+
+// WithTTLBuckets is a CacheOption that groups expiry timers into buckets. Each entry's deadline is rounded up
+// to the next multiple of its TTL divided by buckets, and the entries sharing a deadline are removed together
+// by a single timer, so entries set with the same TTL need at most about buckets timers between them,
+// however many there are. In exchange, an entry may live up to its TTL divided by buckets longer than it was set to.
+// It has no effect with WithCleanupInterval, and a non-positive buckets starts a timer for each entry
+func WithTTLBuckets(buckets int) CacheOption {
+	return func(o *cacheOptions) {
+		o.ttlBuckets = buckets
+	}
+}
+
+// WithShards is a CacheOption that sets the number of shards in a cache created by NewSharded.
+// It has no effect on caches created by NewWithOptions
+func WithShards(n int) CacheOption {
+	return func(o *cacheOptions) {
+		o.shards = n
+	}
+}
+
 // A SetOption will perform logic after a set action completes
-type SetOption func(c *Cache, key string, val T)
+type SetOption func(e setEntry)
+
+// A setEntry is the entry a SetOption applies to, whatever the key and value types of its cache
+type setEntry interface {
+	// expire causes the entry to expire after d, then calls after with its value if after is not nil
+	expire(d time.Duration, after func(val T))
+	// computed records that the entry's value took d to compute
+	computed(d time.Duration)
+	// delete removes the entry
+	delete()
+}
 
 // Expire is a SetOption that will cause the entry to expire after the specified duration
 func Expire(expiry time.Duration) SetOption {
-	return func(c *Cache, key string, val T) {
-		c.expiryOps <- func(expiries map[string]*time.Timer) {
-			if timer, ok := expiries[key]; ok {
-				timer.Stop()
-			}
+	return func(e setEntry) {
+		e.expire(expiry, nil)
+	}
+}
 
-			expiries[key] = time.AfterFunc(expiry, func() { c.Delete(key) })
+// ExpireAtTime is a SetOption that will cause the entry to expire at the specified deadline.
+// If the deadline has already passed, the entry is removed immediately
+func ExpireAtTime(deadline time.Time) SetOption {
+	return func(e setEntry) {
+		d := time.Until(deadline)
+		if d <= 0 {
+			e.delete()
+			return
 		}
+
+		e.expire(d, nil)
+	}
+}
+
+// ComputeTime is a SetOption that records that computing the entry's value took d,
+// for Probabilistic to weigh when deciding to report the entry missing ahead of its expiry.
+// Values stored by GetOrSet or loaded through WithLoader have their compute time measured automatically
+func ComputeTime(d time.Duration) SetOption {
+	return func(e setEntry) {
+		e.computed(d)
 	}
 }
 
 // AfterFunc is a SetOption that will cause the entry to expire and call a supplied function
 func AfterFunc(expiry time.Duration, afterFunc func(T)) SetOption {
-	return func(c *Cache, key string, val T) {
-		c.expiryOps <- func(expiries map[string]*time.Timer) {
-			if timer, ok := expiries[key]; ok {
-				timer.Stop()
-			}
-
-			expiries[key] = time.AfterFunc(expiry, func() {
-				c.Delete(key)
-				afterFunc(val)
-			})
-		}
+	return func(e setEntry) {
+		e.expire(expiry, afterFunc)
+	}
+}
+
+// EntryOptions holds what a list of SetOptions asks of an entry,
+// for implementations of Cacher outside this package, which cannot apply SetOptions themselves
+type EntryOptions struct {
+	// Expiry is how long the entry should live, or 0 if it should not expire
+	Expiry time.Duration
+	// AfterFunc is called with the entry's value once it expires, if it is not nil
+	AfterFunc func(val T)
+	// ComputeTime is how long the entry's value took to compute, or 0 if it is unknown
+	ComputeTime time.Duration
+	// Expired reports if the entry's deadline has already passed, so it should be removed rather than stored
+	Expired bool
+}
+
+// ReadSetOptions returns the EntryOptions that options ask for, applied in order
+func ReadSetOptions(options ...SetOption) EntryOptions {
+	var e EntryOptions
+	for _, option := range options {
+		option(&e)
+	}
+
+	return e
+}
+
+func (e *EntryOptions) expire(d time.Duration, after func(val T)) {
+	e.Expiry, e.AfterFunc, e.Expired = d, after, false
+}
+
+func (e *EntryOptions) computed(d time.Duration) {
+	e.ComputeTime = d
+}
+
+func (e *EntryOptions) delete() {
+	e.Expired = true
+}
+
+// WithLoader is a CacheOption that makes the cache read-through: when Get or GetOK miss,
+// fn is called to load the entry, which is stored with the cache's default expiry and returned as if it were a hit.
+// Concurrent misses on the same key share a single call to fn.
+// If fn returns an error, nothing is stored and the caller sees a miss;
+// fn should return ErrNoValue when the key simply has no value.
+func WithLoader[K comparable, V any](fn func(key K) (V, error)) CacheOption {
+	return func(o *cacheOptions) {
+		o.loader = fn
+	}
+}
+
+// WithNegativeCaching is a CacheOption that makes a cache created with WithLoader remember, for ttl,
+// the keys its loader has no value for, whether it returns ErrNoValue or a nil value.
+// Get and GetOK report those keys missing without calling the loader again until ttl passes or an entry is set at them
+func WithNegativeCaching(ttl time.Duration) CacheOption {
+	return func(o *cacheOptions) {
+		o.negativeTTL = ttl
+	}
+}
+
+// WithRefreshAfterWrite is a CacheOption that makes Get and GetOK reload an entry stored more than d ago
+// in the background, with the loader set by WithLoader, while returning the stale value at once.
+// Only one refresh runs per key at a time, shared with any concurrent misses on the key as a load would be.
+// If the refresh fails, or the entry is written again while it runs, the entry keeps its current value,
+// and a failed entry is refreshed again on its next access. NewCache panics if WithLoader is not also passed.
+// A non-positive d never refreshes entries
+func WithRefreshAfterWrite(d time.Duration) CacheOption {
+	return func(o *cacheOptions) {
+		o.refreshAfter = d
+	}
+}
+
+// WithCircuitBreaker is a CacheOption that stops a cache created with WithLoader from calling its loader
+// once it has failed maxFailures times in a row, so a broken backend is not hammered by every miss.
+// While the circuit is open, Get and GetOK return the value from WithCircuitBreakerFallback if there is one,
+// and otherwise report a miss, logging ErrCircuitOpen. Once resetAfter has passed, a single call to the loader
+// is let through: if it succeeds the circuit closes, and if it fails the circuit stays open for another resetAfter.
+// Loader errors other than ErrNoValue count as failures. Failures are counted across the whole cache
+// unless WithCircuitBreakerPerKey is passed. A non-positive maxFailures leaves the loader unguarded
+func WithCircuitBreaker(maxFailures int, resetAfter time.Duration) CacheOption {
+	return func(o *cacheOptions) {
+		o.maxFailures = maxFailures
+		o.resetAfter = resetAfter
+	}
+}
+
+// WithCircuitBreakerPerKey is a CacheOption that makes the circuit breaker set by WithCircuitBreaker
+// count failures separately for each key, so a key the loader keeps failing on does not stop it loading others
+func WithCircuitBreakerPerKey() CacheOption {
+	return func(o *cacheOptions) {
+		o.circuitPerKey = true
+	}
+}
+
+// WithCircuitBreakerFallback is a CacheOption that makes Get and GetOK return fn(key) as a hit
+// while the circuit breaker set by WithCircuitBreaker is open. The fallback value is not stored
+func WithCircuitBreakerFallback[K comparable, V any](fn func(key K) V) CacheOption {
+	return func(o *cacheOptions) {
+		o.fallback = fn
+	}
+}
+
+// WithSingleFlight is a CacheOption that makes GetOrSet call fn without the cache locked,
+// so a slow fn does not hold up other operations and may call back into the cache.
+// Only the first of several concurrent GetOrSet calls for a missing key calls fn; the others wait for
+// and share its result, as misses on a cache created with WithLoader already do.
+func WithSingleFlight() CacheOption {
+	return func(o *cacheOptions) {
+		o.singleFlight = true
+	}
+}
+
+// WithLogger is a CacheOption that passes cache events to l: reads at LevelDebug, entries leaving the cache
+// at LevelInfo, and errors such as a failed load at LevelError.
+// Reads are logged while the cache is locked, so l must not call back into the cache.
+func WithLogger(l Logger) CacheOption {
+	return func(o *cacheOptions) {
+		o.logger = l
+	}
+}
+
+// WithInitialCapacity is a CacheOption that allocates room for n entries up front,
+// so that loading that many entries into the cache does not have to grow its storage along the way.
+// In a cache created by NewSharded, the capacity is divided evenly between the shards.
+// It has no effect on caches created with WithSyncMap
+func WithInitialCapacity(n int) CacheOption {
+	return func(o *cacheOptions) {
+		o.initialCapacity = n
+	}
+}
+
+// WithSyncMap is a CacheOption that stores entries in a sync.Map, so reads such as Get never wait for writes.
+// It suits read-heavy caches whose keys are mostly stored once and rarely overwritten or removed.
+// Writes still run one at a time. Listing entries, as Items and Keys do, requires a full scan of the sync.Map
+// that may observe writes made while it runs.
+// Bounded caches lock for reads regardless, since their EvictionPolicy records every read.
+func WithSyncMap() CacheOption {
+	return func(o *cacheOptions) {
+		o.syncMap = true
 	}
 }
@@ -0,0 +1,373 @@
+package cache
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// An entry is a cache entry as it is exported and imported.
+// A zero Deadline means the entry never expires
+type entry[K comparable, V any] struct {
+	Key      K
+	Value    V
+	Deadline time.Time
+}
+
+// types maps the names written by ExportJSON to the types ImportJSON decodes them into
+var types = struct {
+	sync.RWMutex
+	byName map[string]reflect.Type
+}{byName: map[string]reflect.Type{}}
+
+func init() {
+	for _, v := range []T{
+		false, "", []byte(nil),
+		int(0), int8(0), int16(0), int32(0), int64(0),
+		uint(0), uint8(0), uint16(0), uint32(0), uint64(0),
+		float32(0), float64(0),
+		time.Time{}, time.Duration(0),
+		[]T(nil), map[string]T(nil),
+	} {
+		Register(v)
+	}
+}
+
+// Register records the type of value so that entries holding it can be exported and imported,
+// both as JSON and with encoding/gob. Basic types are registered already; any other type stored in a cache
+// must be registered before the cache is exported, and in the importing program before it is imported
+func Register(value T) {
+	gob.Register(value)
+
+	t := reflect.TypeOf(value)
+	types.Lock()
+	defer types.Unlock()
+	types.byName[typeName(t)] = t
+}
+
+// typeName returns the name a type is exported under
+func typeName(t reflect.Type) string {
+	if t.Name() != "" && t.PkgPath() != "" {
+		return t.PkgPath() + "." + t.Name()
+	}
+
+	return t.String()
+}
+
+// isInterface reports if V is an interface type, whose values are exported along with their type names
+func isInterface[V any]() bool {
+	return reflect.TypeFor[V]().Kind() == reflect.Interface
+}
+
+// lookupType returns the registered type with the specified name
+func lookupType(name string) (reflect.Type, bool) {
+	types.RLock()
+	defer types.RUnlock()
+	t, ok := types.byName[name]
+	return t, ok
+}
+
+// A CacheEntry is an entry as returned by Dump, with its expiry.
+// HasExpiry is false for entries that never expire, whose ExpiresAt is the zero time
+type CacheEntry[V any] struct {
+	Value     V
+	ExpiresAt time.Time
+	HasExpiry bool
+}
+
+// Dump retrieves every entry in the cache that has not expired, along with when it expires.
+// The entries and their expiries are read in a single pass
+func (c *Cache[K, V]) Dump() map[K]CacheEntry[V] {
+	return dump(c.entries())
+}
+
+// Dump retrieves every entry that has not expired from every shard, along with when it expires.
+// See Cache.Dump
+func (c *ShardedCache[K, V]) Dump() map[K]CacheEntry[V] {
+	return dump(c.entries())
+}
+
+// dump returns entries keyed by their keys, as CacheEntries
+func dump[K comparable, V any](entries []entry[K, V]) map[K]CacheEntry[V] {
+	result := make(map[K]CacheEntry[V], len(entries))
+	for _, e := range entries {
+		result[e.Key] = CacheEntry[V]{Value: e.Value, ExpiresAt: e.Deadline, HasExpiry: !e.Deadline.IsZero()}
+	}
+
+	return result
+}
+
+// entries returns the entries in the cache that have not expired, along with their deadlines
+func (c *Cache[K, V]) entries() []entry[K, V] {
+	result := make(chan []entry[K, V], 1)
+	c.readOp(func(items backend[K, V]) {
+		deadlines := make(chan map[K]time.Time, 1)
+		c.tryExpiryOp(func(expiries map[K]*expiry[K]) {
+			d := make(map[K]time.Time, len(expiries))
+			for key, e := range expiries {
+				d[key] = e.deadline
+			}
+
+			deadlines <- d
+		})
+
+		var d map[K]time.Time
+		select {
+		case d = <-deadlines:
+		case <-c.done:
+		}
+
+		now := time.Now()
+		entries := make([]entry[K, V], 0, items.len())
+		for key, val := range items.all() {
+			deadline := d[key]
+			if !deadline.IsZero() && !deadline.After(now) {
+				continue
+			}
+
+			entries = append(entries, entry[K, V]{Key: key, Value: val, Deadline: deadline})
+		}
+
+		result <- entries
+	})
+
+	return <-result
+}
+
+// restore stores entries in the cache along with their expiries in a single pass, overwriting any existing entries.
+// Entries whose deadline has passed are skipped, and those without one get the cache's default expiry
+func (c *Cache[K, V]) restore(entries []entry[K, V]) {
+	live := liveEntries(entries)
+	c.itemOp(func(items backend[K, V]) {
+		for _, e := range live {
+			c.placeEntry(items, e)
+		}
+	})
+}
+
+// placeEntry stores e in items with place, replacing any existing expiry with one at e's deadline,
+// or with the cache's default expiry if e has no deadline. It must only be called with mu held
+func (c *Cache[K, V]) placeEntry(items backend[K, V], e entry[K, V]) {
+	var options []SetOption
+	if !e.Deadline.IsZero() {
+		options = []SetOption{ExpireAtTime(e.Deadline)}
+	}
+
+	c.place(items, e.Key, e.Value, options)
+}
+
+// liveEntries returns the entries whose deadline has not passed
+func liveEntries[K comparable, V any](entries []entry[K, V]) []entry[K, V] {
+	now := time.Now()
+	live := entries[:0:0]
+	for _, e := range entries {
+		if e.Deadline.IsZero() || e.Deadline.After(now) {
+			live = append(live, e)
+		}
+	}
+
+	return live
+}
+
+// jsonEntry is the JSON encoding of an entry.
+// Type names the registered type of Value, and is only set for caches whose value type is an interface
+type jsonEntry[K comparable] struct {
+	Key      K               `json:"key"`
+	Type     string          `json:"type,omitempty"`
+	Value    json.RawMessage `json:"value"`
+	Deadline *time.Time      `json:"deadline,omitempty"`
+}
+
+func exportJSON[K comparable, V any](w io.Writer, entries []entry[K, V]) error {
+	encoded := make([]jsonEntry[K], len(entries))
+	for i, e := range entries {
+		encoded[i].Key = e.Key
+		if !e.Deadline.IsZero() {
+			deadline := e.Deadline
+			encoded[i].Deadline = &deadline
+		}
+
+		if isInterface[V]() && any(e.Value) != nil {
+			t := reflect.TypeOf(e.Value)
+			if _, ok := lookupType(typeName(t)); !ok {
+				return fmt.Errorf("cache: cannot export entry %q: type %s is not registered", keyString(e.Key), t)
+			}
+
+			encoded[i].Type = typeName(t)
+		}
+
+		v, err := json.Marshal(e.Value)
+		if err != nil {
+			return fmt.Errorf("cache: cannot export entry %q: %w", keyString(e.Key), err)
+		}
+
+		encoded[i].Value = v
+	}
+
+	return json.NewEncoder(w).Encode(encoded)
+}
+
+func importJSON[K comparable, V any](r io.Reader) ([]entry[K, V], error) {
+	var decoded []jsonEntry[K]
+	if err := json.NewDecoder(r).Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("cache: cannot import: %w", err)
+	}
+
+	entries := make([]entry[K, V], len(decoded))
+	for i, d := range decoded {
+		entries[i].Key = d.Key
+		if d.Deadline != nil {
+			entries[i].Deadline = *d.Deadline
+		}
+
+		if !isInterface[V]() {
+			if err := json.Unmarshal(d.Value, &entries[i].Value); err != nil {
+				return nil, fmt.Errorf("cache: cannot import entry %q: %w", keyString(d.Key), err)
+			}
+
+			continue
+		}
+
+		if d.Type == "" {
+			continue
+		}
+
+		t, ok := lookupType(d.Type)
+		if !ok {
+			return nil, fmt.Errorf("cache: cannot import entry %q: type %s is not registered", keyString(d.Key), d.Type)
+		}
+
+		v := reflect.New(t)
+		if err := json.Unmarshal(d.Value, v.Interface()); err != nil {
+			return nil, fmt.Errorf("cache: cannot import entry %q: %w", keyString(d.Key), err)
+		}
+
+		val, ok := v.Elem().Interface().(V)
+		if !ok {
+			return nil, fmt.Errorf("%w: cannot import entry %q holding %s", ErrTypeMismatch, keyString(d.Key), t)
+		}
+
+		entries[i].Value = val
+	}
+
+	return entries, nil
+}
+
+// ExportJSON writes every entry in the cache that has not expired to w as JSON, along with its deadline.
+// If V is an interface type, each value is written with the name of its type so ImportJSON can restore it;
+// see Register.
+// AfterFunc callbacks are not exported
+func (c *Cache[K, V]) ExportJSON(w io.Writer) error {
+	return exportJSON(w, c.entries())
+}
+
+// ImportJSON reads entries written by ExportJSON from r and stores them in the cache,
+// overwriting any existing entries. Entries whose deadline has passed are skipped,
+// and those without one get the cache's default expiry.
+// Nothing is stored if r cannot be decoded
+func (c *Cache[K, V]) ImportJSON(r io.Reader) error {
+	entries, err := importJSON[K, V](r)
+	if err != nil {
+		return err
+	}
+
+	c.restore(entries)
+	return nil
+}
+
+// entries returns the entries that have not expired from every shard
+func (c *ShardedCache[K, V]) entries() []entry[K, V] {
+	var entries []entry[K, V]
+	for _, shard := range c.shards {
+		entries = append(entries, shard.entries()...)
+	}
+
+	return entries
+}
+
+// restore stores entries in the shards that hold their keys. See Cache.restore
+func (c *ShardedCache[K, V]) restore(entries []entry[K, V]) {
+	groups := map[*Cache[K, V]][]entry[K, V]{}
+	for _, e := range entries {
+		shard := c.shard(e.Key)
+		groups[shard] = append(groups[shard], e)
+	}
+
+	for shard, group := range groups {
+		shard.restore(group)
+	}
+}
+
+// ExportJSON writes every entry that has not expired to w as JSON. See Cache.ExportJSON
+func (c *ShardedCache[K, V]) ExportJSON(w io.Writer) error {
+	return exportJSON(w, c.entries())
+}
+
+// ImportJSON reads entries written by ExportJSON from r and stores them. See Cache.ImportJSON
+func (c *ShardedCache[K, V]) ImportJSON(r io.Reader) error {
+	entries, err := importJSON[K, V](r)
+	if err != nil {
+		return err
+	}
+
+	c.restore(entries)
+	return nil
+}
+
+func exportGob[K comparable, V any](w io.Writer, entries []entry[K, V]) error {
+	if err := gob.NewEncoder(w).Encode(entries); err != nil {
+		return fmt.Errorf("cache: cannot export: %w", err)
+	}
+
+	return nil
+}
+
+func importGob[K comparable, V any](r io.Reader) ([]entry[K, V], error) {
+	var entries []entry[K, V]
+	if err := gob.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("cache: cannot import: %w", err)
+	}
+
+	return entries, nil
+}
+
+// ExportGob writes every entry in the cache that has not expired to w using encoding/gob,
+// along with its deadline. Values must be of registered types; see Register.
+// AfterFunc callbacks are not exported
+func (c *Cache[K, V]) ExportGob(w io.Writer) error {
+	return exportGob(w, c.entries())
+}
+
+// ImportGob reads entries written by ExportGob from r and stores them in the cache,
+// overwriting any existing entries. Entries whose deadline has passed are skipped,
+// and those without one get the cache's default expiry.
+// Nothing is stored if r cannot be decoded, including when it holds values of unregistered types
+func (c *Cache[K, V]) ImportGob(r io.Reader) error {
+	entries, err := importGob[K, V](r)
+	if err != nil {
+		return err
+	}
+
+	c.restore(entries)
+	return nil
+}
+
+// ExportGob writes every entry that has not expired to w using encoding/gob. See Cache.ExportGob
+func (c *ShardedCache[K, V]) ExportGob(w io.Writer) error {
+	return exportGob(w, c.entries())
+}
+
+// ImportGob reads entries written by ExportGob from r and stores them. See Cache.ImportGob
+func (c *ShardedCache[K, V]) ImportGob(r io.Reader) error {
+	entries, err := importGob[K, V](r)
+	if err != nil {
+		return err
+	}
+
+	c.restore(entries)
+	return nil
+}
@@ -0,0 +1,176 @@
+package cache
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type persistPoint struct {
+	X, Y int
+}
+
+func init() {
+	Register(persistPoint{})
+}
+
+func TestExportImportJSON(t *testing.T) {
+	c := New()
+	c.Set("int", 1)
+	c.Set("string", "a")
+	c.Set("struct", persistPoint{X: 1, Y: 2})
+	c.Set("nil", nil)
+	c.Set("expiring", 1, Expire(time.Hour))
+	c.Set("expired", 1, Expire(time.Millisecond))
+	time.Sleep(time.Millisecond * 2)
+
+	var buf bytes.Buffer
+	if err := c.ExportJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	imported := New()
+	if err := imported.ImportJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]T{
+		"int":      1,
+		"string":   "a",
+		"struct":   persistPoint{X: 1, Y: 2},
+		"nil":      nil,
+		"expiring": 1,
+	}
+
+	if result := imported.Items(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if ttl, ok := imported.RemainingTTL("expiring"); !ok || ttl <= time.Hour-time.Minute {
+		t.Errorf("Remaining TTL was %v, expected about %v", ttl, time.Hour)
+	}
+}
+
+func TestImportJSONSkipsExpired(t *testing.T) {
+	c := New()
+	input := `[{"key":"1","type":"int","value":1,"deadline":"2000-01-01T00:00:00Z"},{"key":"2","type":"int","value":2}]`
+	if err := c.ImportJSON(strings.NewReader(input)); err != nil {
+		t.Fatal(err)
+	}
+
+	if result, expected := c.Keys(), []string{"2"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestJSONUnregisteredType(t *testing.T) {
+	type unregistered struct{}
+
+	c := New()
+	c.Set("1", unregistered{})
+	if err := c.ExportJSON(&bytes.Buffer{}); err == nil {
+		t.Errorf("Exporting an unregistered type did not fail")
+	}
+
+	input := `[{"key":"1","type":"example.com/unknown.Type","value":{}}]`
+	if err := c.ImportJSON(strings.NewReader(input)); err == nil {
+		t.Errorf("Importing an unregistered type did not fail")
+	}
+}
+
+func TestExportImportJSONTyped(t *testing.T) {
+	type unregistered struct{ X, Y int }
+
+	c := NewCache[int, unregistered]()
+	c.Set(1, unregistered{X: 1, Y: 2})
+
+	var buf bytes.Buffer
+	if err := c.ExportJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(buf.String(), `"type"`) {
+		t.Errorf("Export of a concrete value type should not name the type: %s", buf.String())
+	}
+
+	imported := NewCache[int, unregistered]()
+	if err := imported.ImportJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[int]unregistered{1: {X: 1, Y: 2}}
+	if result := imported.Items(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestExportImportGob(t *testing.T) {
+	c := New()
+	c.Set("int", 1)
+	c.Set("string", "a")
+	c.Set("struct", persistPoint{X: 1, Y: 2})
+	c.Set("nil", nil)
+	c.Set("expiring", 1, Expire(time.Hour))
+	c.Set("expired", 1, Expire(time.Millisecond))
+	time.Sleep(time.Millisecond * 2)
+
+	var buf bytes.Buffer
+	if err := c.ExportGob(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	imported := New()
+	if err := imported.ImportGob(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]T{
+		"int":      1,
+		"string":   "a",
+		"struct":   persistPoint{X: 1, Y: 2},
+		"nil":      nil,
+		"expiring": 1,
+	}
+
+	if result := imported.Items(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if ttl, ok := imported.RemainingTTL("expiring"); !ok || ttl <= time.Hour-time.Minute {
+		t.Errorf("Remaining TTL was %v, expected about %v", ttl, time.Hour)
+	}
+}
+
+func TestGobUnregisteredType(t *testing.T) {
+	type unregistered struct{ X int }
+
+	c := New()
+	c.Set("1", unregistered{})
+	if err := c.ExportGob(&bytes.Buffer{}); err == nil {
+		t.Errorf("Exporting an unregistered type did not fail")
+	}
+}
+
+func TestDump(t *testing.T) {
+	c := New()
+	c.Set("1", 1)
+	c.Set("2", 2, Expire(time.Hour))
+	c.Set("3", 3, Expire(time.Millisecond))
+
+	time.Sleep(time.Millisecond * 2)
+
+	dump := c.Dump()
+	if result, expected := len(dump), 2; result != expected {
+		t.Fatalf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if e := dump["1"]; e.Value != T(1) || e.HasExpiry || !e.ExpiresAt.IsZero() {
+		t.Errorf("Result was %#v, expected a persistent entry holding %#v", e, 1)
+	}
+
+	if e := dump["2"]; e.Value != T(2) || !e.HasExpiry || time.Until(e.ExpiresAt) <= time.Minute*59 {
+		t.Errorf("Result was %#v, expected an entry holding %#v expiring in about an hour", e, 2)
+	}
+}
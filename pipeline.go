@@ -0,0 +1,94 @@
+package cache
+
+// A Pipeline queues operations on a cache and runs them all at once, with the cache locked a single time,
+// when Execute is called. Results are delivered on the channels returned when the operations are queued,
+// which can be read once Execute has returned. A Pipeline must not be used from several goroutines at once
+type Pipeline[K comparable, V any] struct {
+	c   *Cache[K, V]
+	ops []pipelineOp[K, V]
+}
+
+// A pipelineOp is an operation queued in a Pipeline
+type pipelineOp[K comparable, V any] struct {
+	kind    pipelineKind
+	key     K
+	val     V
+	options []SetOption
+	result  chan V
+	ok      chan bool
+}
+
+type pipelineKind int
+
+const (
+	pipelineSet pipelineKind = iota
+	pipelineGet
+	pipelineDelete
+)
+
+// Pipeline returns an empty Pipeline for the cache
+func (c *Cache[K, V]) Pipeline() *Pipeline[K, V] {
+	return &Pipeline[K, V]{c: c}
+}
+
+// Set queues setting val at the specified key, as Cache.Set does
+func (p *Pipeline[K, V]) Set(key K, val V, options ...SetOption) {
+	p.ops = append(p.ops, pipelineOp[K, V]{kind: pipelineSet, key: key, val: val, options: options})
+}
+
+// Get queues reading the entry at the specified key, and returns a channel that receives it once Execute is called.
+// A missing entry is received as the zero value; unlike Cache.Get, the cache's loader is not called
+func (p *Pipeline[K, V]) Get(key K) <-chan V {
+	result := make(chan V, 1)
+	p.ops = append(p.ops, pipelineOp[K, V]{kind: pipelineGet, key: key, result: result})
+	return result
+}
+
+// Delete queues removing the entry at the specified key, and returns a channel that receives
+// whether an entry was removed once Execute is called
+func (p *Pipeline[K, V]) Delete(key K) <-chan bool {
+	ok := make(chan bool, 1)
+	p.ops = append(p.ops, pipelineOp[K, V]{kind: pipelineDelete, key: key, ok: ok})
+	return ok
+}
+
+// Len returns the number of operations queued
+func (p *Pipeline[K, V]) Len() int {
+	return len(p.ops)
+}
+
+// Execute runs the queued operations in the order they were queued, with the cache locked once for all of them,
+// so no other operation on the cache sees the effects of only some of them. The pipeline is then emptied
+// and can be reused. In a cache created with WithWriteThrough, every write is made before the cache is locked,
+// and a Set whose write fails is logged at LevelError and skipped. It panics with ErrClosed if the cache has been closed
+func (p *Pipeline[K, V]) Execute() {
+	ops := p.ops
+	p.ops = nil
+
+	skip := make([]bool, len(ops))
+	for i, op := range ops {
+		if op.kind != pipelineSet {
+			continue
+		}
+
+		skip[i] = !p.c.writeOrLog(op.key, op.val)
+	}
+
+	p.c.itemOp(func(items backend[K, V]) {
+		for i, op := range ops {
+			switch {
+			case skip[i]:
+			case op.kind == pipelineSet:
+				p.c.placeWritten(items, op.key, op.val, op.options)
+			case op.kind == pipelineGet:
+				v, _ := p.c.lookup(items, op.key)
+				op.result <- v
+			case op.kind == pipelineDelete:
+				op.ok <- p.c.evict(items, op.key, Manual)
+				p.c.tryExpiryOp(func(expiries map[K]*expiry[K]) {
+					stopExpiry(expiries, op.key)
+				})
+			}
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestPipeline(t *testing.T) {
+	c := New()
+	c.Set("1", 1)
+
+	p := c.Pipeline()
+	p.Set("2", 2, Expire(time.Hour))
+	get := p.Get("2")
+	deleted := p.Delete("1")
+	missing := p.Delete("3")
+
+	if result, expected := p.Len(), 4; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if _, ok := c.GetOK("2"); ok {
+		t.Errorf("Entry for key '2' should not have been stored before Execute")
+	}
+
+	p.Execute()
+
+	if result, expected := <-get, T(2); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if !<-deleted || <-missing {
+		t.Errorf("Delete did not report only the existing entry as removed")
+	}
+
+	if _, ok := c.RemainingTTL("2"); !ok {
+		t.Errorf("Entry for key '2' should have had the Expire option applied")
+	}
+
+	if result, expected := p.Len(), 0; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func benchmarkPipeline(count int, b *testing.B) {
+	c := New()
+
+	for n := 0; n < b.N; n++ {
+		p := c.Pipeline()
+		for i := 0; i < count; i++ {
+			p.Set(strconv.Itoa(i), i)
+		}
+
+		p.Execute()
+	}
+}
+
+func BenchmarkPipeline1(b *testing.B)     { benchmarkPipeline(1, b) }
+func BenchmarkPipeline10(b *testing.B)    { benchmarkPipeline(10, b) }
+func BenchmarkPipeline100(b *testing.B)   { benchmarkPipeline(100, b) }
+func BenchmarkPipeline1000(b *testing.B)  { benchmarkPipeline(1000, b) }
+func BenchmarkPipeline10000(b *testing.B) { benchmarkPipeline(10000, b) }
@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Probabilistic retrieves an entry at the specified key like GetOK, but may report it missing ahead of its expiry,
+// so that one caller recomputes the value before it expires instead of many at once when it does.
+// This is the XFetch algorithm: an entry with ttl left whose value took computeTime to compute is reported missing
+// with probability exp(-ttl / (beta * computeTime)). A beta of 1 is a good default, and larger values recompute earlier.
+// Entries without an expiry or a compute time, which is recorded by ComputeTime or measured by GetOrSet and WithLoader,
+// are never reported missing early.
+func (c *Cache[K, V]) Probabilistic(key K, beta float64) (V, bool) {
+	type found struct {
+		val V
+		ok  bool
+	}
+
+	// computeTimes is guarded by mu even in caches created with WithSyncMap, so lock for writing
+	result := make(chan found, 1)
+	c.itemOp(func(items backend[K, V]) {
+		if computeTime := c.computeTimes[key]; computeTime > 0 {
+			deadline := make(chan time.Time, 1)
+			c.tryExpiryOp(func(expiries map[K]*expiry[K]) {
+				var d time.Time
+				if e, ok := expiries[key]; ok {
+					d = e.deadline
+				}
+
+				deadline <- d
+			})
+
+			var d time.Time
+			select {
+			case d = <-deadline:
+			default:
+			}
+
+			if !d.IsZero() && expireEarly(time.Until(d), computeTime, beta) {
+				c.stats.misses.Add(1)
+				result <- found{}
+				return
+			}
+		}
+
+		v, ok := c.lookup(items, key)
+		result <- found{v, ok}
+	})
+
+	r := <-result
+	return r.val, r.ok
+}
+
+// expireEarly reports if an entry with ttl left, whose value took computeTime to compute, should be treated as
+// expired: it returns true with probability exp(-ttl / (beta * computeTime))
+func expireEarly(ttl, computeTime time.Duration, beta float64) bool {
+	if ttl <= 0 {
+		return true
+	}
+
+	// 1 - rand.Float64() is in (0, 1], so its log is finite
+	return -float64(computeTime)*beta*math.Log(1-rand.Float64()) >= float64(ttl)
+}
+
+// Probabilistic retrieves an entry at the specified key, but may report it missing ahead of its expiry.
+// See Cache.Probabilistic
+func (c *ShardedCache[K, V]) Probabilistic(key K, beta float64) (V, bool) {
+	return c.shard(key).Probabilistic(key, beta)
+}
@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProbabilistic(t *testing.T) {
+	c := New()
+	c.Set("no compute time", 1, Expire(time.Millisecond))
+	c.Set("no expiry", 1, ComputeTime(time.Hour))
+	c.Set("fresh", 1, Expire(time.Hour), ComputeTime(time.Nanosecond))
+	c.Set("stale", 1, Expire(time.Hour), ComputeTime(time.Hour*1000))
+
+	for i := 0; i < 100; i++ {
+		for _, key := range []string{"no compute time", "no expiry", "fresh"} {
+			if _, ok := c.Probabilistic(key, 1); !ok {
+				t.Fatalf("Entry for key '%s' should not have been reported missing", key)
+			}
+		}
+	}
+
+	var missing int
+	for i := 0; i < 100; i++ {
+		if _, ok := c.Probabilistic("stale", 1); !ok {
+			missing++
+		}
+	}
+
+	if missing < 90 {
+		t.Errorf("Entry for key 'stale' was reported missing %d times in 100, expected nearly always", missing)
+	}
+}
+
+func TestProbabilisticMeasuresGetOrSet(t *testing.T) {
+	c := NewWithOptions(WithDefaultExpiry(time.Hour))
+	c.GetOrSet("1", func() T {
+		time.Sleep(time.Millisecond)
+		return 1
+	})
+
+	if computeTime := c.computeTimes["1"]; computeTime < time.Millisecond {
+		t.Errorf("Compute time was %v, expected at least %v", computeTime, time.Millisecond)
+	}
+
+	c.Delete("1")
+	if _, ok := c.computeTimes["1"]; ok {
+		t.Errorf("Compute time for key '1' should have been removed")
+	}
+}
+
+func TestExpireEarly(t *testing.T) {
+	if !expireEarly(0, time.Second, 1) {
+		t.Errorf("An entry past its expiry should always expire early")
+	}
+
+	if expireEarly(time.Hour, 0, 1) {
+		t.Errorf("An entry that took no time to compute should never expire early")
+	}
+}
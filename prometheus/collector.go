@@ -0,0 +1,63 @@
+// Package prometheus exports cache usage counters as Prometheus metrics
+package prometheus
+
+import (
+	cache "github.com/robotsrulz/go-cache"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collector reads a cache's counters on every scrape
+type collector struct {
+	c cache.CacheMetrics
+
+	size      *prometheus.Desc
+	hits      *prometheus.Desc
+	misses    *prometheus.Desc
+	sets      *prometheus.Desc
+	deletes   *prometheus.Desc
+	evictions *prometheus.Desc
+	expired   *prometheus.Desc
+}
+
+// NewCollector returns a prometheus.Collector that reports the size, hits, misses, sets, deletes, evictions and
+// expirations of c. Both *cache.Cache and *cache.ShardedCache can be passed
+func NewCollector(c cache.CacheMetrics, namespace, subsystem string) prometheus.Collector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystem, name), help, nil, nil)
+	}
+
+	return &collector{
+		c:         c,
+		size:      desc("size", "Number of entries in the cache."),
+		hits:      desc("hits_total", "Number of reads that found an entry."),
+		misses:    desc("misses_total", "Number of reads that found no entry."),
+		sets:      desc("sets_total", "Number of entries stored."),
+		deletes:   desc("deletes_total", "Number of entries removed manually."),
+		evictions: desc("evictions_total", "Number of entries evicted to make room."),
+		expired:   desc("expired_total", "Number of entries removed because they expired."),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.size
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.sets
+	ch <- c.deletes
+	ch <- c.evictions
+	ch <- c.expired
+}
+
+// Collect implements prometheus.Collector
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	s := c.c.Stats()
+	ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(s.CurrentSize))
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(s.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(s.Misses))
+	ch <- prometheus.MustNewConstMetric(c.sets, prometheus.CounterValue, float64(s.Sets))
+	ch <- prometheus.MustNewConstMetric(c.deletes, prometheus.CounterValue, float64(s.Deletes))
+	ch <- prometheus.MustNewConstMetric(c.evictions, prometheus.CounterValue, float64(s.Evictions))
+	ch <- prometheus.MustNewConstMetric(c.expired, prometheus.CounterValue, float64(s.Expired))
+}
@@ -0,0 +1,75 @@
+package prometheus
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	cache "github.com/robotsrulz/go-cache"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector(t *testing.T) {
+	c := cache.New()
+	c.Set("1", 1)
+	c.Set("2", 2)
+	c.Get("1")
+	c.Get("3")
+	c.Delete("2")
+
+	expected := `
+# HELP test_cache_hits_total Number of reads that found an entry.
+# TYPE test_cache_hits_total counter
+test_cache_hits_total 1
+# HELP test_cache_misses_total Number of reads that found no entry.
+# TYPE test_cache_misses_total counter
+test_cache_misses_total 1
+# HELP test_cache_sets_total Number of entries stored.
+# TYPE test_cache_sets_total counter
+test_cache_sets_total 2
+# HELP test_cache_size Number of entries in the cache.
+# TYPE test_cache_size gauge
+test_cache_size 1
+`
+
+	err := testutil.CollectAndCompare(NewCollector(c, "test", "cache"), strings.NewReader(expected),
+		"test_cache_hits_total", "test_cache_misses_total", "test_cache_sets_total", "test_cache_size")
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCollectorConcurrent(t *testing.T) {
+	c := cache.NewSharded()
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewCollector(c, "test", "cache"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				key := strconv.Itoa(j)
+				c.Set(key, j)
+				c.Get(key)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if _, err := registry.Gather(); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	if result, expected := testutil.CollectAndCount(NewCollector(c, "test", "cache")), 7; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
@@ -0,0 +1,206 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrReadOnly is returned, or panicked with by methods that cannot return an error,
+// when a cache returned by NewReadOnly is modified
+var ErrReadOnly = errors.New("cache: read-only cache")
+
+// NewReadOnly returns a read-only view of c.
+// Methods that only read entries are passed through to c, while methods that would modify c
+// return ErrReadOnly, or panic with it if they cannot return an error. GetOrSet and GetOrSetMany panic, and GetOrLoad fails, even if the entries exist
+func NewReadOnly[K comparable, V any](c Cacher[K, V]) Cacher[K, V] {
+	return readOnly[K, V]{c}
+}
+
+// readOnly wraps a Cacher, overriding every method that modifies it
+type readOnly[K comparable, V any] struct {
+	Cacher[K, V]
+}
+
+func (readOnly[K, V]) ResetStats() {
+	panic(ErrReadOnly)
+}
+
+func (readOnly[K, V]) Set(key K, val V, options ...SetOption) {
+	panic(ErrReadOnly)
+}
+
+func (readOnly[K, V]) SetE(key K, val V, options ...SetOption) error {
+	return ErrReadOnly
+}
+
+func (readOnly[K, V]) SetMany(entries map[K]V, options ...SetOption) {
+	panic(ErrReadOnly)
+}
+
+func (readOnly[K, V]) MSet(entries map[K]V) error {
+	return ErrReadOnly
+}
+
+func (readOnly[K, V]) SetBatch(entries []BatchEntry[K, V]) {
+	panic(ErrReadOnly)
+}
+
+func (readOnly[K, V]) SetCtx(ctx context.Context, key K, val V, options ...SetOption) error {
+	return ErrReadOnly
+}
+
+func (readOnly[K, V]) SetWithTags(key K, val V, tags []string, options ...SetOption) {
+	panic(ErrReadOnly)
+}
+
+func (readOnly[K, V]) GetOrSet(key K, fn func() V, options ...SetOption) V {
+	panic(ErrReadOnly)
+}
+
+func (readOnly[K, V]) GetOrSetMany(keys []K, loader func(keys []K) map[K]V, options ...SetOption) map[K]V {
+	panic(ErrReadOnly)
+}
+
+func (readOnly[K, V]) GetOrLoad(key K, loader func() (V, time.Duration, error)) (V, error) {
+	var zero V
+	return zero, ErrReadOnly
+}
+
+func (readOnly[K, V]) SetIfAbsent(key K, val V, options ...SetOption) bool {
+	panic(ErrReadOnly)
+}
+
+func (readOnly[K, V]) SetDefault(key K, val V, options ...SetOption) bool {
+	panic(ErrReadOnly)
+}
+
+func (readOnly[K, V]) SetNX(key K, val V, options ...SetOption) bool {
+	panic(ErrReadOnly)
+}
+
+func (readOnly[K, V]) SetIfPresent(key K, val V, options ...SetOption) bool {
+	panic(ErrReadOnly)
+}
+
+func (readOnly[K, V]) SetXX(key K, val V, options ...SetOption) bool {
+	panic(ErrReadOnly)
+}
+
+func (readOnly[K, V]) GetAndSet(key K, val V, options ...SetOption) (V, bool) {
+	panic(ErrReadOnly)
+}
+
+func (readOnly[K, V]) CompareAndSwap(key K, oldVal, newVal V, options ...SetOption) bool {
+	panic(ErrReadOnly)
+}
+
+func (readOnly[K, V]) Increment(key K, delta int64) (int64, error) {
+	return 0, ErrReadOnly
+}
+
+func (readOnly[K, V]) Decrement(key K, delta int64) (int64, error) {
+	return 0, ErrReadOnly
+}
+
+func (readOnly[K, V]) Append(key K, suffix string) (string, error) {
+	return "", ErrReadOnly
+}
+
+func (readOnly[K, V]) Modify(key K, fn func(current V) V) bool {
+	panic(ErrReadOnly)
+}
+
+func (readOnly[K, V]) Touch(key K, d time.Duration) bool {
+	panic(ErrReadOnly)
+}
+
+func (readOnly[K, V]) ExpireAt(key K, t time.Time) bool {
+	panic(ErrReadOnly)
+}
+
+func (readOnly[K, V]) Expire(key K, d time.Duration) bool {
+	panic(ErrReadOnly)
+}
+
+func (readOnly[K, V]) Persist(key K) bool {
+	panic(ErrReadOnly)
+}
+
+func (readOnly[K, V]) Rename(oldKey, newKey K) bool {
+	panic(ErrReadOnly)
+}
+
+func (readOnly[K, V]) Clear() {
+	panic(ErrReadOnly)
+}
+
+func (readOnly[K, V]) ClearExpired() int {
+	panic(ErrReadOnly)
+}
+
+func (readOnly[K, V]) ClearEvery(d time.Duration) (stop func()) {
+	panic(ErrReadOnly)
+}
+
+func (readOnly[K, V]) Delete(key K) {
+	panic(ErrReadOnly)
+}
+
+func (readOnly[K, V]) DeleteCtx(ctx context.Context, key K) error {
+	return ErrReadOnly
+}
+
+func (readOnly[K, V]) DeleteMany(keys []K) int {
+	panic(ErrReadOnly)
+}
+
+func (readOnly[K, V]) DeletePrefix(prefix string) int {
+	panic(ErrReadOnly)
+}
+
+func (readOnly[K, V]) DeleteSuffix(suffix string) int {
+	panic(ErrReadOnly)
+}
+
+func (readOnly[K, V]) DeleteContains(substr string) int {
+	panic(ErrReadOnly)
+}
+
+func (readOnly[K, V]) DeleteByTag(tag string) int {
+	panic(ErrReadOnly)
+}
+
+func (readOnly[K, V]) GetAndDelete(key K) (V, bool) {
+	panic(ErrReadOnly)
+}
+
+func (readOnly[K, V]) Evict(key K) (V, bool) {
+	panic(ErrReadOnly)
+}
+
+func (readOnly[K, V]) CompareAndDelete(key K, expected V) bool {
+	panic(ErrReadOnly)
+}
+
+func (readOnly[K, V]) ImportJSON(r io.Reader) error {
+	return ErrReadOnly
+}
+
+func (readOnly[K, V]) ImportGob(r io.Reader) error {
+	return ErrReadOnly
+}
+
+func (readOnly[K, V]) LoadFromFile(path string) error {
+	return ErrReadOnly
+}
+
+func (readOnly[K, V]) Restore(data []byte) error {
+	return ErrReadOnly
+}
+
+// Close returns ErrReadOnly, leaving the wrapped cache open
+func (readOnly[K, V]) Close() error {
+	return ErrReadOnly
+}
@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestReadOnly(t *testing.T) {
+	c := New()
+	c.Set("1", 1)
+
+	r := NewReadOnly(c)
+	if result, expected := r.Get("1"), T(1); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if result, expected := r.Keys(), []string{"1"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if err := r.SetCtx(context.Background(), "2", 2); err != ErrReadOnly {
+		t.Errorf("Error was %v, expected %v", err, ErrReadOnly)
+	}
+
+	if err := r.SetE("2", 2); err != ErrReadOnly {
+		t.Errorf("Error was %v, expected %v", err, ErrReadOnly)
+	}
+
+	if err := r.Close(); err != ErrReadOnly {
+		t.Errorf("Error was %v, expected %v", err, ErrReadOnly)
+	}
+
+	for name, fn := range map[string]func(){
+		"Set":    func() { r.Set("2", 2) },
+		"Delete": func() { r.Delete("1") },
+		"Clear":  func() { r.Clear() },
+	} {
+		func() {
+			defer func() {
+				if recovered := recover(); recovered != ErrReadOnly {
+					t.Errorf("%s panicked with %v, expected %v", name, recovered, ErrReadOnly)
+				}
+			}()
+
+			fn()
+		}()
+	}
+
+	if result, expected := c.Items(), map[string]T{"1": 1}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
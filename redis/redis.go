@@ -0,0 +1,1551 @@
+// Package redis adapts a Redis client, from github.com/redis/go-redis/v9, to the cache.Cacher interface,
+// so code written against an in-process cache can move to Redis without changes
+package redis
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	cache "github.com/robotsrulz/go-cache"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// ErrNotSupported is returned, or panicked with by methods that cannot return an error,
+// when a call asks for something Redis cannot do, such as running an AfterFunc when an entry expires
+var ErrNotSupported = errors.New("cache: not supported by redis")
+
+const (
+	// scanCount is the number of keys asked of each SCAN call
+	scanCount = 100
+
+	// pollInterval is how often WaitForKey looks for an entry stored by another client
+	pollInterval = 50 * time.Millisecond
+
+	// tagPrefix starts the name of the Redis set holding the keys registered under a tag
+	tagPrefix = "\x00tag:"
+
+	// watchBuffer is the number of events a Watch channel holds before further events are dropped
+	watchBuffer = 64
+)
+
+// A watcher is a channel returned by Watch, or by WatchPrefix if prefixed is true
+type watcher[V any] struct {
+	key      string
+	prefix   string
+	prefixed bool
+	ch       chan cache.WatchEvent[string, V]
+}
+
+// A box wraps a value for encoding/gob, which cannot encode a nil interface on its own
+type box[V any] struct {
+	Value V
+}
+
+// A load is a call to a GetOrLoad loader, shared by every concurrent miss on the same key through one Cacher.
+// val and err are written before done is closed
+type load[V any] struct {
+	done chan struct{}
+	val  V
+	err  error
+}
+
+// A redisCache is a cache.Cacher storing its entries in a Redis database
+type redisCache[V any] struct {
+	client *goredis.Client
+
+	// mu guards the fields below it
+	mu       sync.Mutex
+	watchers []*watcher[V]
+	changed  chan struct{}
+	loads    map[string]*load[V]
+
+	hits    atomic.Int64
+	misses  atomic.Int64
+	sets    atomic.Int64
+	deletes atomic.Int64
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+var _ cache.Cacher[string, cache.T] = (*redisCache[cache.T])(nil)
+
+// NewRedisCache returns a Cacher storing its entries in the Redis database client is connected to.
+// Values are encoded with encoding/gob, so types held in an interface must be registered with cache.Register.
+// The cache assumes it owns the database: Clear runs FLUSHDB, and Items and Keys list every key SCAN finds.
+// An Expire option maps to the TTL of the SET command. Redis expires entries itself, so it cannot run an AfterFunc,
+// and a call passing one fails with ErrNotSupported. Methods that cannot return an error treat any error
+// from Redis as a miss, or as a change that was not made; their E and ctx variants, such as SetE, GetCtx and MGet,
+// return it. Watch only sees changes made through the returned Cacher, not those made by other clients
+// or entries Redis expires. Closing the Cacher does not close client
+func NewRedisCache[V any](client *goredis.Client) cache.Cacher[string, V] {
+	return &redisCache[V]{
+		client:  client,
+		changed: make(chan struct{}),
+		loads:   map[string]*load[V]{},
+		done:    make(chan struct{}),
+	}
+}
+
+// failed reports if err is not nil, for methods that cannot return an error. They treat an error from Redis
+// as a miss, or as a change that was not made. ErrNotSupported is still panicked with,
+// since it reports a call the cache can never serve
+func failed(err error) bool {
+	if errors.Is(err, ErrNotSupported) {
+		panic(err)
+	}
+
+	return err != nil
+}
+
+// encode returns val encoded with encoding/gob
+func encode[V any](val V) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(box[V]{val}); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// decode returns the result of a command reading a value written by encode.
+// Returns false if the command found no value
+func decode[V any](data string, err error) (V, bool, error) {
+	var b box[V]
+	if errors.Is(err, goredis.Nil) {
+		return b.Value, false, nil
+	}
+
+	if err != nil {
+		return b.Value, false, err
+	}
+
+	if err := gob.NewDecoder(strings.NewReader(data)).Decode(&b); err != nil {
+		return b.Value, false, err
+	}
+
+	return b.Value, true, nil
+}
+
+// setArgs returns the SET arguments that options ask for, and reports if they ask for the entry to be removed
+func setArgs(options []cache.SetOption) (goredis.SetArgs, bool, error) {
+	opts := cache.ReadSetOptions(options...)
+	if opts.AfterFunc != nil {
+		return goredis.SetArgs{}, false, ErrNotSupported
+	}
+
+	return goredis.SetArgs{TTL: opts.Expiry}, opts.Expired, nil
+}
+
+// escape quotes the characters SCAN's MATCH pattern treats specially
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`*?[]^\`, r) {
+			b.WriteByte('\\')
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// publish sends a WatchEvent to the channels watching key, dropping it for any that are full
+func (c *redisCache[V]) publish(key string, old, val V, t cache.WatchEventType) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if t == cache.WatchSet {
+		close(c.changed)
+		c.changed = make(chan struct{})
+	}
+
+	e := cache.WatchEvent[string, V]{Key: key, OldValue: old, NewValue: val, EventType: t}
+	for _, w := range c.watchers {
+		if w.prefixed && strings.HasPrefix(key, w.prefix) || !w.prefixed && w.key == key {
+			select {
+			case w.ch <- e:
+			default:
+			}
+		}
+	}
+}
+
+// get reads the entry at key, counting the read as a hit or a miss
+func (c *redisCache[V]) get(ctx context.Context, key string) (V, bool, error) {
+	v, ok, err := decode[V](c.client.Get(ctx, key).Result())
+	if err != nil {
+		return v, false, err
+	}
+
+	if ok {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+
+	return v, ok, nil
+}
+
+// put stores val at key with SET, as asked by args, returning the entry it replaced, if it could see one.
+// Also reports if val was stored, which an NX or XX mode may prevent
+func (c *redisCache[V]) put(ctx context.Context, key string, val V, args goredis.SetArgs) (V, bool, bool, error) {
+	var old V
+	var replaced bool
+	data, err := encode(val)
+	if err != nil {
+		return old, false, false, err
+	}
+
+	// Redis only allows GET together with NX from version 7.0
+	args.Get = args.Mode != "NX"
+	res, err := c.client.SetArgs(ctx, key, data, args).Result()
+	switch {
+	case errors.Is(err, goredis.Nil):
+		// With NX an entry already existed, with XX none did, and otherwise there was no entry to return
+		if args.Mode != "" {
+			return old, false, false, nil
+		}
+	case err != nil:
+		return old, false, false, err
+	case args.Get:
+		if old, replaced, err = decode[V](res, nil); err != nil {
+			return old, false, false, err
+		}
+	}
+
+	c.sets.Add(1)
+	c.publish(key, old, val, cache.WatchSet)
+	return old, replaced, true, nil
+}
+
+// set stores val at key as asked by options, using mode as the SET mode.
+// Returns false if the mode kept val from being stored
+func (c *redisCache[V]) set(ctx context.Context, key string, val V, mode string, options []cache.SetOption) (bool, error) {
+	args, expired, err := setArgs(options)
+	if err != nil {
+		return false, err
+	}
+
+	if expired {
+		_, _, err := c.remove(ctx, key)
+		return false, err
+	}
+
+	args.Mode = mode
+	_, _, ok, err := c.put(ctx, key, val, args)
+	return ok, err
+}
+
+// remove deletes the entry at key with GETDEL, returning its value
+func (c *redisCache[V]) remove(ctx context.Context, key string) (V, bool, error) {
+	v, ok, err := decode[V](c.client.GetDel(ctx, key).Result())
+	if err != nil || !ok {
+		return v, false, err
+	}
+
+	var zero V
+	c.deletes.Add(1)
+	c.publish(key, v, zero, cache.WatchDeleted)
+	return v, true, nil
+}
+
+// A change is what a transaction does to the entry it watches: nothing, store val with args, or delete it
+type change[V any] struct {
+	store  bool
+	delete bool
+	val    V
+	args   goredis.SetArgs
+}
+
+// retry calls fn until it does not fail because another client changed a watched key
+func retry(fn func() error) error {
+	for {
+		if err := fn(); !errors.Is(err, goredis.TxFailedErr) {
+			return err
+		}
+	}
+}
+
+// transact reads the entry at key and applies the change fn returns within a WATCH transaction.
+// fn is called again whenever another client changes the entry before the transaction commits
+func (c *redisCache[V]) transact(ctx context.Context, key string, fn func(old V, ok bool) (change[V], error)) error {
+	return retry(func() error {
+		return c.client.Watch(ctx, func(tx *goredis.Tx) error {
+			old, ok, err := decode[V](tx.Get(ctx, key).Result())
+			if err != nil {
+				return err
+			}
+
+			ch, err := fn(old, ok)
+			if err != nil || !ch.store && !ch.delete {
+				return err
+			}
+
+			var data []byte
+			if ch.store {
+				if data, err = encode(ch.val); err != nil {
+					return err
+				}
+			}
+
+			_, err = tx.TxPipelined(ctx, func(p goredis.Pipeliner) error {
+				if ch.delete {
+					p.Del(ctx, key)
+				} else {
+					p.SetArgs(ctx, key, data, ch.args)
+				}
+
+				return nil
+			})
+
+			if err != nil {
+				return err
+			}
+
+			var zero V
+			if ch.delete {
+				c.deletes.Add(1)
+				c.publish(key, old, zero, cache.WatchDeleted)
+			} else {
+				c.sets.Add(1)
+				c.publish(key, old, ch.val, cache.WatchSet)
+			}
+
+			return nil
+		}, key)
+	})
+}
+
+// update runs fn on a scratch cache.Cache holding only the entry at key, if there is one,
+// then stores the value fn leaves there, keeping the entry's TTL. If fn fails, the entry is left alone.
+// Value arithmetic such as Increment thus behaves exactly as on a cache.Cache
+func (c *redisCache[V]) update(key string, fn func(s *cache.Cache[string, V]) error) error {
+	return c.transact(context.Background(), key, func(old V, ok bool) (change[V], error) {
+		s := cache.NewCache[string, V]()
+		defer s.Close()
+
+		if ok {
+			s.Set(key, old)
+		}
+
+		if err := fn(s); err != nil {
+			return change[V]{}, err
+		}
+
+		v, ok := s.GetOK(key)
+		return change[V]{store: ok, val: v, args: goredis.SetArgs{KeepTTL: true}}, nil
+	})
+}
+
+// scan calls fn with each page of keys matching pattern that SCAN returns, leaving out the sets holding tags
+func (c *redisCache[V]) scan(ctx context.Context, pattern string, fn func(keys []string) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := c.client.Scan(ctx, cursor, pattern, scanCount).Result()
+		if err != nil {
+			return err
+		}
+
+		keys = slices.DeleteFunc(keys, func(key string) bool { return strings.HasPrefix(key, tagPrefix) })
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+
+		if cursor = next; cursor == 0 {
+			return nil
+		}
+	}
+}
+
+// keys returns the keys matching pattern
+func (c *redisCache[V]) keys(ctx context.Context, pattern string) ([]string, error) {
+	var result []string
+	err := c.scan(ctx, pattern, func(keys []string) error {
+		result = append(result, keys...)
+		return nil
+	})
+
+	return result, err
+}
+
+// An entry is an entry read from Redis, with its remaining TTL
+type entry[V any] struct {
+	key string
+	val V
+	ttl time.Duration
+}
+
+// entries reads every entry in the database, along with its remaining TTL if withTTL is true.
+// Entries that expire while they are being read are left out
+func (c *redisCache[V]) entries(ctx context.Context, withTTL bool) ([]entry[V], error) {
+	var entries []entry[V]
+	err := c.scan(ctx, "*", func(keys []string) error {
+		vals := make([]*goredis.StringCmd, len(keys))
+		ttls := make([]*goredis.DurationCmd, len(keys))
+		_, err := c.client.Pipelined(ctx, func(p goredis.Pipeliner) error {
+			for i, key := range keys {
+				vals[i] = p.Get(ctx, key)
+				if withTTL {
+					ttls[i] = p.PTTL(ctx, key)
+				}
+			}
+
+			return nil
+		})
+
+		if err != nil && !errors.Is(err, goredis.Nil) {
+			return err
+		}
+
+		for i, key := range keys {
+			v, ok, err := decode[V](vals[i].Result())
+			if err != nil {
+				return err
+			}
+
+			if !ok {
+				continue
+			}
+
+			e := entry[V]{key: key, val: v}
+			if withTTL {
+				e.ttl = max(ttls[i].Val(), 0)
+			}
+
+			entries = append(entries, e)
+		}
+
+		return nil
+	})
+
+	return entries, err
+}
+
+// scratch returns a cache.Cache holding a copy of the entries with their remaining TTLs,
+// used to encode them exactly as a cache.Cache does
+func (c *redisCache[V]) scratch() (*cache.Cache[string, V], error) {
+	entries, err := c.entries(context.Background(), true)
+	if err != nil {
+		return nil, err
+	}
+
+	s := cache.NewCache[string, V]()
+	for _, e := range entries {
+		if e.ttl > 0 {
+			s.Set(e.key, e.val, cache.Expire(e.ttl))
+		} else {
+			s.Set(e.key, e.val)
+		}
+	}
+
+	return s, nil
+}
+
+// export encodes the entries by calling fn on a scratch cache.Cache holding them
+func (c *redisCache[V]) export(fn func(s *cache.Cache[string, V]) error) error {
+	s, err := c.scratch()
+	if err != nil {
+		return err
+	}
+
+	defer s.Close()
+	return fn(s)
+}
+
+// load calls fn to fill a scratch cache.Cache, then stores its entries with their remaining TTLs,
+// first removing every entry if replace is true
+func (c *redisCache[V]) load(fn func(s *cache.Cache[string, V]) error, replace bool) error {
+	s := cache.NewCache[string, V]()
+	defer s.Close()
+
+	if err := fn(s); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if replace {
+		if err := c.clear(ctx); err != nil {
+			return err
+		}
+	}
+
+	for key, val := range s.Items() {
+		ttl, _ := s.RemainingTTL(key)
+		if _, _, _, err := c.put(ctx, key, val, goredis.SetArgs{TTL: ttl}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// check returns ctx.Err() if ctx is done, or cache.ErrClosed if the cache has been closed
+func (c *redisCache[V]) check(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	select {
+	case <-c.done:
+		return cache.ErrClosed
+	default:
+		return nil
+	}
+}
+
+// Stats returns the usage counters kept by this Cacher. Redis evicts and expires entries itself,
+// so Evictions and Expired stay at zero. CurrentSize counts every key in the database
+func (c *redisCache[V]) Stats() cache.CacheStats {
+	return cache.CacheStats{
+		Hits:        c.hits.Load(),
+		Misses:      c.misses.Load(),
+		Sets:        c.sets.Load(),
+		Deletes:     c.deletes.Load(),
+		CurrentSize: int64(c.Size()),
+	}
+}
+
+func (c *redisCache[V]) ResetStats() {
+	c.hits.Store(0)
+	c.misses.Store(0)
+	c.sets.Store(0)
+	c.deletes.Store(0)
+}
+
+func (c *redisCache[V]) Set(key string, val V, options ...cache.SetOption) {
+	_, err := c.set(context.Background(), key, val, "", options)
+	failed(err)
+}
+
+// SetE behaves like Set, but returns any error from Redis, or ErrNotSupported, instead of dropping it or panicking
+func (c *redisCache[V]) SetE(key string, val V, options ...cache.SetOption) error {
+	_, err := c.set(context.Background(), key, val, "", options)
+	return err
+}
+
+func (c *redisCache[V]) SetMany(entries map[string]V, options ...cache.SetOption) {
+	for key, val := range entries {
+		c.Set(key, val, options...)
+	}
+}
+
+// MSet stores each entry of entries with its own SET, returning the first error from Redis.
+// Unlike Redis's MSET, the entries are not stored atomically, so that watchers see each entry replaced
+func (c *redisCache[V]) MSet(entries map[string]V) error {
+	ctx := context.Background()
+	if err := c.check(ctx); err != nil {
+		return err
+	}
+
+	for key, val := range entries {
+		if _, err := c.set(ctx, key, val, "", nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *redisCache[V]) SetBatch(entries []cache.BatchEntry[string, V]) {
+	for _, e := range entries {
+		c.Set(e.Key, e.Value, e.Options...)
+	}
+}
+
+func (c *redisCache[V]) SetCtx(ctx context.Context, key string, val V, options ...cache.SetOption) error {
+	if err := c.check(ctx); err != nil {
+		return err
+	}
+
+	_, err := c.set(ctx, key, val, "", options)
+	return err
+}
+
+// SetWithTags stores the entry, then adds key to the Redis set holding the keys registered under each tag
+func (c *redisCache[V]) SetWithTags(key string, val V, tags []string, options ...cache.SetOption) {
+	ctx := context.Background()
+	ok, err := c.set(ctx, key, val, "", options)
+	if failed(err) || !ok {
+		return
+	}
+
+	_, err = c.client.Pipelined(ctx, func(p goredis.Pipeliner) error {
+		for _, tag := range tags {
+			p.SAdd(ctx, tagPrefix+tag, key)
+		}
+
+		return nil
+	})
+
+	failed(err)
+}
+
+// GetOrSet retrieves the entry at the specified key, storing the result of fn if none exists.
+// Two clients missing at once may both call fn, and the later store wins
+func (c *redisCache[V]) GetOrSet(key string, fn func() V, options ...cache.SetOption) V {
+	ctx := context.Background()
+	if v, ok, err := c.get(ctx, key); !failed(err) && ok {
+		return v
+	}
+
+	v := fn()
+	_, err := c.set(ctx, key, v, "", options)
+	failed(err)
+	return v
+}
+
+// GetOrSetMany retrieves the entries at the specified keys with MGET, calling loader once with the keys that are missing
+// and storing the entries it returns. As with GetOrSet, two clients missing at once may both call loader,
+// and the later store wins. Entries loader returns for keys it was not passed are ignored
+func (c *redisCache[V]) GetOrSetMany(keys []string, loader func(keys []string) map[string]V, options ...cache.SetOption) map[string]V {
+	found := c.GetMany(keys)
+
+	var missing []string
+	for _, key := range keys {
+		if _, ok := found[key]; !ok && !slices.Contains(missing, key) {
+			missing = append(missing, key)
+		}
+	}
+
+	if len(missing) == 0 {
+		return found
+	}
+
+	loaded := loader(missing)
+	for _, key := range missing {
+		if v, ok := loaded[key]; ok {
+			c.Set(key, v, options...)
+			found[key] = v
+		}
+	}
+
+	return found
+}
+
+// GetOrLoad retrieves the entry at the specified key, storing the value loader returns with SET NX,
+// using the TTL it returns, if none exists. A non-positive TTL stores the entry without one.
+// Concurrent misses through this Cacher share one call to loader, but other clients may load the same key;
+// whichever stores its value first wins, and is returned. If loader returns an error, nothing is stored
+// and the error is returned
+func (c *redisCache[V]) GetOrLoad(key string, loader func() (V, time.Duration, error)) (V, error) {
+	ctx := context.Background()
+	if v, ok, err := c.get(ctx, key); err != nil || ok {
+		return v, err
+	}
+
+	c.mu.Lock()
+	l, ok := c.loads[key]
+	if !ok {
+		l = &load[V]{done: make(chan struct{})}
+		c.loads[key] = l
+	}
+
+	c.mu.Unlock()
+
+	if !ok {
+		l.val, l.err = c.runLoad(ctx, key, loader)
+
+		c.mu.Lock()
+		delete(c.loads, key)
+		c.mu.Unlock()
+
+		close(l.done)
+	}
+
+	<-l.done
+	return l.val, l.err
+}
+
+// runLoad calls loader and stores the value it returns at key, unless another entry was stored there first,
+// in which case that entry is returned instead
+func (c *redisCache[V]) runLoad(ctx context.Context, key string, loader func() (V, time.Duration, error)) (V, error) {
+	v, ttl, err := loader()
+	if err != nil {
+		return v, err
+	}
+
+	_, _, ok, err := c.put(ctx, key, v, goredis.SetArgs{Mode: "NX", TTL: max(ttl, 0)})
+	if err != nil || ok {
+		return v, err
+	}
+
+	existing, found, err := decode[V](c.client.Get(ctx, key).Result())
+	if err != nil || !found {
+		return v, err
+	}
+
+	return existing, nil
+}
+
+// SetIfAbsent stores the entry with SET NX
+func (c *redisCache[V]) SetIfAbsent(key string, val V, options ...cache.SetOption) bool {
+	ok, err := c.set(context.Background(), key, val, "NX", options)
+	if failed(err) {
+		return false
+	}
+
+	return ok
+}
+
+func (c *redisCache[V]) SetDefault(key string, val V, options ...cache.SetOption) bool {
+	return c.SetIfAbsent(key, val, options...)
+}
+
+// SetNX stores the entry with SET NX, as SetIfAbsent does
+func (c *redisCache[V]) SetNX(key string, val V, options ...cache.SetOption) bool {
+	return c.SetIfAbsent(key, val, options...)
+}
+
+// SetIfPresent stores the entry with SET XX
+func (c *redisCache[V]) SetIfPresent(key string, val V, options ...cache.SetOption) bool {
+	ok, err := c.set(context.Background(), key, val, "XX", options)
+	if failed(err) {
+		return false
+	}
+
+	return ok
+}
+
+// SetXX stores the entry with SET XX, as SetIfPresent does
+func (c *redisCache[V]) SetXX(key string, val V, options ...cache.SetOption) bool {
+	return c.SetIfPresent(key, val, options...)
+}
+
+// GetAndSet stores the entry with SET GET, which returns the entry it replaced
+func (c *redisCache[V]) GetAndSet(key string, val V, options ...cache.SetOption) (V, bool) {
+	ctx := context.Background()
+	var zero V
+	args, expired, err := setArgs(options)
+	if failed(err) {
+		return zero, false
+	}
+
+	var old V
+	var ok bool
+	if expired {
+		old, ok, err = c.remove(ctx, key)
+	} else {
+		old, ok, _, err = c.put(ctx, key, val, args)
+	}
+
+	if failed(err) {
+		return zero, false
+	}
+
+	return old, ok
+}
+
+// CompareAndSwap stores newVal within a WATCH transaction if the entry at the specified key
+// is deeply equal to oldVal, as reported by reflect.DeepEqual
+func (c *redisCache[V]) CompareAndSwap(key string, oldVal, newVal V, options ...cache.SetOption) bool {
+	args, expired, err := setArgs(options)
+	if failed(err) {
+		return false
+	}
+
+	var swapped bool
+	err = c.transact(context.Background(), key, func(v V, ok bool) (change[V], error) {
+		swapped = ok && reflect.DeepEqual(v, oldVal)
+		return change[V]{store: swapped && !expired, delete: swapped && expired, val: newVal, args: args}, nil
+	})
+
+	if failed(err) {
+		return false
+	}
+
+	return swapped
+}
+
+// Increment adds delta to the numeric entry at the specified key within a WATCH transaction, keeping its TTL.
+// See cache.Cache.Increment
+func (c *redisCache[V]) Increment(key string, delta int64) (int64, error) {
+	var n int64
+	err := c.update(key, func(s *cache.Cache[string, V]) (err error) {
+		n, err = s.Increment(key, delta)
+		return err
+	})
+
+	return n, err
+}
+
+// Decrement subtracts delta from the numeric entry at the specified key within a WATCH transaction,
+// keeping its TTL. See cache.Cache.Decrement
+func (c *redisCache[V]) Decrement(key string, delta int64) (int64, error) {
+	var n int64
+	err := c.update(key, func(s *cache.Cache[string, V]) (err error) {
+		n, err = s.Decrement(key, delta)
+		return err
+	})
+
+	return n, err
+}
+
+// Append appends suffix to the string entry at the specified key within a WATCH transaction, keeping its TTL.
+// See cache.Cache.Append
+func (c *redisCache[V]) Append(key string, suffix string) (string, error) {
+	var str string
+	err := c.update(key, func(s *cache.Cache[string, V]) (err error) {
+		str, err = s.Append(key, suffix)
+		return err
+	})
+
+	return str, err
+}
+
+// Modify replaces the entry at the specified key with the result of fn within a WATCH transaction,
+// keeping its TTL. fn is called again if another client changes the entry before the transaction commits
+func (c *redisCache[V]) Modify(key string, fn func(current V) V) bool {
+	var modified bool
+	err := c.transact(context.Background(), key, func(v V, ok bool) (change[V], error) {
+		if modified = ok; !ok {
+			return change[V]{}, nil
+		}
+
+		return change[V]{store: true, val: fn(v), args: goredis.SetArgs{KeepTTL: true}}, nil
+	})
+
+	if failed(err) {
+		return false
+	}
+
+	return modified
+}
+
+// Touch sets the TTL of the entry at the specified key with PEXPIRE, or removes it with PERSIST if d is zero
+func (c *redisCache[V]) Touch(key string, d time.Duration) bool {
+	ctx := context.Background()
+	if d > 0 {
+		ok, err := c.client.PExpire(ctx, key, d).Result()
+		return ok && !failed(err)
+	}
+
+	var exists *goredis.IntCmd
+	_, err := c.client.Pipelined(ctx, func(p goredis.Pipeliner) error {
+		p.Persist(ctx, key)
+		exists = p.Exists(ctx, key)
+		return nil
+	})
+
+	return !failed(err) && exists.Val() == 1
+}
+
+// ExpireAt sets the deadline of the entry at the specified key with PEXPIREAT.
+// If t has already passed, the entry is removed immediately
+func (c *redisCache[V]) ExpireAt(key string, t time.Time) bool {
+	ctx := context.Background()
+	if !t.After(time.Now()) {
+		_, ok, err := c.remove(ctx, key)
+		return ok && !failed(err)
+	}
+
+	ok, err := c.client.PExpireAt(ctx, key, t).Result()
+	return ok && !failed(err)
+}
+
+// Expire sets the entry at the specified key to expire d from now with PEXPIRE.
+// A non-positive d removes the entry immediately
+func (c *redisCache[V]) Expire(key string, d time.Duration) bool {
+	ctx := context.Background()
+	if d <= 0 {
+		_, ok, err := c.remove(ctx, key)
+		return ok && !failed(err)
+	}
+
+	ok, err := c.client.PExpire(ctx, key, d).Result()
+	return ok && !failed(err)
+}
+
+// Persist removes the TTL of the entry at the specified key with PERSIST
+func (c *redisCache[V]) Persist(key string) bool {
+	ok, err := c.client.Persist(context.Background(), key).Result()
+	return ok && !failed(err)
+}
+
+// Rename moves the entry at oldKey to newKey with RENAME, which keeps its TTL, then moves its tags.
+// Any entry already at newKey is overwritten
+func (c *redisCache[V]) Rename(oldKey, newKey string) bool {
+	ctx := context.Background()
+	var val, replaced V
+	var ok bool
+	err := retry(func() error {
+		return c.client.Watch(ctx, func(tx *goredis.Tx) error {
+			var err error
+			if val, ok, err = decode[V](tx.Get(ctx, oldKey).Result()); err != nil || !ok || oldKey == newKey {
+				return err
+			}
+
+			if replaced, _, err = decode[V](tx.Get(ctx, newKey).Result()); err != nil {
+				return err
+			}
+
+			_, err = tx.TxPipelined(ctx, func(p goredis.Pipeliner) error {
+				p.Rename(ctx, oldKey, newKey)
+				return nil
+			})
+
+			return err
+		}, oldKey, newKey)
+	})
+
+	if failed(err) {
+		return false
+	}
+
+	if !ok || oldKey == newKey {
+		return ok
+	}
+
+	var zero V
+	c.publish(oldKey, val, zero, cache.WatchDeleted)
+	c.publish(newKey, replaced, val, cache.WatchSet)
+
+	// The entry has moved even if its tags cannot be
+	tags, err := c.tagSets(ctx, oldKey)
+	if failed(err) || len(tags) == 0 {
+		return true
+	}
+
+	_, err = c.client.TxPipelined(ctx, func(p goredis.Pipeliner) error {
+		for _, tag := range tags {
+			p.SRem(ctx, tag, oldKey)
+			p.SAdd(ctx, tag, newKey)
+		}
+
+		return nil
+	})
+
+	failed(err)
+	return true
+}
+
+// tagSets returns the names of the Redis sets holding the tags key is registered under
+func (c *redisCache[V]) tagSets(ctx context.Context, key string) ([]string, error) {
+	var result []string
+	var cursor uint64
+	for {
+		sets, next, err := c.client.Scan(ctx, cursor, escape(tagPrefix)+"*", scanCount).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, set := range sets {
+			ok, err := c.client.SIsMember(ctx, set, key).Result()
+			if err != nil {
+				return nil, err
+			}
+
+			if ok {
+				result = append(result, set)
+			}
+		}
+
+		if cursor = next; cursor == 0 {
+			return result, nil
+		}
+	}
+}
+
+// RemainingTTL reads the TTL of the entry at the specified key with PTTL
+func (c *redisCache[V]) RemainingTTL(key string) (time.Duration, bool) {
+	d, err := c.client.PTTL(context.Background(), key).Result()
+	if failed(err) {
+		return 0, false
+	}
+
+	return d, d > 0
+}
+
+// TTL reads the TTL of the entry at the specified key with PTTL, which replies -1 and -2 as
+// cache.TTLPersistent and cache.TTLMissing
+func (c *redisCache[V]) TTL(key string) (time.Duration, error) {
+	ctx := context.Background()
+	if err := c.check(ctx); err != nil {
+		return 0, err
+	}
+
+	return c.client.PTTL(ctx, key).Result()
+}
+
+// clear removes every entry with FLUSHDB, first reading them if any channel watches them
+func (c *redisCache[V]) clear(ctx context.Context) error {
+	c.mu.Lock()
+	watched := len(c.watchers) > 0
+	c.mu.Unlock()
+
+	var entries []entry[V]
+	var n int
+	if watched {
+		var err error
+		if entries, err = c.entries(ctx, false); err != nil {
+			return err
+		}
+
+		n = len(entries)
+	} else {
+		var err error
+		if n, err = c.size(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err := c.client.FlushDB(ctx).Err(); err != nil {
+		return err
+	}
+
+	var zero V
+	c.deletes.Add(int64(n))
+	for _, e := range entries {
+		c.publish(e.key, e.val, zero, cache.WatchDeleted)
+	}
+
+	return nil
+}
+
+// Clear removes every key in the database with FLUSHDB
+func (c *redisCache[V]) Clear() {
+	failed(c.clear(context.Background()))
+}
+
+// ClearExpired returns 0, since Redis removes expired entries itself
+func (c *redisCache[V]) ClearExpired() int {
+	return 0
+}
+
+// ClearEvery clears the cache on a loop at the specified interval.
+// The loop stops when the cache is closed or when the returned stop func is called,
+// which waits for the loop to exit and may be called more than once
+func (c *redisCache[V]) ClearEvery(d time.Duration) (stop func()) {
+	ticker := time.NewTicker(d)
+	stopped := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.clear(context.Background())
+			case <-stopped:
+				return
+			case <-c.done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(stopped) })
+		<-exited
+	}
+}
+
+func (c *redisCache[V]) Delete(key string) {
+	_, _, err := c.remove(context.Background(), key)
+	failed(err)
+}
+
+func (c *redisCache[V]) DeleteCtx(ctx context.Context, key string) error {
+	if err := c.check(ctx); err != nil {
+		return err
+	}
+
+	_, _, err := c.remove(ctx, key)
+	return err
+}
+
+// removeAll removes the entries at keys, stopping at the first error from Redis.
+// Returns the number of entries that were removed
+func (c *redisCache[V]) removeAll(ctx context.Context, keys []string) int {
+	var n int
+	for _, key := range keys {
+		_, ok, err := c.remove(ctx, key)
+		if failed(err) {
+			return n
+		}
+
+		if ok {
+			n++
+		}
+	}
+
+	return n
+}
+
+func (c *redisCache[V]) DeleteMany(keys []string) int {
+	return c.removeAll(context.Background(), keys)
+}
+
+// deleteMatching removes every entry whose key matches the SCAN pattern.
+// Returns the number of entries that were removed
+func (c *redisCache[V]) deleteMatching(pattern string) int {
+	ctx := context.Background()
+	keys, err := c.keys(ctx, pattern)
+	if failed(err) {
+		return 0
+	}
+
+	return c.removeAll(ctx, keys)
+}
+
+// DeletePrefix removes every entry whose key starts with prefix, found with SCAN MATCH
+func (c *redisCache[V]) DeletePrefix(prefix string) int {
+	return c.deleteMatching(escape(prefix) + "*")
+}
+
+// DeleteSuffix removes every entry whose key ends with suffix, found with SCAN MATCH
+func (c *redisCache[V]) DeleteSuffix(suffix string) int {
+	return c.deleteMatching("*" + escape(suffix))
+}
+
+// DeleteContains removes every entry whose key contains substr, found with SCAN MATCH
+func (c *redisCache[V]) DeleteContains(substr string) int {
+	return c.deleteMatching("*" + escape(substr) + "*")
+}
+
+// DeleteByTag removes every entry whose key is in the Redis set holding tag, then the set itself
+func (c *redisCache[V]) DeleteByTag(tag string) int {
+	ctx := context.Background()
+	keys, err := c.client.SMembers(ctx, tagPrefix+tag).Result()
+	if failed(err) {
+		return 0
+	}
+
+	n := c.removeAll(ctx, keys)
+	failed(c.client.Del(ctx, tagPrefix+tag).Err())
+	return n
+}
+
+// GetAndDelete removes the entry at the specified key with GETDEL, which returns it
+func (c *redisCache[V]) GetAndDelete(key string) (V, bool) {
+	v, ok, err := c.remove(context.Background(), key)
+	if failed(err) {
+		var zero V
+		return zero, false
+	}
+
+	return v, ok
+}
+
+// Evict removes the entry at the specified key as GetAndDelete does; a redis cache has no eviction callbacks
+func (c *redisCache[V]) Evict(key string) (V, bool) {
+	return c.GetAndDelete(key)
+}
+
+// CompareAndDelete removes the entry at the specified key within a WATCH transaction
+// if it is deeply equal to expected, as reported by reflect.DeepEqual
+func (c *redisCache[V]) CompareAndDelete(key string, expected V) bool {
+	var deleted bool
+	err := c.transact(context.Background(), key, func(v V, ok bool) (change[V], error) {
+		deleted = ok && reflect.DeepEqual(v, expected)
+		return change[V]{delete: deleted}, nil
+	})
+
+	if failed(err) {
+		return false
+	}
+
+	return deleted
+}
+
+func (c *redisCache[V]) Get(key string) V {
+	v, _ := c.GetOK(key)
+	return v
+}
+
+func (c *redisCache[V]) GetOK(key string) (V, bool) {
+	v, ok, err := c.get(context.Background(), key)
+	if failed(err) {
+		var zero V
+		return zero, false
+	}
+
+	return v, ok
+}
+
+// Contains reports whether an entry exists at the specified key with EXISTS, so the value is never transferred
+func (c *redisCache[V]) Contains(key string) bool {
+	n, err := c.client.Exists(context.Background(), key).Result()
+	if !failed(err) && n > 0 {
+		c.hits.Add(1)
+		return true
+	}
+
+	c.misses.Add(1)
+	return false
+}
+
+// Probabilistic behaves like GetOK, since Redis does not record how long values take to compute
+func (c *redisCache[V]) Probabilistic(key string, beta float64) (V, bool) {
+	return c.GetOK(key)
+}
+
+func (c *redisCache[V]) GetCtx(ctx context.Context, key string) (V, error) {
+	if err := c.check(ctx); err != nil {
+		var zero V
+		return zero, err
+	}
+
+	v, _, err := c.get(ctx, key)
+	return v, err
+}
+
+// GetMany retrieves the entries at the specified keys with MGET
+func (c *redisCache[V]) GetMany(keys []string) map[string]V {
+	result, err := c.MGet(keys)
+	if failed(err) {
+		return map[string]V{}
+	}
+
+	return result
+}
+
+// MGet retrieves the entries at the specified keys with MGET, returning any error from Redis
+func (c *redisCache[V]) MGet(keys []string) (map[string]V, error) {
+	result := make(map[string]V, len(keys))
+	if len(keys) == 0 {
+		return result, nil
+	}
+
+	vals, err := c.client.MGet(context.Background(), keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, val := range vals {
+		data, ok := val.(string)
+		if !ok {
+			c.misses.Add(1)
+			continue
+		}
+
+		v, _, err := decode[V](data, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		c.hits.Add(1)
+		result[keys[i]] = v
+	}
+
+	return result, nil
+}
+
+// WaitForKey retrieves the entry at the specified key, blocking until one is stored if none exists.
+// An entry stored through this Cacher is seen at once, while one stored by another client is only seen
+// by polling Redis. Returns ctx.Err() if ctx is done first, or cache.ErrClosed if the cache is closed first
+func (c *redisCache[V]) WaitForKey(ctx context.Context, key string) (V, error) {
+	var zero V
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		c.mu.Lock()
+		changed := c.changed
+		c.mu.Unlock()
+
+		v, ok, err := c.get(ctx, key)
+		if err != nil {
+			return zero, err
+		}
+
+		if ok {
+			return v, nil
+		}
+
+		select {
+		case <-changed:
+		case <-ticker.C:
+		case <-c.done:
+			return zero, cache.ErrClosed
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		}
+	}
+}
+
+// Watch returns a channel that receives a WatchEvent whenever the entry at the specified key is stored or removed
+// through this Cacher, along with a function that stops watching and closes the channel.
+// Events that arrive while the channel is full are dropped. See cache.Cache.Watch
+func (c *redisCache[V]) Watch(key string) (<-chan cache.WatchEvent[string, V], func()) {
+	return c.watch(&watcher[V]{key: key, ch: make(chan cache.WatchEvent[string, V], watchBuffer)})
+}
+
+// WatchPrefix behaves like Watch, but the channel receives a WatchEvent for every key that starts with prefix
+func (c *redisCache[V]) WatchPrefix(prefix string) (<-chan cache.WatchEvent[string, V], func()) {
+	return c.watch(&watcher[V]{prefix: prefix, prefixed: true, ch: make(chan cache.WatchEvent[string, V], watchBuffer)})
+}
+
+func (c *redisCache[V]) watch(w *watcher[V]) (<-chan cache.WatchEvent[string, V], func()) {
+	c.mu.Lock()
+	c.watchers = append(c.watchers, w)
+	c.mu.Unlock()
+
+	var once sync.Once
+	return w.ch, func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+
+			if i := slices.Index(c.watchers, w); i >= 0 {
+				c.watchers = slices.Delete(c.watchers, i, i+1)
+				close(w.ch)
+			}
+		})
+	}
+}
+
+// Items retrieves every entry in the database, listed with SCAN
+func (c *redisCache[V]) Items() map[string]V {
+	return c.FilterItems(func(string, V) bool { return true })
+}
+
+// Dump retrieves every entry in the database along with when it expires, read with pipelined GET and PTTL
+func (c *redisCache[V]) Dump() map[string]cache.CacheEntry[V] {
+	entries, err := c.entries(context.Background(), true)
+	if failed(err) {
+		return map[string]cache.CacheEntry[V]{}
+	}
+
+	now := time.Now()
+	result := make(map[string]cache.CacheEntry[V], len(entries))
+	for _, e := range entries {
+		ce := cache.CacheEntry[V]{Value: e.val}
+		if e.ttl > 0 {
+			ce.ExpiresAt, ce.HasExpiry = now.Add(e.ttl), true
+		}
+
+		result[e.key] = ce
+	}
+
+	return result
+}
+
+func (c *redisCache[V]) Values() []V {
+	entries, err := c.entries(context.Background(), false)
+	if failed(err) {
+		return []V{}
+	}
+
+	vals := make([]V, len(entries))
+	for i, e := range entries {
+		vals[i] = e.val
+	}
+
+	return vals
+}
+
+// ForEach calls fn for each entry in the database. Entries are read before fn is first called,
+// so fn may call back into the cache
+func (c *redisCache[V]) ForEach(fn func(key string, val V)) {
+	entries, err := c.entries(context.Background(), false)
+	if failed(err) {
+		return
+	}
+
+	for _, e := range entries {
+		fn(e.key, e.val)
+	}
+}
+
+func (c *redisCache[V]) FilterItems(predicate func(string, V) bool) map[string]V {
+	entries, err := c.entries(context.Background(), false)
+	if failed(err) {
+		return map[string]V{}
+	}
+
+	result := map[string]V{}
+	for _, e := range entries {
+		if predicate(e.key, e.val) {
+			result[e.key] = e.val
+		}
+	}
+
+	return result
+}
+
+func (c *redisCache[V]) Count(predicate func(string, V) bool) int {
+	return len(c.FilterItems(predicate))
+}
+
+func (c *redisCache[V]) IsEmpty() bool {
+	return c.Size() == 0
+}
+
+// size counts the keys in the database with SCAN, leaving out the sets holding tags
+func (c *redisCache[V]) size(ctx context.Context) (int, error) {
+	var n int
+	err := c.scan(ctx, "*", func(keys []string) error {
+		n += len(keys)
+		return nil
+	})
+
+	return n, err
+}
+
+// Size returns the number of keys in the database, counted with SCAN
+func (c *redisCache[V]) Size() int {
+	n, err := c.size(context.Background())
+	if failed(err) {
+		return 0
+	}
+
+	return n
+}
+
+// Keys retrieves a sorted list of the keys in the database, listed with SCAN
+func (c *redisCache[V]) Keys() []string {
+	keys := c.UnsortedKeys()
+	slices.Sort(keys)
+	return keys
+}
+
+func (c *redisCache[V]) UnsortedKeys() []string {
+	keys, err := c.keys(context.Background(), "*")
+	if failed(err) {
+		return nil
+	}
+
+	return keys
+}
+
+// Scan runs a single SCAN with count as its COUNT hint, returning the keys it found and the cursor to continue from.
+// Unlike cache.Cache.Scan it follows Redis's guarantees: keys come in no particular order, may be returned
+// more than once, and a call may return more or fewer than count keys, or none before the scan is complete
+func (c *redisCache[V]) Scan(cursor, count int) (int, []string) {
+	if count <= 0 {
+		count = 10
+	}
+
+	// A cursor of 0 ends the caller's scan
+	keys, next, err := c.client.Scan(context.Background(), uint64(cursor), "*", int64(count)).Result()
+	if failed(err) {
+		return 0, nil
+	}
+
+	keys = slices.DeleteFunc(keys, func(key string) bool { return strings.HasPrefix(key, tagPrefix) })
+	return int(next), keys
+}
+
+// KeysExpiringSoon retrieves a sorted list of the keys whose entries expire within the specified duration from now,
+// reading the TTL of every key with pipelined PTTLs
+func (c *redisCache[V]) KeysExpiringSoon(within time.Duration) []string {
+	ctx := context.Background()
+	result := []string{}
+	err := c.scan(ctx, "*", func(keys []string) error {
+		ttls := make([]*goredis.DurationCmd, len(keys))
+		_, err := c.client.Pipelined(ctx, func(p goredis.Pipeliner) error {
+			for i, key := range keys {
+				ttls[i] = p.PTTL(ctx, key)
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return err
+		}
+
+		for i, key := range keys {
+			if ttl := ttls[i].Val(); ttl > 0 && ttl <= within {
+				result = append(result, key)
+			}
+		}
+
+		return nil
+	})
+
+	if failed(err) {
+		return []string{}
+	}
+
+	slices.Sort(result)
+	return result
+}
+
+// ExpiredKeys returns an empty list, since Redis never returns a key that has passed its deadline,
+// even before it is removed
+func (c *redisCache[V]) ExpiredKeys() []string {
+	return []string{}
+}
+
+func (c *redisCache[V]) FilterKeys(predicate func(string) bool) []string {
+	return slices.DeleteFunc(c.Keys(), func(key string) bool { return !predicate(key) })
+}
+
+// ExportJSON writes the entries to w as JSON, in the format cache.Cache.ExportJSON writes
+func (c *redisCache[V]) ExportJSON(w io.Writer) error {
+	return c.export(func(s *cache.Cache[string, V]) error { return s.ExportJSON(w) })
+}
+
+func (c *redisCache[V]) ImportJSON(r io.Reader) error {
+	return c.load(func(s *cache.Cache[string, V]) error { return s.ImportJSON(r) }, false)
+}
+
+// ExportGob writes the entries to w using encoding/gob, in the format cache.Cache.ExportGob writes
+func (c *redisCache[V]) ExportGob(w io.Writer) error {
+	return c.export(func(s *cache.Cache[string, V]) error { return s.ExportGob(w) })
+}
+
+func (c *redisCache[V]) ImportGob(r io.Reader) error {
+	return c.load(func(s *cache.Cache[string, V]) error { return s.ImportGob(r) }, false)
+}
+
+func (c *redisCache[V]) SaveToFile(path string) error {
+	return c.export(func(s *cache.Cache[string, V]) error { return s.SaveToFile(path) })
+}
+
+func (c *redisCache[V]) LoadFromFile(path string) error {
+	return c.load(func(s *cache.Cache[string, V]) error { return s.LoadFromFile(path) }, false)
+}
+
+// Snapshot returns the entries encoded as cache.Cache.Snapshot encodes them
+func (c *redisCache[V]) Snapshot() ([]byte, error) {
+	var data []byte
+	err := c.export(func(s *cache.Cache[string, V]) (err error) {
+		data, err = s.Snapshot()
+		return err
+	})
+
+	return data, err
+}
+
+// Restore replaces every key in the database with the entries in data, returned by Snapshot
+func (c *redisCache[V]) Restore(data []byte) error {
+	return c.load(func(s *cache.Cache[string, V]) error { return s.Restore(data) }, true)
+}
+
+// Close stops ClearEvery loops and closes every Watch channel, leaving the client open.
+// Returns cache.ErrClosed if the cache has already been closed
+func (c *redisCache[V]) Close() error {
+	err := cache.ErrClosed
+	c.closeOnce.Do(func() {
+		close(c.done)
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		for _, w := range c.watchers {
+			close(w.ch)
+		}
+
+		c.watchers = nil
+		err = nil
+	})
+
+	return err
+}
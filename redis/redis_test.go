@@ -0,0 +1,391 @@
+package redis
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	cache "github.com/robotsrulz/go-cache"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func newCache(t *testing.T) (cache.StringCacher, *miniredis.Miniredis) {
+	m := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: m.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	c := NewRedisCache[cache.T](client)
+	t.Cleanup(func() { c.Close() })
+	return c, m
+}
+
+func TestRedisCache(t *testing.T) {
+	c, _ := newCache(t)
+	c.Set("2", 2)
+	c.Set("1", 1)
+	c.Set("3", "three", cache.Expire(time.Hour))
+
+	if result, expected := c.Get("1"), cache.T(1); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if result, expected := c.Keys(), []string{"1", "2", "3"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if _, ok := c.RemainingTTL("1"); ok {
+		t.Errorf("Entry for key '1' should have no TTL")
+	}
+
+	if ttl, ok := c.RemainingTTL("3"); !ok || ttl <= 0 || ttl > time.Hour {
+		t.Errorf("Result was %v, expected a TTL of up to %v", ttl, time.Hour)
+	}
+
+	c.Delete("2")
+	expected := map[string]cache.T{"1": 1, "3": "three"}
+	if result := c.Items(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if result, expected := c.Stats(), (cache.CacheStats{Hits: 1, Sets: 3, Deletes: 1, CurrentSize: 2}); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	c.Clear()
+	if !c.IsEmpty() {
+		t.Errorf("Cache should be empty after Clear")
+	}
+}
+
+func TestRedisCacheExpire(t *testing.T) {
+	c, m := newCache(t)
+	c.Set("1", 1, cache.Expire(time.Second))
+	c.Set("2", 2)
+
+	m.FastForward(time.Second)
+	if _, ok := c.GetOK("1"); ok {
+		t.Errorf("Entry for key '1' should have expired by now")
+	}
+
+	if !c.Touch("2", time.Second) || c.Touch("1", time.Second) {
+		t.Errorf("Touch did not report only the existing entry")
+	}
+
+	m.FastForward(time.Second)
+	if result, expected := c.Size(), 0; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestRedisCacheExpirePersist(t *testing.T) {
+	c, m := newCache(t)
+	c.Set("1", 1)
+	c.Set("2", 2)
+
+	if !c.Expire("1", time.Second) || !c.Expire("2", 0) || c.Expire("3", time.Second) {
+		t.Errorf("Expire did not report only the existing entries")
+	}
+
+	if _, ok := c.GetOK("2"); ok {
+		t.Errorf("Entry for key '2' should have been removed")
+	}
+
+	if !c.Persist("1") || c.Persist("1") {
+		t.Errorf("Persist did not report only the entry with a TTL")
+	}
+
+	m.FastForward(time.Second)
+	if _, ok := c.GetOK("1"); !ok {
+		t.Errorf("Entry for key '1' should not have expired")
+	}
+}
+
+func TestRedisCacheTTL(t *testing.T) {
+	c, _ := newCache(t)
+	c.Set("1", 1, cache.Expire(time.Hour))
+	c.Set("2", 2)
+
+	if ttl, err := c.TTL("1"); err != nil || ttl <= 0 || ttl > time.Hour {
+		t.Errorf("Result was %v, %v, expected a TTL of up to %v", ttl, err, time.Hour)
+	}
+
+	if result, err := c.TTL("2"); err != nil || result != cache.TTLPersistent {
+		t.Errorf("Result was %v, %v, expected %v", result, err, cache.TTLPersistent)
+	}
+
+	if result, err := c.TTL("3"); err != nil || result != cache.TTLMissing {
+		t.Errorf("Result was %v, %v, expected %v", result, err, cache.TTLMissing)
+	}
+
+	c.Set("4", 4, cache.Expire(time.Millisecond*1500))
+	if ttl, err := c.TTL("4"); err != nil || ttl <= time.Second || ttl > time.Millisecond*1500 {
+		t.Errorf("Result was %v, %v, expected a TTL of up to %v", ttl, err, time.Millisecond*1500)
+	}
+}
+
+func TestRedisCacheAfterFunc(t *testing.T) {
+	c, _ := newCache(t)
+	after := cache.AfterFunc(time.Second, func(cache.T) {})
+
+	if err := c.SetCtx(context.Background(), "1", 1, after); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Result was %v, expected %v", err, ErrNotSupported)
+	}
+
+	defer func() {
+		if r := recover(); r != ErrNotSupported {
+			t.Errorf("Result was %v, expected a panic with %v", r, ErrNotSupported)
+		}
+	}()
+
+	c.Set("1", 1, after)
+}
+
+func TestRedisCacheConditional(t *testing.T) {
+	c, _ := newCache(t)
+
+	if !c.SetIfAbsent("1", 1) || c.SetIfAbsent("1", 2) {
+		t.Errorf("SetIfAbsent did not store only the first entry")
+	}
+
+	if c.SetIfPresent("2", 2) || !c.SetIfPresent("1", 3) {
+		t.Errorf("SetIfPresent did not store only over the existing entry")
+	}
+
+	if old, ok := c.GetAndSet("1", 4); !ok || old != cache.T(3) {
+		t.Errorf("Result was %#v, %v, expected %#v", old, ok, 3)
+	}
+
+	if !c.CompareAndSwap("1", 4, 5) || c.CompareAndSwap("1", 4, 6) {
+		t.Errorf("CompareAndSwap did not swap only the expected value")
+	}
+
+	if c.CompareAndDelete("1", 4) || !c.CompareAndDelete("1", 5) {
+		t.Errorf("CompareAndDelete did not delete only the expected value")
+	}
+
+	if _, ok := c.GetAndDelete("1"); ok {
+		t.Errorf("Entry for key '1' should have been deleted")
+	}
+}
+
+func TestRedisCacheUpdate(t *testing.T) {
+	c, _ := newCache(t)
+	c.Set("1", 1, cache.Expire(time.Hour))
+
+	if n, err := c.Increment("1", 2); err != nil || n != 3 {
+		t.Errorf("Result was %#v, %v, expected %#v", n, err, 3)
+	}
+
+	if _, ok := c.RemainingTTL("1"); !ok {
+		t.Errorf("Increment should have kept the TTL")
+	}
+
+	c.Set("2", "a")
+	if s, err := c.Append("2", "b"); err != nil || s != "ab" {
+		t.Errorf("Result was %#v, %v, expected %#v", s, err, "ab")
+	}
+
+	if _, err := c.Increment("2", 1); !errors.Is(err, cache.ErrTypeMismatch) {
+		t.Errorf("Result was %v, expected %v", err, cache.ErrTypeMismatch)
+	}
+
+	if !c.Modify("1", func(v cache.T) cache.T { return v.(int) * 2 }) || c.Modify("3", func(v cache.T) cache.T { return v }) {
+		t.Errorf("Modify did not modify only the existing entry")
+	}
+
+	if result, expected := c.Get("1"), cache.T(6); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestRedisCacheScan(t *testing.T) {
+	c, _ := newCache(t)
+	for i := 0; i < scanCount*3; i++ {
+		c.Set(fmt.Sprintf("a%d", i), i)
+	}
+
+	c.Set("b*", 0)
+	c.SetWithTags("c1", 1, []string{"x"})
+	c.SetWithTags("c2", 2, []string{"x"})
+
+	if result, expected := len(c.Keys()), scanCount*3+3; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if result, expected := c.DeleteByTag("x"), 2; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if result, expected := c.DeletePrefix("b*"), 1; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if result, expected := c.DeletePrefix("a"), scanCount*3; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if !c.IsEmpty() {
+		t.Errorf("Cache should be empty, but holds %v", c.Keys())
+	}
+}
+
+func TestRedisCacheRename(t *testing.T) {
+	c, _ := newCache(t)
+	c.SetWithTags("1", 1, []string{"x"}, cache.Expire(time.Hour))
+
+	if !c.Rename("1", "2") || c.Rename("1", "3") {
+		t.Errorf("Rename did not move only the existing entry")
+	}
+
+	if _, ok := c.RemainingTTL("2"); !ok {
+		t.Errorf("Rename should have kept the TTL")
+	}
+
+	if result, expected := c.DeleteByTag("x"), 1; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestRedisCacheWatch(t *testing.T) {
+	c, _ := newCache(t)
+	events, stop := c.Watch("1")
+	c.Set("1", 1)
+	c.Set("1", 2)
+	c.Set("2", 2)
+	c.Delete("1")
+	stop()
+
+	var result []cache.WatchEvent[string, cache.T]
+	for e := range events {
+		result = append(result, e)
+	}
+
+	expected := []cache.WatchEvent[string, cache.T]{
+		{Key: "1", NewValue: 1, EventType: cache.WatchSet},
+		{Key: "1", OldValue: 1, NewValue: 2, EventType: cache.WatchSet},
+		{Key: "1", OldValue: 2, EventType: cache.WatchDeleted},
+	}
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestRedisCacheExportImport(t *testing.T) {
+	c, _ := newCache(t)
+	c.Set("1", 1)
+	c.Set("2", "two", cache.Expire(time.Hour))
+
+	var buf bytes.Buffer
+	if err := c.ExportJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	imported, _ := newCache(t)
+	if err := imported.ImportJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if result, expected := imported.Items(), c.Items(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if _, ok := imported.RemainingTTL("2"); !ok {
+		t.Errorf("Imported entry for key '2' should have a TTL")
+	}
+
+	data, err := c.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imported.Set("3", 3)
+	if err := imported.Restore(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if result, expected := imported.Keys(), []string{"1", "2"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestRedisCacheGetOrLoad(t *testing.T) {
+	c, _ := newCache(t)
+
+	v, err := c.GetOrLoad("1", func() (cache.T, time.Duration, error) { return 1, time.Hour, nil })
+	if err != nil || v != cache.T(1) {
+		t.Errorf("Result was %#v, %v, expected %#v", v, err, 1)
+	}
+
+	if _, ok := c.RemainingTTL("1"); !ok {
+		t.Errorf("Loaded entry should have a TTL")
+	}
+
+	errLoad := errors.New("load failed")
+	if _, err := c.GetOrLoad("2", func() (cache.T, time.Duration, error) { return 2, 0, errLoad }); err != errLoad {
+		t.Errorf("Error was %v, expected %v", err, errLoad)
+	}
+
+	if _, ok := c.GetOK("2"); ok {
+		t.Errorf("A failed load should not have stored an entry")
+	}
+}
+
+func TestRedisCacheScanCursor(t *testing.T) {
+	c, _ := newCache(t)
+	c.SetWithTags("1", 1, []string{"x"})
+	c.Set("2", 2)
+
+	seen := map[string]bool{}
+	cursor := 0
+	for {
+		var keys []string
+		cursor, keys = c.Scan(cursor, 1)
+		for _, key := range keys {
+			seen[key] = true
+		}
+
+		if cursor == 0 {
+			break
+		}
+	}
+
+	if expected := map[string]bool{"1": true, "2": true}; !reflect.DeepEqual(seen, expected) {
+		t.Errorf("Result was %#v, expected %#v", seen, expected)
+	}
+}
+
+func TestRedisCacheUnavailable(t *testing.T) {
+	c, m := newCache(t)
+	c.Set("1", 1)
+	m.Close()
+
+	c.Set("2", 2)
+	c.Delete("1")
+
+	if v, ok := c.GetOK("1"); ok || v != nil {
+		t.Errorf("Result was %#v, %v, expected a miss", v, ok)
+	}
+
+	if c.Contains("1") || c.SetIfAbsent("3", 3) || c.CompareAndSwap("1", 1, 2) {
+		t.Errorf("A call to an unavailable Redis should not report success")
+	}
+
+	if result, expected := c.Size(), 0; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if result := c.Items(); len(result) != 0 {
+		t.Errorf("Result was %#v, expected no entries", result)
+	}
+
+	if err := c.SetCtx(context.Background(), "2", 2); err == nil {
+		t.Errorf("SetCtx should have returned the error from Redis")
+	}
+}
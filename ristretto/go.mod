@@ -0,0 +1,14 @@
+module github.com/robotsrulz/go-cache/ristretto
+
+go 1.23.0
+
+require (
+	github.com/dgraph-io/ristretto/v2 v2.3.0
+	github.com/robotsrulz/go-cache v0.1.0
+)
+
+require (
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/dustin/go-humanize v1.0.1 // indirect
+	golang.org/x/sys v0.35.0 // indirect
+)
@@ -0,0 +1,1470 @@
+// Package ristretto adapts a ristretto cache, from github.com/dgraph-io/ristretto/v2, to the cache.Cacher interface,
+// so code written against a Cacher can move to ristretto without changes
+package ristretto
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	cache "github.com/robotsrulz/go-cache"
+
+	"github.com/dgraph-io/ristretto/v2"
+	"github.com/dgraph-io/ristretto/v2/z"
+)
+
+// A Key is a key type supported by both ristretto and Cacher
+type Key interface {
+	uint64 | string | byte | int | uint | int32 | uint32 | int64
+}
+
+// A hash identifies a key as ristretto does, by its key and conflict hashes
+type hash [2]uint64
+
+// A watcher is a channel returned by Watch, or by WatchPrefix if prefixed is true
+type watcher[K Key, V any] struct {
+	key      K
+	prefix   string
+	prefixed bool
+	ch       chan cache.WatchEvent[K, V]
+}
+
+// watchBuffer is the number of events a Watch channel holds before further events are dropped
+const watchBuffer = 64
+
+// A Cache is a cache.Cacher backed by a ristretto cache.
+// Since ristretto cannot list its keys, the Cache tracks them alongside it, forgetting each key
+// when ristretto reports it evicted, rejected or expired.
+// Like ristretto, the Cache admits entries selectively: a Set may be dropped by ristretto's admission policy,
+// so an entry that was just set can be missing. Calls that modify the cache are serialized,
+// so that calls whose result depends on the current value, such as CompareAndSwap or Increment, are atomic
+type Cache[K Key, V any] struct {
+	r         *ristretto.Cache[K, V]
+	keyToHash func(key K) (uint64, uint64)
+	cost      int64
+
+	// mu is held by every call that modifies the cache
+	mu sync.Mutex
+
+	// idx guards the fields below it. ristretto's callbacks lock it from ristretto's own goroutine,
+	// so it must not be held while calling a ristretto method that waits for that goroutine,
+	// such as Wait, Del, Clear or Close
+	idx      sync.Mutex
+	keys     map[hash]K
+	tags     map[string]map[K]struct{}
+	keyTags  map[K][]string
+	timers   map[K]*timer
+	watchers []*watcher[K, V]
+	changed  chan struct{}
+
+	hits      atomic.Int64
+	misses    atomic.Int64
+	sets      atomic.Int64
+	deletes   atomic.Int64
+	evictions atomic.Int64
+	expired   atomic.Int64
+
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// scans holds the snapshots of the scans in progress started by Scan
+	scans scanner[K]
+}
+
+var _ cache.Cacher[string, cache.T] = (*Cache[string, cache.T])(nil)
+
+// New returns a Cache backed by a ristretto cache created from config.
+// The OnEvict and OnReject callbacks in config are wrapped to keep track of the keys the Cache holds, and are
+// still called. Entries are stored with a cost of 1, or of 0 if config.Cost is set, so that ristretto calls it
+func New[K Key, V any](config *ristretto.Config[K, V]) (*Cache[K, V], error) {
+	c := &Cache[K, V]{
+		keyToHash: config.KeyToHash,
+		cost:      1,
+		keys:      map[hash]K{},
+		tags:      map[string]map[K]struct{}{},
+		keyTags:   map[K][]string{},
+		timers:    map[K]*timer{},
+		changed:   make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	if c.keyToHash == nil {
+		c.keyToHash = z.KeyToHash[K]
+	}
+
+	if config.Cost != nil {
+		c.cost = 0
+	}
+
+	cfg := *config
+	cfg.OnEvict = func(item *ristretto.Item[V]) {
+		reason := cache.Capacity
+		if !item.Expiration.IsZero() && !item.Expiration.After(time.Now()) {
+			reason = cache.Expired
+		}
+
+		c.forget(hash{item.Key, item.Conflict}, item.Value, reason)
+		if config.OnEvict != nil {
+			config.OnEvict(item)
+		}
+	}
+
+	cfg.OnReject = func(item *ristretto.Item[V]) {
+		c.idx.Lock()
+		if key, ok := c.keys[hash{item.Key, item.Conflict}]; ok {
+			c.untrack(key, true)
+		}
+
+		c.idx.Unlock()
+		if config.OnReject != nil {
+			config.OnReject(item)
+		}
+	}
+
+	r, err := ristretto.NewCache(&cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	c.r = r
+	return c, nil
+}
+
+// A timer removes an entry set with AfterFunc once it expires
+type timer struct {
+	t *time.Timer
+}
+
+// hash returns the hash ristretto identifies key by
+func (c *Cache[K, V]) hash(key K) hash {
+	h, conflict := c.keyToHash(key)
+	return hash{h, conflict}
+}
+
+// untrack forgets the key, its tags and, if stop is true, its AfterFunc timer. It must only be called with idx held
+func (c *Cache[K, V]) untrack(key K, stop bool) {
+	delete(c.keys, c.hash(key))
+	for _, tag := range c.keyTags[key] {
+		delete(c.tags[tag], key)
+		if len(c.tags[tag]) == 0 {
+			delete(c.tags, tag)
+		}
+	}
+
+	delete(c.keyTags, key)
+	if t, ok := c.timers[key]; ok && stop {
+		t.t.Stop()
+		delete(c.timers, key)
+	}
+}
+
+// tracked reports if key is tracked as held by the cache. It must only be called with idx held
+func (c *Cache[K, V]) tracked(key K) bool {
+	k, ok := c.keys[c.hash(key)]
+	return ok && k == key
+}
+
+// forget untracks the key ristretto reports it removed for reason. An expired entry keeps its AfterFunc timer,
+// which still fires to call the function
+func (c *Cache[K, V]) forget(h hash, val V, reason cache.EvictionReason) {
+	c.idx.Lock()
+	defer c.idx.Unlock()
+
+	key, ok := c.keys[h]
+	if !ok {
+		return
+	}
+
+	c.untrack(key, reason != cache.Expired)
+	var zero V
+	if reason == cache.Expired {
+		c.expired.Add(1)
+		c.publish(key, val, zero, cache.WatchExpired)
+	} else {
+		c.evictions.Add(1)
+		c.publish(key, val, zero, cache.WatchDeleted)
+	}
+}
+
+// publish sends a WatchEvent to the channels watching key, dropping it for any that are full.
+// It must only be called with idx held
+func (c *Cache[K, V]) publish(key K, old, val V, t cache.WatchEventType) {
+	if len(c.watchers) == 0 {
+		return
+	}
+
+	e := cache.WatchEvent[K, V]{Key: key, OldValue: old, NewValue: val, EventType: t}
+	for _, w := range c.watchers {
+		if w.prefixed && strings.HasPrefix(keyString(key), w.prefix) || !w.prefixed && w.key == key {
+			select {
+			case w.ch <- e:
+			default:
+			}
+		}
+	}
+}
+
+// store sets val into ristretto at key with the specified ttl, waiting until ristretto has admitted or rejected it.
+// Unless keep is true, any AfterFunc timer for key is replaced by one calling after, if it is not nil.
+// It must only be called with mu held
+func (c *Cache[K, V]) store(key K, val V, ttl time.Duration, after func(val cache.T), keep bool) {
+	c.idx.Lock()
+	var old V
+	if len(c.watchers) > 0 {
+		old, _ = c.r.Get(key)
+	}
+
+	c.keys[c.hash(key)] = key
+	if t, ok := c.timers[key]; ok && !keep {
+		t.t.Stop()
+		delete(c.timers, key)
+	}
+
+	c.idx.Unlock()
+
+	c.sets.Add(1)
+	admitted := c.r.SetWithTTL(key, val, c.cost, ttl)
+	c.r.Wait()
+
+	c.idx.Lock()
+	defer c.idx.Unlock()
+
+	if !admitted {
+		c.untrack(key, !keep)
+		return
+	}
+
+	if !c.tracked(key) {
+		return
+	}
+
+	if after != nil && ttl > 0 {
+		t := &timer{}
+		t.t = time.AfterFunc(ttl, func() { c.fire(key, val, t, after) })
+		c.timers[key] = t
+	}
+
+	c.publish(key, old, val, cache.WatchSet)
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// set stores val at key as asked by options. It must only be called with mu held
+func (c *Cache[K, V]) set(key K, val V, options []cache.SetOption) {
+	opts := cache.ReadSetOptions(options...)
+	if opts.Expired {
+		c.remove(key)
+		return
+	}
+
+	c.store(key, val, opts.Expiry, opts.AfterFunc, false)
+}
+
+// fire removes the entry at key once the AfterFunc timer t set for val has elapsed, then calls after
+func (c *Cache[K, V]) fire(key K, val V, t *timer, after func(val cache.T)) {
+	c.mu.Lock()
+	c.idx.Lock()
+	if c.timers[key] != t {
+		c.idx.Unlock()
+		c.mu.Unlock()
+		return
+	}
+
+	delete(c.timers, key)
+	if c.tracked(key) {
+		c.untrack(key, false)
+		c.expired.Add(1)
+		var zero V
+		c.publish(key, val, zero, cache.WatchExpired)
+	}
+
+	c.idx.Unlock()
+	c.r.Del(key)
+	c.mu.Unlock()
+
+	after(val)
+}
+
+// remove deletes the entry at key, returning its value. It must only be called with mu held
+func (c *Cache[K, V]) remove(key K) (V, bool) {
+	v, ok := c.r.Get(key)
+
+	c.idx.Lock()
+	c.untrack(key, true)
+	if ok {
+		var zero V
+		c.publish(key, v, zero, cache.WatchDeleted)
+	}
+
+	c.idx.Unlock()
+
+	c.r.Del(key)
+	if ok {
+		c.deletes.Add(1)
+	}
+
+	return v, ok
+}
+
+// get reads the entry at key, counting the read as a hit or a miss
+func (c *Cache[K, V]) get(key K) (V, bool) {
+	v, ok := c.r.Get(key)
+	if ok {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+
+	return v, ok
+}
+
+// ttl returns the remaining TTL of the entry at key, or 0 if it has none
+func (c *Cache[K, V]) ttl(key K) time.Duration {
+	d, _ := c.r.GetTTL(key)
+	return d
+}
+
+// An entry is an entry read from ristretto, with its remaining TTL
+type entry[K Key, V any] struct {
+	key K
+	val V
+	ttl time.Duration
+}
+
+// entries reads every tracked key that ristretto still holds
+func (c *Cache[K, V]) entries() []entry[K, V] {
+	c.idx.Lock()
+	keys := make([]K, 0, len(c.keys))
+	for _, key := range c.keys {
+		keys = append(keys, key)
+	}
+
+	c.idx.Unlock()
+
+	entries := make([]entry[K, V], 0, len(keys))
+	for _, key := range keys {
+		if v, ok := c.r.Get(key); ok {
+			entries = append(entries, entry[K, V]{key, v, c.ttl(key)})
+		}
+	}
+
+	return entries
+}
+
+// scratch returns a cache.Cache holding a copy of the entries with their remaining TTLs,
+// used to encode them exactly as a cache.Cache does
+func (c *Cache[K, V]) scratch() *cache.Cache[K, V] {
+	s := cache.NewCache[K, V]()
+	for _, e := range c.entries() {
+		if e.ttl > 0 {
+			s.Set(e.key, e.val, cache.Expire(e.ttl))
+		} else {
+			s.Set(e.key, e.val)
+		}
+	}
+
+	return s
+}
+
+// load calls fn to fill a scratch cache.Cache, then stores its entries with their remaining TTLs,
+// first removing every entry if replace is true
+func (c *Cache[K, V]) load(fn func(s *cache.Cache[K, V]) error, replace bool) error {
+	s := cache.NewCache[K, V]()
+	defer s.Close()
+
+	if err := fn(s); err != nil {
+		return err
+	}
+
+	if replace {
+		c.Clear()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, val := range s.Items() {
+		ttl, _ := s.RemainingTTL(key)
+		c.store(key, val, ttl, nil, false)
+	}
+
+	return nil
+}
+
+// update runs fn on a scratch cache.Cache holding only the entry at key, if there is one,
+// then stores the value fn leaves there, keeping the entry's TTL and AfterFunc.
+// Value arithmetic such as Increment thus behaves exactly as on a cache.Cache
+func (c *Cache[K, V]) update(key K, fn func(s *cache.Cache[K, V])) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := cache.NewCache[K, V]()
+	defer s.Close()
+
+	if v, ok := c.r.Get(key); ok {
+		s.Set(key, v)
+	}
+
+	fn(s)
+	if v, ok := s.GetOK(key); ok {
+		c.store(key, v, c.ttl(key), nil, true)
+	}
+}
+
+// keyString returns the string form of key that DeletePrefix and similar calls match against
+func keyString[K Key](key K) string {
+	if s, ok := any(key).(string); ok {
+		return s
+	}
+
+	return fmt.Sprint(key)
+}
+
+// Stats returns the cache's usage counters. CurrentSize counts the tracked keys,
+// which may include expired entries ristretto has not removed yet
+func (c *Cache[K, V]) Stats() cache.CacheStats {
+	c.idx.Lock()
+	size := len(c.keys)
+	c.idx.Unlock()
+
+	return cache.CacheStats{
+		Hits:        c.hits.Load(),
+		Misses:      c.misses.Load(),
+		Sets:        c.sets.Load(),
+		Deletes:     c.deletes.Load(),
+		Evictions:   c.evictions.Load(),
+		Expired:     c.expired.Load(),
+		CurrentSize: int64(size),
+	}
+}
+
+// ResetStats zeroes the usage counters, except for CurrentSize
+func (c *Cache[K, V]) ResetStats() {
+	c.hits.Store(0)
+	c.misses.Store(0)
+	c.sets.Store(0)
+	c.deletes.Store(0)
+	c.evictions.Store(0)
+	c.expired.Store(0)
+}
+
+// Set will set the val into ristretto at the specified key, with the TTL set by an Expire option
+func (c *Cache[K, V]) Set(key K, val V, options ...cache.SetOption) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.set(key, val, options)
+}
+
+// SetE behaves like Set and always returns nil, since the Cache has no write-through
+func (c *Cache[K, V]) SetE(key K, val V, options ...cache.SetOption) error {
+	c.Set(key, val, options...)
+	return nil
+}
+
+// SetMany will set each entry of entries into ristretto
+func (c *Cache[K, V]) SetMany(entries map[K]V, options ...cache.SetOption) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, val := range entries {
+		c.set(key, val, options)
+	}
+}
+
+// MSet will set each entry of entries into ristretto, as SetMany does.
+// Returns cache.ErrClosed if the cache has been closed
+func (c *Cache[K, V]) MSet(entries map[K]V) error {
+	if err := c.check(context.Background()); err != nil {
+		return err
+	}
+
+	c.SetMany(entries)
+	return nil
+}
+
+// SetBatch will set each of entries into ristretto with its own options
+func (c *Cache[K, V]) SetBatch(entries []cache.BatchEntry[K, V]) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, e := range entries {
+		c.set(e.Key, e.Value, e.Options)
+	}
+}
+
+// SetCtx behaves like Set, but returns ctx.Err() without storing the entry if ctx is already done,
+// or cache.ErrClosed if the cache has been closed
+func (c *Cache[K, V]) SetCtx(ctx context.Context, key K, val V, options ...cache.SetOption) error {
+	if err := c.check(ctx); err != nil {
+		return err
+	}
+
+	c.Set(key, val, options...)
+	return nil
+}
+
+// check returns ctx.Err() if ctx is done, or cache.ErrClosed if the cache has been closed
+func (c *Cache[K, V]) check(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	select {
+	case <-c.done:
+		return cache.ErrClosed
+	default:
+		return nil
+	}
+}
+
+// SetWithTags will set the val into ristretto at the specified key and register it under tags
+func (c *Cache[K, V]) SetWithTags(key K, val V, tags []string, options ...cache.SetOption) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.set(key, val, options)
+
+	c.idx.Lock()
+	defer c.idx.Unlock()
+
+	if !c.tracked(key) {
+		return
+	}
+
+	for _, tag := range tags {
+		if c.tags[tag] == nil {
+			c.tags[tag] = map[K]struct{}{}
+		}
+
+		if _, ok := c.tags[tag][key]; !ok {
+			c.tags[tag][key] = struct{}{}
+			c.keyTags[key] = append(c.keyTags[key], tag)
+		}
+	}
+}
+
+// GetOrSet retrieves an entry at the specified key, storing the result of fn if none exists.
+// fn is called with the cache locked, so it must not call back into the cache
+func (c *Cache[K, V]) GetOrSet(key K, fn func() V, options ...cache.SetOption) V {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if v, ok := c.get(key); ok {
+		return v
+	}
+
+	v := fn()
+	c.set(key, v, options)
+	return v
+}
+
+// GetOrLoad retrieves the entry at the specified key, storing the value loader returns with the TTL it returns
+// if none exists. A non-positive TTL stores the entry without one. loader is called with the cache locked,
+// so it is called at most once per missing key but must not call back into the cache.
+// If loader returns an error, nothing is stored and the error is returned
+func (c *Cache[K, V]) GetOrLoad(key K, loader func() (V, time.Duration, error)) (V, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if v, ok := c.get(key); ok {
+		return v, nil
+	}
+
+	v, ttl, err := loader()
+	if err != nil {
+		return v, err
+	}
+
+	c.store(key, v, max(ttl, 0), nil, false)
+	return v, nil
+}
+
+// GetOrSetMany retrieves the entries at the specified keys, calling loader once with the keys that are missing
+// and storing the entries it returns. loader is called with the cache locked, so it must not call back into the cache.
+// Entries loader returns for keys it was not passed are ignored
+func (c *Cache[K, V]) GetOrSetMany(keys []K, loader func(keys []K) map[K]V, options ...cache.SetOption) map[K]V {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	found := make(map[K]V, len(keys))
+	var missing []K
+	for _, key := range keys {
+		if _, ok := found[key]; ok {
+			continue
+		}
+
+		if v, ok := c.get(key); ok {
+			found[key] = v
+		} else if !slices.Contains(missing, key) {
+			missing = append(missing, key)
+		}
+	}
+
+	if len(missing) == 0 {
+		return found
+	}
+
+	loaded := loader(missing)
+	for _, key := range missing {
+		if v, ok := loaded[key]; ok {
+			c.set(key, v, options)
+			found[key] = v
+		}
+	}
+
+	return found
+}
+
+// SetIfAbsent will set the val into ristretto at the specified key only if no entry exists there.
+// Returns true if the val was stored
+func (c *Cache[K, V]) SetIfAbsent(key K, val V, options ...cache.SetOption) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.r.Get(key); ok {
+		return false
+	}
+
+	c.set(key, val, options)
+	return true
+}
+
+// SetDefault will set val as the default value at the specified key. See SetIfAbsent
+func (c *Cache[K, V]) SetDefault(key K, val V, options ...cache.SetOption) bool {
+	return c.SetIfAbsent(key, val, options...)
+}
+
+// SetNX will set the val into ristretto at the specified key only if no entry exists there. See SetIfAbsent
+func (c *Cache[K, V]) SetNX(key K, val V, options ...cache.SetOption) bool {
+	return c.SetIfAbsent(key, val, options...)
+}
+
+// SetIfPresent will set the val into ristretto at the specified key only if an entry already exists there.
+// Returns true if the val was stored
+func (c *Cache[K, V]) SetIfPresent(key K, val V, options ...cache.SetOption) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.r.Get(key); !ok {
+		return false
+	}
+
+	c.set(key, val, options)
+	return true
+}
+
+// SetXX will set the val into ristretto at the specified key only if an entry already exists there. See SetIfPresent
+func (c *Cache[K, V]) SetXX(key K, val V, options ...cache.SetOption) bool {
+	return c.SetIfPresent(key, val, options...)
+}
+
+// GetAndSet will set the val into ristretto at the specified key and return the entry it replaced.
+// Returns bool specifying if an entry previously existed
+func (c *Cache[K, V]) GetAndSet(key K, val V, options ...cache.SetOption) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	old, ok := c.r.Get(key)
+	c.set(key, val, options)
+	return old, ok
+}
+
+// CompareAndSwap will set newVal into ristretto at the specified key only if the existing entry
+// is deeply equal to oldVal, as reported by reflect.DeepEqual. Returns true if the swap happened
+func (c *Cache[K, V]) CompareAndSwap(key K, oldVal, newVal V, options ...cache.SetOption) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if v, ok := c.r.Get(key); !ok || !reflect.DeepEqual(v, oldVal) {
+		return false
+	}
+
+	c.set(key, newVal, options)
+	return true
+}
+
+// Increment adds delta to the numeric entry at the specified key, keeping its TTL. See cache.Cache.Increment
+func (c *Cache[K, V]) Increment(key K, delta int64) (int64, error) {
+	var n int64
+	var err error
+	c.update(key, func(s *cache.Cache[K, V]) {
+		n, err = s.Increment(key, delta)
+	})
+
+	return n, err
+}
+
+// Decrement subtracts delta from the numeric entry at the specified key, keeping its TTL.
+// See cache.Cache.Decrement
+func (c *Cache[K, V]) Decrement(key K, delta int64) (int64, error) {
+	var n int64
+	var err error
+	c.update(key, func(s *cache.Cache[K, V]) {
+		n, err = s.Decrement(key, delta)
+	})
+
+	return n, err
+}
+
+// Append appends suffix to the string entry at the specified key, keeping its TTL. See cache.Cache.Append
+func (c *Cache[K, V]) Append(key K, suffix string) (string, error) {
+	var str string
+	var err error
+	c.update(key, func(s *cache.Cache[K, V]) {
+		str, err = s.Append(key, suffix)
+	})
+
+	return str, err
+}
+
+// Modify replaces the entry at the specified key with the result of fn, keeping its TTL.
+// fn is called with the cache locked, so it must not call back into the cache.
+// Returns false if no entry exists
+func (c *Cache[K, V]) Modify(key K, fn func(current V) V) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	v, ok := c.r.Get(key)
+	if !ok {
+		return false
+	}
+
+	c.store(key, fn(v), c.ttl(key), nil, true)
+	return true
+}
+
+// Touch resets the TTL of the entry at the specified key to d, dropping any AfterFunc.
+// A d of zero removes the TTL. Returns true if the entry exists
+func (c *Cache[K, V]) Touch(key K, d time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	v, ok := c.r.Get(key)
+	if !ok {
+		return false
+	}
+
+	c.store(key, v, d, nil, false)
+	return true
+}
+
+// ExpireAt sets the entry at the specified key to expire at the deadline t, dropping any AfterFunc.
+// If t has already passed, the entry is removed immediately. Returns true if the entry exists
+func (c *Cache[K, V]) ExpireAt(key K, t time.Time) bool {
+	return c.Expire(key, time.Until(t))
+}
+
+// Expire sets the entry at the specified key to expire d from now, dropping any AfterFunc.
+// A non-positive d removes the entry immediately. Returns true if the entry exists
+func (c *Cache[K, V]) Expire(key K, d time.Duration) bool {
+	if d > 0 {
+		return c.Touch(key, d)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, ok := c.remove(key)
+	return ok
+}
+
+// Persist removes the TTL from the entry at the specified key, dropping any AfterFunc.
+// Returns true if the entry existed and had a TTL
+func (c *Cache[K, V]) Persist(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	v, ok := c.r.Get(key)
+	if !ok {
+		return false
+	}
+
+	if ttl, ok := c.r.GetTTL(key); !ok || ttl <= 0 {
+		return false
+	}
+
+	c.store(key, v, 0, nil, false)
+	return true
+}
+
+// Rename moves the entry at oldKey to newKey with its TTL and tags, overwriting any entry already at newKey.
+// Any AfterFunc is dropped. Returns false if no entry exists at oldKey
+func (c *Cache[K, V]) Rename(oldKey, newKey K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	v, ok := c.r.Get(oldKey)
+	if !ok {
+		return false
+	}
+
+	if oldKey == newKey {
+		return true
+	}
+
+	ttl := c.ttl(oldKey)
+	c.idx.Lock()
+	tags := c.keyTags[oldKey]
+	c.idx.Unlock()
+
+	c.remove(oldKey)
+	c.remove(newKey)
+	c.store(newKey, v, ttl, nil, false)
+
+	c.idx.Lock()
+	defer c.idx.Unlock()
+
+	if c.tracked(newKey) {
+		for _, tag := range tags {
+			if c.tags[tag] == nil {
+				c.tags[tag] = map[K]struct{}{}
+			}
+
+			c.tags[tag][newKey] = struct{}{}
+		}
+
+		c.keyTags[newKey] = tags
+	}
+
+	return true
+}
+
+// RemainingTTL returns how long the entry at the specified key has left before it expires.
+// Returns false if no entry exists or the entry has no TTL
+func (c *Cache[K, V]) RemainingTTL(key K) (time.Duration, bool) {
+	d, ok := c.r.GetTTL(key)
+	return d, ok && d > 0
+}
+
+// TTL returns how long the entry at the specified key has left before it expires,
+// cache.TTLPersistent if it has no TTL, or cache.TTLMissing if no entry exists.
+// Returns cache.ErrClosed if the cache has been closed
+func (c *Cache[K, V]) TTL(key K) (time.Duration, error) {
+	if err := c.check(context.Background()); err != nil {
+		return 0, err
+	}
+
+	d, ok := c.r.GetTTL(key)
+	switch {
+	case !ok:
+		return cache.TTLMissing, nil
+	case d == 0:
+		return cache.TTLPersistent, nil
+	case d < 0:
+		return cache.TTLMissing, nil
+	}
+
+	return d, nil
+}
+
+// Clear removes all entries from the cache
+func (c *Cache[K, V]) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.idx.Lock()
+	var zero V
+	for _, key := range c.keys {
+		if v, ok := c.r.Get(key); ok {
+			c.deletes.Add(1)
+			c.publish(key, v, zero, cache.WatchDeleted)
+		}
+	}
+
+	for _, t := range c.timers {
+		t.t.Stop()
+	}
+
+	clear(c.keys)
+	clear(c.tags)
+	clear(c.keyTags)
+	clear(c.timers)
+	c.idx.Unlock()
+
+	c.r.Clear()
+}
+
+// ClearExpired removes every tracked key whose entry has expired but has not been removed by ristretto yet.
+// Returns the number of entries that were removed
+func (c *Cache[K, V]) ClearExpired() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.idx.Lock()
+	var expired []K
+	for _, key := range c.keys {
+		if _, ok := c.r.GetTTL(key); !ok {
+			expired = append(expired, key)
+		}
+	}
+
+	var zero V
+	for _, key := range expired {
+		c.untrack(key, true)
+		c.expired.Add(1)
+		c.publish(key, zero, zero, cache.WatchExpired)
+	}
+
+	c.idx.Unlock()
+
+	for _, key := range expired {
+		c.r.Del(key)
+	}
+
+	return len(expired)
+}
+
+// ClearEvery clears the cache on a loop at the specified interval.
+// The loop stops when the cache is closed or when the returned stop func is called,
+// which waits for the loop to exit and may be called more than once
+func (c *Cache[K, V]) ClearEvery(d time.Duration) (stop func()) {
+	ticker := time.NewTicker(d)
+	stopped := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.Clear()
+			case <-stopped:
+				return
+			case <-c.done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(stopped) })
+		<-exited
+	}
+}
+
+// Delete removes the entry at the specified key
+func (c *Cache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.remove(key)
+}
+
+// DeleteCtx behaves like Delete, but returns ctx.Err() without removing the entry if ctx is already done,
+// or cache.ErrClosed if the cache has been closed
+func (c *Cache[K, V]) DeleteCtx(ctx context.Context, key K) error {
+	if err := c.check(ctx); err != nil {
+		return err
+	}
+
+	c.Delete(key)
+	return nil
+}
+
+// DeleteMany removes the entries at the specified keys. Returns the number of entries that were removed
+func (c *Cache[K, V]) DeleteMany(keys []K) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var n int
+	for _, key := range keys {
+		if _, ok := c.remove(key); ok {
+			n++
+		}
+	}
+
+	return n
+}
+
+// deleteMatching removes every entry whose key matches. Returns the number of entries that were removed
+func (c *Cache[K, V]) deleteMatching(match func(key K) bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var n int
+	for _, e := range c.entries() {
+		if !match(e.key) {
+			continue
+		}
+
+		if _, ok := c.remove(e.key); ok {
+			n++
+		}
+	}
+
+	return n
+}
+
+// DeletePrefix removes every entry whose key starts with prefix. Returns the number of entries that were removed
+func (c *Cache[K, V]) DeletePrefix(prefix string) int {
+	return c.deleteMatching(func(key K) bool {
+		return strings.HasPrefix(keyString(key), prefix)
+	})
+}
+
+// DeleteSuffix removes every entry whose key ends with suffix. Returns the number of entries that were removed
+func (c *Cache[K, V]) DeleteSuffix(suffix string) int {
+	return c.deleteMatching(func(key K) bool {
+		return strings.HasSuffix(keyString(key), suffix)
+	})
+}
+
+// DeleteContains removes every entry whose key contains substr. Returns the number of entries that were removed
+func (c *Cache[K, V]) DeleteContains(substr string) int {
+	return c.deleteMatching(func(key K) bool {
+		return strings.Contains(keyString(key), substr)
+	})
+}
+
+// DeleteByTag removes every entry registered under tag by SetWithTags.
+// Returns the number of entries that were removed
+func (c *Cache[K, V]) DeleteByTag(tag string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.idx.Lock()
+	keys := make([]K, 0, len(c.tags[tag]))
+	for key := range c.tags[tag] {
+		keys = append(keys, key)
+	}
+
+	c.idx.Unlock()
+
+	var n int
+	for _, key := range keys {
+		if _, ok := c.remove(key); ok {
+			n++
+		}
+	}
+
+	return n
+}
+
+// GetAndDelete removes an entry from the cache at the specified key and returns it.
+// Returns bool specifying if the entry existed
+func (c *Cache[K, V]) GetAndDelete(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.remove(key)
+}
+
+// Evict removes an entry from the cache at the specified key and returns it, as GetAndDelete does.
+// ristretto only calls OnEvict for entries it evicts itself, so it is not called
+func (c *Cache[K, V]) Evict(key K) (V, bool) {
+	return c.GetAndDelete(key)
+}
+
+// CompareAndDelete removes an entry from the cache at the specified key only if
+// it is deeply equal to expected, as reported by reflect.DeepEqual. Returns true if the entry was removed
+func (c *Cache[K, V]) CompareAndDelete(key K, expected V) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if v, ok := c.r.Get(key); !ok || !reflect.DeepEqual(v, expected) {
+		return false
+	}
+
+	c.remove(key)
+	return true
+}
+
+// Get retrieves an entry at the specified key
+func (c *Cache[K, V]) Get(key K) V {
+	v, _ := c.get(key)
+	return v
+}
+
+// GetOK retrieves an entry at the specified key. Returns bool specifying if the entry exists
+func (c *Cache[K, V]) GetOK(key K) (V, bool) {
+	return c.get(key)
+}
+
+// Contains reports whether an entry exists at the specified key, counting the check as a hit or a miss
+func (c *Cache[K, V]) Contains(key K) bool {
+	_, ok := c.get(key)
+	return ok
+}
+
+// Probabilistic behaves like GetOK, since ristretto does not record how long values take to compute
+func (c *Cache[K, V]) Probabilistic(key K, beta float64) (V, bool) {
+	return c.get(key)
+}
+
+// GetCtx behaves like Get, but returns ctx.Err() if ctx is already done,
+// or cache.ErrClosed if the cache has been closed
+func (c *Cache[K, V]) GetCtx(ctx context.Context, key K) (V, error) {
+	var zero V
+	if err := c.check(ctx); err != nil {
+		return zero, err
+	}
+
+	return c.Get(key), nil
+}
+
+// GetMany retrieves the entries at the specified keys. Keys with no entry are absent from the result
+func (c *Cache[K, V]) GetMany(keys []K) map[K]V {
+	result := make(map[K]V, len(keys))
+	for _, key := range keys {
+		if v, ok := c.get(key); ok {
+			result[key] = v
+		}
+	}
+
+	return result
+}
+
+// MGet retrieves the entries at the specified keys, as GetMany does.
+// Returns cache.ErrClosed if the cache has been closed
+func (c *Cache[K, V]) MGet(keys []K) (map[K]V, error) {
+	if err := c.check(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return c.GetMany(keys), nil
+}
+
+// Watch returns a channel that receives a WatchEvent whenever the entry at the specified key is stored,
+// removed or expires, along with a function that stops watching and closes the channel.
+// Events that arrive while the channel is full are dropped. See cache.Cache.Watch
+func (c *Cache[K, V]) Watch(key K) (<-chan cache.WatchEvent[K, V], func()) {
+	return c.watch(&watcher[K, V]{key: key, ch: make(chan cache.WatchEvent[K, V], watchBuffer)})
+}
+
+// WatchPrefix behaves like Watch, but the channel receives a WatchEvent for every key that starts with prefix.
+// See cache.Cache.WatchPrefix
+func (c *Cache[K, V]) WatchPrefix(prefix string) (<-chan cache.WatchEvent[K, V], func()) {
+	return c.watch(&watcher[K, V]{prefix: prefix, prefixed: true, ch: make(chan cache.WatchEvent[K, V], watchBuffer)})
+}
+
+func (c *Cache[K, V]) watch(w *watcher[K, V]) (<-chan cache.WatchEvent[K, V], func()) {
+	c.idx.Lock()
+	c.watchers = append(c.watchers, w)
+	c.idx.Unlock()
+
+	var once sync.Once
+	return w.ch, func() {
+		once.Do(func() {
+			c.idx.Lock()
+			defer c.idx.Unlock()
+
+			if i := slices.Index(c.watchers, w); i >= 0 {
+				c.watchers = slices.Delete(c.watchers, i, i+1)
+				close(w.ch)
+			}
+		})
+	}
+}
+
+// WaitForKey retrieves the entry at the specified key, blocking until one is stored if none exists.
+// Returns ctx.Err() if ctx is done first, or cache.ErrClosed if the cache is closed first
+func (c *Cache[K, V]) WaitForKey(ctx context.Context, key K) (V, error) {
+	var zero V
+	for {
+		c.idx.Lock()
+		changed := c.changed
+		c.idx.Unlock()
+
+		if v, ok := c.get(key); ok {
+			return v, nil
+		}
+
+		select {
+		case <-changed:
+		case <-c.done:
+			return zero, cache.ErrClosed
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		}
+	}
+}
+
+// Dump retrieves all entries in the cache along with when they expire, as reported by ristretto's TTLs
+func (c *Cache[K, V]) Dump() map[K]cache.CacheEntry[V] {
+	now := time.Now()
+	entries := c.entries()
+	result := make(map[K]cache.CacheEntry[V], len(entries))
+	for _, e := range entries {
+		ce := cache.CacheEntry[V]{Value: e.val}
+		if e.ttl > 0 {
+			ce.ExpiresAt, ce.HasExpiry = now.Add(e.ttl), true
+		}
+
+		result[e.key] = ce
+	}
+
+	return result
+}
+
+// Items retrieves all entries in the cache
+func (c *Cache[K, V]) Items() map[K]V {
+	return c.FilterItems(func(K, V) bool { return true })
+}
+
+// Values retrieves all values in the cache in no particular order
+func (c *Cache[K, V]) Values() []V {
+	entries := c.entries()
+	vals := make([]V, len(entries))
+	for i, e := range entries {
+		vals[i] = e.val
+	}
+
+	return vals
+}
+
+// ForEach calls fn for each entry in the cache. Entries are read before fn is first called,
+// so fn may call back into the cache
+func (c *Cache[K, V]) ForEach(fn func(key K, val V)) {
+	for _, e := range c.entries() {
+		fn(e.key, e.val)
+	}
+}
+
+// FilterItems retrieves the entries for which predicate returns true
+func (c *Cache[K, V]) FilterItems(predicate func(K, V) bool) map[K]V {
+	result := map[K]V{}
+	for _, e := range c.entries() {
+		if predicate(e.key, e.val) {
+			result[e.key] = e.val
+		}
+	}
+
+	return result
+}
+
+// Count returns the number of entries in the cache for which predicate returns true
+func (c *Cache[K, V]) Count(predicate func(K, V) bool) int {
+	return len(c.FilterItems(predicate))
+}
+
+// IsEmpty reports if the cache holds no entries
+func (c *Cache[K, V]) IsEmpty() bool {
+	return c.Size() == 0
+}
+
+// Size returns the number of entries in the cache
+func (c *Cache[K, V]) Size() int {
+	return len(c.entries())
+}
+
+// Keys retrieves a sorted list of the keys in the cache
+func (c *Cache[K, V]) Keys() []K {
+	return c.FilterKeys(func(K) bool { return true })
+}
+
+// UnsortedKeys retrieves the keys in the cache in no particular order
+func (c *Cache[K, V]) UnsortedKeys() []K {
+	entries := c.entries()
+	keys := make([]K, len(entries))
+	for i, e := range entries {
+		keys[i] = e.key
+	}
+
+	return keys
+}
+
+// Scan returns up to count of the cache's keys in sorted order, along with the cursor to continue from.
+// See cache.Cache.Scan
+func (c *Cache[K, V]) Scan(cursor, count int) (int, []K) {
+	return c.scans.scan(cursor, count, c.UnsortedKeys)
+}
+
+const (
+	// scanIDBits is the number of low bits of a Scan cursor that identify its snapshot
+	scanIDBits = 16
+
+	// maxScans is the number of snapshots kept for scans in progress
+	maxScans = 16
+
+	// defaultScanCount is the number of keys Scan returns if count is not positive
+	defaultScanCount = 10
+)
+
+// A scanner holds the sorted key snapshots of the scans in progress, as in cache.Cache.Scan.
+// Its zero value is ready to use
+type scanner[K Key] struct {
+	mu    sync.Mutex
+	next  int
+	scans map[int][]K
+	order []int
+}
+
+// scan returns the next count keys of the scan identified by cursor and the cursor to continue it with,
+// taking a snapshot of keys if cursor is 0
+func (s *scanner[K]) scan(cursor, count int, keys func() []K) (int, []K) {
+	if count <= 0 {
+		count = defaultScanCount
+	}
+
+	var snapshot []K
+	if cursor == 0 {
+		snapshot = keys()
+		slices.Sort(snapshot)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, offset := cursor&(1<<scanIDBits-1), cursor>>scanIDBits
+	if cursor == 0 {
+		if s.scans == nil {
+			s.scans = map[int][]K{}
+		}
+
+		if len(s.order) == maxScans {
+			s.drop(s.order[0])
+		}
+
+		s.next = s.next%(1<<scanIDBits-1) + 1
+		s.drop(s.next)
+		s.scans[s.next] = snapshot
+		s.order = append(s.order, s.next)
+		id = s.next
+	}
+
+	snapshot, ok := s.scans[id]
+	if !ok || offset < 0 || offset >= len(snapshot) {
+		s.drop(id)
+		return 0, nil
+	}
+
+	end := min(offset+count, len(snapshot))
+	if end == len(snapshot) {
+		s.drop(id)
+		return 0, snapshot[offset:end]
+	}
+
+	return end<<scanIDBits | id, snapshot[offset:end]
+}
+
+// drop forgets the snapshot of the scan with the specified id, if any. It must only be called with mu held
+func (s *scanner[K]) drop(id int) {
+	if _, ok := s.scans[id]; !ok {
+		return
+	}
+
+	delete(s.scans, id)
+	s.order = slices.DeleteFunc(s.order, func(other int) bool { return other == id })
+}
+
+// KeysExpiringSoon retrieves a sorted list of the keys whose entries expire within the specified duration from now,
+// as reported by ristretto's TTLs
+func (c *Cache[K, V]) KeysExpiringSoon(within time.Duration) []K {
+	keys := []K{}
+	for _, e := range c.entries() {
+		if e.ttl > 0 && e.ttl <= within {
+			keys = append(keys, e.key)
+		}
+	}
+
+	slices.Sort(keys)
+	return keys
+}
+
+// ExpiredKeys returns an empty list, since ristretto never returns an entry that has passed its deadline,
+// even before its cleanup removes it
+func (c *Cache[K, V]) ExpiredKeys() []K {
+	return []K{}
+}
+
+// FilterKeys retrieves a sorted list of the keys in the cache for which predicate returns true
+func (c *Cache[K, V]) FilterKeys(predicate func(K) bool) []K {
+	var keys []K
+	for _, e := range c.entries() {
+		if predicate(e.key) {
+			keys = append(keys, e.key)
+		}
+	}
+
+	slices.Sort(keys)
+	return keys
+}
+
+// ExportJSON writes the entries to w as JSON, in the format cache.Cache.ExportJSON writes
+func (c *Cache[K, V]) ExportJSON(w io.Writer) error {
+	s := c.scratch()
+	defer s.Close()
+
+	return s.ExportJSON(w)
+}
+
+// ImportJSON reads entries written by ExportJSON from r and stores them. See cache.Cache.ImportJSON
+func (c *Cache[K, V]) ImportJSON(r io.Reader) error {
+	return c.load(func(s *cache.Cache[K, V]) error { return s.ImportJSON(r) }, false)
+}
+
+// ExportGob writes the entries to w using encoding/gob, in the format cache.Cache.ExportGob writes
+func (c *Cache[K, V]) ExportGob(w io.Writer) error {
+	s := c.scratch()
+	defer s.Close()
+
+	return s.ExportGob(w)
+}
+
+// ImportGob reads entries written by ExportGob from r and stores them. See cache.Cache.ImportGob
+func (c *Cache[K, V]) ImportGob(r io.Reader) error {
+	return c.load(func(s *cache.Cache[K, V]) error { return s.ImportGob(r) }, false)
+}
+
+// SaveToFile writes the entries to the file at path. See cache.Cache.SaveToFile
+func (c *Cache[K, V]) SaveToFile(path string) error {
+	s := c.scratch()
+	defer s.Close()
+
+	return s.SaveToFile(path)
+}
+
+// LoadFromFile imports the file at path written by SaveToFile. See cache.Cache.LoadFromFile
+func (c *Cache[K, V]) LoadFromFile(path string) error {
+	return c.load(func(s *cache.Cache[K, V]) error { return s.LoadFromFile(path) }, false)
+}
+
+// Snapshot returns the entries encoded as cache.Cache.Snapshot encodes them
+func (c *Cache[K, V]) Snapshot() ([]byte, error) {
+	s := c.scratch()
+	defer s.Close()
+
+	return s.Snapshot()
+}
+
+// Restore replaces the entries with those in data, returned by Snapshot. See cache.Cache.Restore
+func (c *Cache[K, V]) Restore(data []byte) error {
+	return c.load(func(s *cache.Cache[K, V]) error { return s.Restore(data) }, true)
+}
+
+// Close closes the ristretto cache, stops every AfterFunc timer and closes every Watch channel.
+// Returns cache.ErrClosed if the cache has already been closed
+func (c *Cache[K, V]) Close() error {
+	err := cache.ErrClosed
+	c.closeOnce.Do(func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		close(c.done)
+
+		c.idx.Lock()
+		for _, t := range c.timers {
+			t.t.Stop()
+		}
+
+		for _, w := range c.watchers {
+			close(w.ch)
+		}
+
+		c.watchers = nil
+		c.idx.Unlock()
+
+		c.r.Close()
+		err = nil
+	})
+
+	return err
+}
@@ -0,0 +1,222 @@
+package ristretto
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	cache "github.com/robotsrulz/go-cache"
+
+	"github.com/dgraph-io/ristretto/v2"
+)
+
+func newCache(t *testing.T, maxCost int64) *Cache[string, cache.T] {
+	c, err := New(&ristretto.Config[string, cache.T]{
+		NumCounters:        maxCost * 10,
+		MaxCost:            maxCost,
+		BufferItems:        64,
+		IgnoreInternalCost: true,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestCache(t *testing.T) {
+	c := newCache(t, 100)
+	c.Set("2", 2)
+	c.Set("1", 1)
+	c.Set("3", 3, cache.Expire(time.Hour))
+
+	if result, expected := c.Get("1"), cache.T(1); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if result, expected := c.Keys(), []string{"1", "2", "3"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if _, ok := c.RemainingTTL("1"); ok {
+		t.Errorf("Entry for key '1' should have no TTL")
+	}
+
+	if ttl, ok := c.RemainingTTL("3"); !ok || ttl <= 0 || ttl > time.Hour {
+		t.Errorf("Result was %v, expected a TTL of up to %v", ttl, time.Hour)
+	}
+
+	c.Delete("2")
+	expected := map[string]cache.T{"1": 1, "3": 3}
+	if result := c.Items(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if result, expected := c.Stats(), (cache.CacheStats{Hits: 1, Sets: 3, Deletes: 1, CurrentSize: 2}); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestCacheAfterFunc(t *testing.T) {
+	c := newCache(t, 100)
+	called := make(chan cache.T, 1)
+	c.Set("1", 1, cache.AfterFunc(time.Millisecond*10, func(val cache.T) { called <- val }))
+
+	select {
+	case val := <-called:
+		if expected := cache.T(1); val != expected {
+			t.Errorf("AfterFunc was called with %#v, expected %#v", val, expected)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("AfterFunc was not called")
+	}
+
+	if _, ok := c.GetOK("1"); ok {
+		t.Errorf("Entry for key '1' should have expired by now")
+	}
+
+	if result, expected := c.Size(), 0; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	c := newCache(t, 10)
+	for i := 0; i < 100; i++ {
+		c.Set(string(rune('a'+i%26))+string(rune('a'+i/26)), i)
+	}
+
+	keys := c.Keys()
+	if len(keys) > 10 {
+		t.Errorf("Cache holds %d keys, expected at most 10", len(keys))
+	}
+
+	if result, expected := c.Stats().CurrentSize, int64(len(keys)); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestCacheUpdate(t *testing.T) {
+	c := newCache(t, 100)
+	c.Set("1", 1, cache.Expire(time.Hour))
+
+	if n, err := c.Increment("1", 2); err != nil || n != 3 {
+		t.Errorf("Result was %#v, %v, expected %#v", n, err, 3)
+	}
+
+	if _, ok := c.RemainingTTL("1"); !ok {
+		t.Errorf("Increment should have kept the TTL")
+	}
+
+	if n, err := c.Increment("2", 1); err != nil || n != 1 {
+		t.Errorf("Result was %#v, %v, expected %#v", n, err, 1)
+	}
+
+	if _, err := c.Append("2", "x"); err == nil {
+		t.Errorf("Append to an integer should have failed")
+	}
+
+	if !c.CompareAndSwap("1", 3, 4) || c.CompareAndSwap("1", 3, 5) {
+		t.Errorf("CompareAndSwap did not swap only the expected value")
+	}
+
+	if result, expected := c.Get("1"), cache.T(4); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestCacheTags(t *testing.T) {
+	c := newCache(t, 100)
+	c.SetWithTags("1", 1, []string{"a"})
+	c.SetWithTags("2", 2, []string{"a", "b"})
+	c.Set("3", 3)
+
+	if result, expected := c.DeleteByTag("a"), 2; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if result, expected := c.Keys(), []string{"3"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestCacheWatch(t *testing.T) {
+	c := newCache(t, 100)
+	events, stop := c.Watch("1")
+	c.Set("1", 1)
+	c.Set("2", 2)
+	c.Delete("1")
+	stop()
+
+	var result []cache.WatchEvent[string, cache.T]
+	for e := range events {
+		result = append(result, e)
+	}
+
+	expected := []cache.WatchEvent[string, cache.T]{
+		{Key: "1", NewValue: 1, EventType: cache.WatchSet},
+		{Key: "1", OldValue: 1, EventType: cache.WatchDeleted},
+	}
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestCacheExportImport(t *testing.T) {
+	c := newCache(t, 100)
+	c.Set("1", 1)
+	c.Set("2", "two", cache.Expire(time.Hour))
+
+	var buf bytes.Buffer
+	if err := c.ExportJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	imported := newCache(t, 100)
+	if err := imported.ImportJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if result, expected := imported.Items(), c.Items(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if _, ok := imported.RemainingTTL("2"); !ok {
+		t.Errorf("Imported entry for key '2' should have a TTL")
+	}
+
+	data, err := c.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imported.Set("3", 3)
+	if err := imported.Restore(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if result, expected := imported.Keys(), []string{"1", "2"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestCacheScan(t *testing.T) {
+	c := newCache(t, 100)
+	c.Set("3", 3)
+	c.Set("1", 1)
+	c.Set("2", 2)
+
+	cursor, keys := c.Scan(0, 2)
+	if expected := []string{"1", "2"}; cursor == 0 || !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Result was %d, %#v, expected %#v", cursor, keys, expected)
+	}
+
+	cursor, keys = c.Scan(cursor, 2)
+	if expected := []string{"3"}; cursor != 0 || !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Result was %d, %#v, expected %#v", cursor, keys, expected)
+	}
+}
@@ -0,0 +1,112 @@
+package cache
+
+import "sync"
+
+const (
+	// scanIDBits is the number of low bits of a Scan cursor that identify its snapshot;
+	// the rest hold the offset into it
+	scanIDBits = 16
+
+	// maxScans is the number of snapshots kept for scans in progress.
+	// Starting another scan drops the snapshot of the scan that started longest ago
+	maxScans = 16
+
+	// defaultScanCount is the number of keys Scan returns if count is not positive
+	defaultScanCount = 10
+)
+
+// A scanner holds the sorted key snapshots of the scans in progress over a cache. Its zero value is ready to use
+type scanner[K comparable] struct {
+	mu    sync.Mutex
+	next  int
+	scans map[int][]K
+	order []int
+}
+
+// scan returns the next count keys of the scan identified by cursor and the cursor to pass to continue it,
+// which is 0 once the scan is complete. A zero cursor starts a new scan over a snapshot of the keys returned by keys.
+// A cursor whose snapshot has been dropped ends the scan
+func (s *scanner[K]) scan(cursor, count int, keys func() []K) (int, []K) {
+	if count <= 0 {
+		count = defaultScanCount
+	}
+
+	var snapshot []K
+	if cursor == 0 {
+		snapshot = keys()
+		sortKeys(snapshot)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, offset := cursor&(1<<scanIDBits-1), cursor>>scanIDBits
+	if cursor == 0 {
+		id = s.start(snapshot)
+	}
+
+	snapshot, ok := s.scans[id]
+	if !ok || offset < 0 || offset >= len(snapshot) {
+		s.drop(id)
+		return 0, nil
+	}
+
+	end := min(offset+count, len(snapshot))
+	if end == len(snapshot) {
+		s.drop(id)
+		return 0, snapshot[offset:end]
+	}
+
+	return end<<scanIDBits | id, snapshot[offset:end]
+}
+
+// start registers snapshot as a new scan, dropping the oldest if there are too many, and returns its id.
+// It must only be called with mu held
+func (s *scanner[K]) start(snapshot []K) int {
+	if s.scans == nil {
+		s.scans = map[int][]K{}
+	}
+
+	if len(s.order) == maxScans {
+		s.drop(s.order[0])
+	}
+
+	// ids start at 1, so that the cursor of a scan is never 0 until it completes
+	s.next = s.next%(1<<scanIDBits-1) + 1
+	s.drop(s.next)
+	s.scans[s.next] = snapshot
+	s.order = append(s.order, s.next)
+	return s.next
+}
+
+// drop forgets the snapshot of the scan with the specified id, if any. It must only be called with mu held
+func (s *scanner[K]) drop(id int) {
+	if _, ok := s.scans[id]; !ok {
+		return
+	}
+
+	delete(s.scans, id)
+	for i, other := range s.order {
+		if other == id {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// Scan returns up to count of the cache's keys in sorted order, along with the cursor to pass to the next call
+// to continue from where it left off, so a large cache can be iterated without calling Keys.
+// Start with a cursor of 0; the scan is complete once the returned cursor is 0 again.
+// Each scan works through a snapshot of the keys taken when it starts, so it visits every key present then
+// exactly once, does not visit keys stored since, and may still return keys removed since.
+// Only the most recent scans are remembered; a scan that has been superseded by too many newer ones
+// returns no keys and a cursor of 0. A non-positive count returns a default of 10 keys
+func (c *Cache[K, V]) Scan(cursor, count int) (int, []K) {
+	return c.scans.scan(cursor, count, c.UnsortedKeys)
+}
+
+// Scan returns up to count keys from every shard in sorted order, along with the cursor to continue from.
+// See Cache.Scan
+func (c *ShardedCache[K, V]) Scan(cursor, count int) (int, []K) {
+	return c.scans.scan(cursor, count, c.UnsortedKeys)
+}
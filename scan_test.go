@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestScan(t *testing.T) {
+	c := New()
+	for i := 0; i < 100; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+
+	cursor, first := c.Scan(0, 7)
+	other, _ := c.Scan(0, 7)
+	c.Set("new", 1)
+
+	visited := first
+	for cursor != 0 {
+		var keys []string
+		cursor, keys = c.Scan(cursor, 7)
+		if len(keys) > 7 {
+			t.Errorf("Scan returned %d keys, expected at most %d", len(keys), 7)
+		}
+
+		visited = append(visited, keys...)
+	}
+
+	expected := c.Keys()
+	expected = expected[:len(expected)-1]
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("Result was %#v, expected %#v", visited, expected)
+	}
+
+	if other == 0 {
+		t.Errorf("Scan started alongside another should still be in progress")
+	}
+
+	if cursor, keys := c.Scan(other, 1000); cursor != 0 || len(keys) != 93 {
+		t.Errorf("Result was %d, %d keys, expected %d, %d keys", cursor, len(keys), 0, 93)
+	}
+
+	if cursor, keys := c.Scan(other, 1000); cursor != 0 || keys != nil {
+		t.Errorf("Result was %d, %#v, expected a completed scan to return nothing", cursor, keys)
+	}
+}
+
+func TestScanDropsOldScans(t *testing.T) {
+	c := New()
+	c.Set("1", 1)
+	c.Set("2", 2)
+
+	first, _ := c.Scan(0, 1)
+	for i := 0; i < maxScans; i++ {
+		c.Scan(0, 1)
+	}
+
+	if cursor, keys := c.Scan(first, 1); cursor != 0 || keys != nil {
+		t.Errorf("Result was %d, %#v, expected the oldest scan to have been dropped", cursor, keys)
+	}
+}
+
+func TestShardedScan(t *testing.T) {
+	c := NewSharded(WithShards(4))
+	for i := 0; i < 50; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+
+	var visited []string
+	cursor := 0
+	for {
+		var keys []string
+		cursor, keys = c.Scan(cursor, 0)
+		visited = append(visited, keys...)
+		if cursor == 0 {
+			break
+		}
+	}
+
+	if result := c.Keys(); !reflect.DeepEqual(visited, result) {
+		t.Errorf("Result was %#v, expected %#v", visited, result)
+	}
+}
@@ -0,0 +1,710 @@
+package cache
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"reflect"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// A ShardedCache is a thread-safe store that splits its entries across several independent caches,
+// so that operations on different keys do not have to wait for each other.
+// It provides the same methods as Cache.
+type ShardedCache[K comparable, V any] struct {
+	shards    []*Cache[K, V]
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// scans holds the snapshots of the scans in progress started by Scan
+	scans scanner[K]
+}
+
+// A ShardedStringCache is a ShardedCache with string keys and values of any type, as created by NewSharded
+type ShardedStringCache = ShardedCache[string, T]
+
+// NewSharded returns an empty sharded cache configured by the specified options.
+// The number of shards is set by WithShards, and defaults to runtime.GOMAXPROCS(0).
+// A size limit set by WithMaxSize, WithMaxMemory or WithMaxWeight is divided evenly between the shards,
+// so each shard evicts independently of the others. Since an EvictionPolicy cannot be shared between shards,
+// NewSharded panics if WithEvictionPolicy is used.
+func NewSharded(options ...CacheOption) *ShardedStringCache {
+	return NewShardedCache[string, T](options...)
+}
+
+// NewShardedCache returns an empty sharded cache holding values of type V at keys of type K,
+// configured by the specified options. See NewSharded and NewCache
+func NewShardedCache[K comparable, V any](options ...CacheOption) *ShardedCache[K, V] {
+	opts := newCacheOptions(options)
+	if opts.policy != nil {
+		panic("cache: WithEvictionPolicy cannot be used with NewSharded")
+	}
+
+	n := opts.shards
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+
+	if opts.maxSize > 0 {
+		opts.maxSize = (opts.maxSize + n - 1) / n
+	}
+
+	if opts.maxWeight > 0 {
+		opts.maxWeight = (opts.maxWeight + n - 1) / n
+	}
+
+	if opts.maxMemory > 0 {
+		opts.maxMemory = (opts.maxMemory + int64(n) - 1) / int64(n)
+	}
+
+	if opts.initialCapacity > 0 {
+		opts.initialCapacity = (opts.initialCapacity + n - 1) / n
+	}
+
+	c := &ShardedCache[K, V]{
+		shards: make([]*Cache[K, V], n),
+		done:   make(chan struct{}),
+	}
+
+	for i := range c.shards {
+		c.shards[i] = newCache[K, V](opts)
+	}
+
+	c.shareCircuit(opts)
+	return c
+}
+
+// shareCircuit gives every shard the same circuit breaker, counting failures across the whole cache,
+// unless opts ask for no breaker or for one per key
+func (c *ShardedCache[K, V]) shareCircuit(opts cacheOptions) {
+	if opts.maxFailures > 0 && !opts.circuitPerKey {
+		cb := newCircuit[K](opts)
+		for _, shard := range c.shards {
+			shard.circuit = cb
+		}
+	}
+}
+
+// allShardsOp runs op on every shard with all of them locked at once, for writing or for reading if write is false,
+// so op sees a consistent view of the whole cache. Shards are always locked in index order, so that
+// concurrent calls cannot deadlock. It panics with ErrClosed if the cache has been closed
+func (c *ShardedCache[K, V]) allShardsOp(write bool, op func(shard *Cache[K, V], items backend[K, V])) {
+	shardsOp(c.shards, write, func() {
+		for _, shard := range c.shards {
+			op(shard, shard.items)
+		}
+	})
+}
+
+// shardsOp runs op with shards, which must be in index order, all locked at once for writing,
+// or for reading if write is false. See allShardsOp
+func shardsOp[K comparable, V any](shards []*Cache[K, V], write bool, op func()) {
+	for _, shard := range shards {
+		shard.lockNow(write)
+	}
+
+	var closed bool
+	for _, shard := range shards {
+		select {
+		case <-shard.done:
+			closed = true
+		default:
+		}
+	}
+
+	events := make([][]event[K, V], len(shards))
+	func() {
+		// unlock even if op panics, as Cache.runLocked does
+		defer func() {
+			for i := len(shards) - 1; i >= 0; i-- {
+				// only writers record events, and readers must not touch them while other readers hold the lock
+				if write {
+					events[i] = shards[i].events
+					shards[i].events = nil
+				}
+
+				shards[i].unlock(write)
+			}
+		}()
+
+		if !closed {
+			op()
+		}
+	}()
+
+	if closed {
+		panic(ErrClosed)
+	}
+
+	for i, shard := range shards {
+		for _, e := range events[i] {
+			shard.notify(e)
+		}
+	}
+}
+
+// shard returns the shard that holds the specified key
+func (c *ShardedCache[K, V]) shard(key K) *Cache[K, V] {
+	return c.shards[c.shardIndex(key)]
+}
+
+// shardIndex returns the index of the shard that holds the specified key, chosen by the fnv32 hash of the key
+func (c *ShardedCache[K, V]) shardIndex(key K) int {
+	h := fnv.New32a()
+	if s, ok := any(key).(string); ok {
+		h.Write([]byte(s))
+	} else {
+		h.Write(appendKey(nil, reflect.ValueOf(key)))
+	}
+
+	return int(h.Sum32() % uint32(len(c.shards)))
+}
+
+// appendKey appends the bytes of a comparable key to b, such that keys that are equal append the same bytes
+func appendKey(b []byte, v reflect.Value) []byte {
+	if !v.IsValid() {
+		return b
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return append(b, 1)
+		}
+
+		return append(b, 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return binary.LittleEndian.AppendUint64(b, uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return binary.LittleEndian.AppendUint64(b, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return appendFloat(b, v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		return appendFloat(appendFloat(b, real(v.Complex())), imag(v.Complex()))
+	case reflect.String:
+		return append(b, v.String()...)
+	case reflect.Pointer, reflect.Chan, reflect.UnsafePointer:
+		return binary.LittleEndian.AppendUint64(b, uint64(v.Pointer()))
+	case reflect.Interface:
+		return appendKey(b, v.Elem())
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			b = appendKey(b, v.Index(i))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			b = appendKey(b, v.Field(i))
+		}
+	}
+
+	return b
+}
+
+// appendFloat appends the bits of f to b, with -0 appended as 0 since the two are equal
+func appendFloat(b []byte, f float64) []byte {
+	if f == 0 {
+		f = 0
+	}
+
+	return binary.LittleEndian.AppendUint64(b, math.Float64bits(f))
+}
+
+// groupKeys splits keys by the shard that holds them
+func (c *ShardedCache[K, V]) groupKeys(keys []K) map[*Cache[K, V]][]K {
+	groups := map[*Cache[K, V]][]K{}
+	for _, key := range keys {
+		shard := c.shard(key)
+		groups[shard] = append(groups[shard], key)
+	}
+
+	return groups
+}
+
+// Close closes every shard. See Cache.Close
+func (c *ShardedCache[K, V]) Close() error {
+	err := ErrClosed
+	c.closeOnce.Do(func() {
+		close(c.done)
+		for _, shard := range c.shards {
+			shard.Close()
+		}
+
+		err = nil
+	})
+
+	return err
+}
+
+// Set will set the val into the cache at the specified key. See Cache.Set
+func (c *ShardedCache[K, V]) Set(key K, val V, options ...SetOption) {
+	c.shard(key).Set(key, val, options...)
+}
+
+// SetE will set the val into the cache at the specified key, returning any write-through error. See Cache.SetE
+func (c *ShardedCache[K, V]) SetE(key K, val V, options ...SetOption) error {
+	return c.shard(key).SetE(key, val, options...)
+}
+
+// SetMany will set each entry of entries into the cache, using a single pass per shard. See Cache.SetMany
+func (c *ShardedCache[K, V]) SetMany(entries map[K]V, options ...SetOption) {
+	for shard, group := range c.groupEntries(entries) {
+		shard.SetMany(group, options...)
+	}
+}
+
+// MSet will set each entry of entries into the cache, using a single pass per shard.
+// Each shard's entries are stored at once, but another operation may see the entries of only some shards.
+// See Cache.MSet
+func (c *ShardedCache[K, V]) MSet(entries map[K]V) error {
+	for shard, group := range c.groupEntries(entries) {
+		if err := shard.MSet(group); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// groupEntries splits entries by the shard that holds their keys
+func (c *ShardedCache[K, V]) groupEntries(entries map[K]V) map[*Cache[K, V]]map[K]V {
+	groups := map[*Cache[K, V]]map[K]V{}
+	for key, val := range entries {
+		shard := c.shard(key)
+		if groups[shard] == nil {
+			groups[shard] = map[K]V{}
+		}
+
+		groups[shard][key] = val
+	}
+
+	return groups
+}
+
+// SetBatch will set each of entries into the cache with its own options, using a single pass per shard.
+// See Cache.SetBatch
+func (c *ShardedCache[K, V]) SetBatch(entries []BatchEntry[K, V]) {
+	groups := map[*Cache[K, V]][]BatchEntry[K, V]{}
+	for _, e := range entries {
+		shard := c.shard(e.Key)
+		groups[shard] = append(groups[shard], e)
+	}
+
+	for shard, group := range groups {
+		shard.SetBatch(group)
+	}
+}
+
+// GetOrSet retrieves an entry at the specified key, storing the result of fn if none exists. See Cache.GetOrSet
+func (c *ShardedCache[K, V]) GetOrSet(key K, fn func() V, options ...SetOption) V {
+	return c.shard(key).GetOrSet(key, fn, options...)
+}
+
+// GetOrLoad retrieves an entry at the specified key, loading it with loader if none exists. See Cache.GetOrLoad
+func (c *ShardedCache[K, V]) GetOrLoad(key K, loader func() (V, time.Duration, error)) (V, error) {
+	return c.shard(key).GetOrLoad(key, loader)
+}
+
+// GetOrSetMany retrieves the entries at the specified keys, calling loader once with the keys missing from every shard
+// and storing the entries it returns in a single pass per shard. See Cache.GetOrSetMany
+func (c *ShardedCache[K, V]) GetOrSetMany(keys []K, loader func(keys []K) map[K]V, options ...SetOption) map[K]V {
+	pending := map[*Cache[K, V]]pendingMany[K, V]{}
+	var missed []K
+	for shard, group := range c.groupKeys(keys) {
+		p := shard.claimMany(group)
+		pending[shard] = p
+		missed = append(missed, p.missed...)
+	}
+
+	if len(missed) > 0 {
+		var loaded map[K]V
+		func() {
+			defer func() {
+				for shard, p := range pending {
+					shard.fillMany(p, loaded, options)
+				}
+			}()
+
+			loaded = loader(missed)
+		}()
+	}
+
+	found := make(map[K]V, len(keys))
+	for _, p := range pending {
+		for key, val := range p.wait() {
+			found[key] = val
+		}
+	}
+
+	return found
+}
+
+// SetIfAbsent will set the val into the cache at the specified key only if no entry exists there.
+// See Cache.SetIfAbsent
+func (c *ShardedCache[K, V]) SetIfAbsent(key K, val V, options ...SetOption) bool {
+	return c.shard(key).SetIfAbsent(key, val, options...)
+}
+
+// SetDefault will set val as the default value at the specified key. See Cache.SetDefault
+func (c *ShardedCache[K, V]) SetDefault(key K, val V, options ...SetOption) bool {
+	return c.shard(key).SetDefault(key, val, options...)
+}
+
+// SetNX will set the val into the cache at the specified key only if no entry exists there. See Cache.SetNX
+func (c *ShardedCache[K, V]) SetNX(key K, val V, options ...SetOption) bool {
+	return c.shard(key).SetNX(key, val, options...)
+}
+
+// SetIfPresent will set the val into the cache at the specified key only if an entry already exists there.
+// See Cache.SetIfPresent
+func (c *ShardedCache[K, V]) SetIfPresent(key K, val V, options ...SetOption) bool {
+	return c.shard(key).SetIfPresent(key, val, options...)
+}
+
+// SetXX will set the val into the cache at the specified key only if an entry already exists there. See Cache.SetXX
+func (c *ShardedCache[K, V]) SetXX(key K, val V, options ...SetOption) bool {
+	return c.shard(key).SetXX(key, val, options...)
+}
+
+// GetAndSet will set the val into the cache at the specified key and return the entry it replaced.
+// See Cache.GetAndSet
+func (c *ShardedCache[K, V]) GetAndSet(key K, val V, options ...SetOption) (V, bool) {
+	return c.shard(key).GetAndSet(key, val, options...)
+}
+
+// CompareAndSwap will set newVal into the cache at the specified key only if the existing entry
+// is deeply equal to oldVal. See Cache.CompareAndSwap
+func (c *ShardedCache[K, V]) CompareAndSwap(key K, oldVal, newVal V, options ...SetOption) bool {
+	return c.shard(key).CompareAndSwap(key, oldVal, newVal, options...)
+}
+
+// Touch resets the expiry of the entry at the specified key to d from now. See Cache.Touch
+func (c *ShardedCache[K, V]) Touch(key K, d time.Duration) bool {
+	return c.shard(key).Touch(key, d)
+}
+
+// ExpireAt sets the entry at the specified key to expire at the deadline t. See Cache.ExpireAt
+func (c *ShardedCache[K, V]) ExpireAt(key K, t time.Time) bool {
+	return c.shard(key).ExpireAt(key, t)
+}
+
+// Expire sets the entry at the specified key to expire d from now. See Cache.Expire
+func (c *ShardedCache[K, V]) Expire(key K, d time.Duration) bool {
+	return c.shard(key).Expire(key, d)
+}
+
+// Persist removes the expiry from the entry at the specified key. See Cache.Persist
+func (c *ShardedCache[K, V]) Persist(key K) bool {
+	return c.shard(key).Persist(key)
+}
+
+// Rename moves the entry at oldKey to newKey. See Cache.Rename.
+// When the keys belong to different shards, both are locked while the entry moves, so it keeps
+// its remaining expiry, AfterFunc callback and tags just as within one shard
+func (c *ShardedCache[K, V]) Rename(oldKey, newKey K) bool {
+	i, j := c.shardIndex(oldKey), c.shardIndex(newKey)
+	from, to := c.shards[i], c.shards[j]
+	if i == j {
+		return from.Rename(oldKey, newKey)
+	}
+
+	// lock the shards in index order, as allShardsOp does
+	shards := []*Cache[K, V]{from, to}
+	if j < i {
+		shards[0], shards[1] = to, from
+	}
+
+	var ok bool
+	shardsOp(shards, true, func() {
+		var m moved[K, V]
+		if m, ok = from.takeMoved(from.items, oldKey); ok {
+			to.putMoved(to.items, newKey, m)
+		}
+	})
+
+	return ok
+}
+
+// Clear removes all entries from the cache
+func (c *ShardedCache[K, V]) Clear() {
+	c.allShardsOp(true, func(shard *Cache[K, V], items backend[K, V]) {
+		shard.clearItems(items)
+	})
+}
+
+// ClearExpired removes every expired entry from each shard in turn. See Cache.ClearExpired
+func (c *ShardedCache[K, V]) ClearExpired() int {
+	var n int
+	for _, shard := range c.shards {
+		n += shard.ClearExpired()
+	}
+
+	return n
+}
+
+// ClearEvery clears the cache on a loop at the specified interval.
+// The loop stops when the cache is closed or when the returned stop func is called. See Cache.ClearEvery
+func (c *ShardedCache[K, V]) ClearEvery(d time.Duration) (stop func()) {
+	return clearLoop(d, c.done, func() bool {
+		for _, shard := range c.shards {
+			if !shard.tryItemOp(shard.clearItems) {
+				return false
+			}
+		}
+
+		return true
+	})
+}
+
+// Delete removes an entry from the cache at the specified key. See Cache.Delete
+func (c *ShardedCache[K, V]) Delete(key K) {
+	c.shard(key).Delete(key)
+}
+
+// DeleteMany removes the entries from the cache at the specified keys, using a single pass per shard.
+// Returns the number of entries that were removed
+func (c *ShardedCache[K, V]) DeleteMany(keys []K) int {
+	var removed int
+	for shard, group := range c.groupKeys(keys) {
+		removed += shard.DeleteMany(group)
+	}
+
+	return removed
+}
+
+// DeletePrefix removes every entry whose key starts with prefix, using a single pass per shard.
+// Returns the number of entries that were removed
+func (c *ShardedCache[K, V]) DeletePrefix(prefix string) int {
+	count := 0
+	for _, shard := range c.shards {
+		count += shard.DeletePrefix(prefix)
+	}
+
+	return count
+}
+
+// DeleteSuffix removes every entry whose key ends with suffix, using a single pass per shard.
+// Returns the number of entries that were removed
+func (c *ShardedCache[K, V]) DeleteSuffix(suffix string) int {
+	count := 0
+	for _, shard := range c.shards {
+		count += shard.DeleteSuffix(suffix)
+	}
+
+	return count
+}
+
+// DeleteContains removes every entry whose key contains substr, using a single pass per shard.
+// Returns the number of entries that were removed
+func (c *ShardedCache[K, V]) DeleteContains(substr string) int {
+	count := 0
+	for _, shard := range c.shards {
+		count += shard.DeleteContains(substr)
+	}
+
+	return count
+}
+
+// GetAndDelete removes an entry from the cache at the specified key and returns it. See Cache.GetAndDelete
+func (c *ShardedCache[K, V]) GetAndDelete(key K) (V, bool) {
+	return c.shard(key).GetAndDelete(key)
+}
+
+// Evict removes an entry from the cache at the specified key and returns it, calling OnEvict. See Cache.Evict
+func (c *ShardedCache[K, V]) Evict(key K) (V, bool) {
+	return c.shard(key).Evict(key)
+}
+
+// CompareAndDelete removes an entry from the cache at the specified key only if it is deeply equal
+// to expected. See Cache.CompareAndDelete
+func (c *ShardedCache[K, V]) CompareAndDelete(key K, expected V) bool {
+	return c.shard(key).CompareAndDelete(key, expected)
+}
+
+// Get retrieves an entry at the specified key
+func (c *ShardedCache[K, V]) Get(key K) V {
+	return c.shard(key).Get(key)
+}
+
+// GetOK retrieves an entry at the specified key.
+// Returns bool specifying if the entry exists
+func (c *ShardedCache[K, V]) GetOK(key K) (V, bool) {
+	return c.shard(key).GetOK(key)
+}
+
+// Contains reports whether an entry exists at the specified key. See Cache.Contains
+func (c *ShardedCache[K, V]) Contains(key K) bool {
+	return c.shard(key).Contains(key)
+}
+
+// GetMany retrieves the entries at the specified keys, using a single pass per shard. See Cache.GetMany
+func (c *ShardedCache[K, V]) GetMany(keys []K) map[K]V {
+	found := make(map[K]V, len(keys))
+	for shard, group := range c.groupKeys(keys) {
+		for key, val := range shard.GetMany(group) {
+			found[key] = val
+		}
+	}
+
+	return found
+}
+
+// MGet retrieves the entries at the specified keys, using a single pass per shard. See Cache.MGet
+func (c *ShardedCache[K, V]) MGet(keys []K) (map[K]V, error) {
+	found := make(map[K]V, len(keys))
+	for shard, group := range c.groupKeys(keys) {
+		vals, err := shard.MGet(group)
+		if err != nil {
+			return nil, err
+		}
+
+		for key, val := range vals {
+			found[key] = val
+		}
+	}
+
+	return found, nil
+}
+
+// RemainingTTL returns how long the entry at the specified key has left before it expires.
+// See Cache.RemainingTTL
+func (c *ShardedCache[K, V]) RemainingTTL(key K) (time.Duration, bool) {
+	return c.shard(key).RemainingTTL(key)
+}
+
+// TTL returns how long the entry at the specified key has left before it expires. See Cache.TTL
+func (c *ShardedCache[K, V]) TTL(key K) (time.Duration, error) {
+	return c.shard(key).TTL(key)
+}
+
+// Items retrieves all entries in the cache
+func (c *ShardedCache[K, V]) Items() map[K]V {
+	cp := map[K]V{}
+	c.allShardsOp(false, func(shard *Cache[K, V], items backend[K, V]) {
+		for key, val := range shard.all(items) {
+			cp[key] = val
+		}
+	})
+
+	return cp
+}
+
+// Values retrieves all values in the cache in no particular order
+func (c *ShardedCache[K, V]) Values() []V {
+	vals := []V{}
+	for _, shard := range c.shards {
+		vals = append(vals, shard.Values()...)
+	}
+
+	return vals
+}
+
+// ForEach calls fn for each entry in the cache, one shard at a time. See Cache.ForEach
+func (c *ShardedCache[K, V]) ForEach(fn func(key K, val V)) {
+	for _, shard := range c.shards {
+		shard.ForEach(fn)
+	}
+}
+
+// FilterItems retrieves the entries in the cache for which predicate returns true. See Cache.FilterItems
+func (c *ShardedCache[K, V]) FilterItems(predicate func(K, V) bool) map[K]V {
+	items := map[K]V{}
+	for _, shard := range c.shards {
+		for key, val := range shard.FilterItems(predicate) {
+			items[key] = val
+		}
+	}
+
+	return items
+}
+
+// Count returns the number of entries in the cache for which predicate returns true. See Cache.Count
+func (c *ShardedCache[K, V]) Count(predicate func(K, V) bool) int {
+	var count int
+	for _, shard := range c.shards {
+		count += shard.Count(predicate)
+	}
+
+	return count
+}
+
+// IsEmpty returns wherever the cache is empty
+func (c *ShardedCache[K, V]) IsEmpty() bool {
+	for _, shard := range c.shards {
+		if !shard.IsEmpty() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Size returns wherever the cache size
+func (c *ShardedCache[K, V]) Size() int {
+	var size int
+	for _, shard := range c.shards {
+		size += shard.Size()
+	}
+
+	return size
+}
+
+// Keys retrieves a sorted list of all keys in the cache
+func (c *ShardedCache[K, V]) Keys() []K {
+	keys := c.UnsortedKeys()
+	sortKeys(keys)
+	return keys
+}
+
+// KeysExpiringSoon retrieves a sorted list of the keys in every shard whose entries expire within the specified
+// duration from now. See Cache.KeysExpiringSoon
+func (c *ShardedCache[K, V]) KeysExpiringSoon(within time.Duration) []K {
+	keys := []K{}
+	for _, shard := range c.shards {
+		shard.expiryOp(func(expiries map[K]*expiry[K]) {
+			keys = append(keys, expiringKeys(expiries, within)...)
+		})
+	}
+
+	sortKeys(keys)
+	return keys
+}
+
+// ExpiredKeys retrieves a sorted list of the keys in every shard whose entries have passed their deadline
+// but not yet been removed. See Cache.ExpiredKeys
+func (c *ShardedCache[K, V]) ExpiredKeys() []K {
+	keys := []K{}
+	for _, shard := range c.shards {
+		shard.expiryOp(func(expiries map[K]*expiry[K]) {
+			keys = append(keys, expiredKeys(expiries)...)
+		})
+	}
+
+	sortKeys(keys)
+	return keys
+}
+
+// UnsortedKeys retrieves a list of all keys in the cache in no particular order
+func (c *ShardedCache[K, V]) UnsortedKeys() []K {
+	keys := []K{}
+	c.allShardsOp(false, func(shard *Cache[K, V], items backend[K, V]) {
+		for key := range shard.all(items) {
+			keys = append(keys, key)
+		}
+	})
+
+	return keys
+}
+
+// FilterKeys retrieves a sorted list of the keys in the cache for which predicate returns true.
+// See Cache.FilterKeys
+func (c *ShardedCache[K, V]) FilterKeys(predicate func(K) bool) []K {
+	keys := []K{}
+	for _, shard := range c.shards {
+		keys = append(keys, shard.FilterKeys(predicate)...)
+	}
+
+	sortKeys(keys)
+	return keys
+}
@@ -0,0 +1,263 @@
+package cache
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestShardedSetGet(t *testing.T) {
+	c := NewSharded(WithShards(4))
+	for i := 0; i < 100; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+
+	for i := 0; i < 100; i++ {
+		key := strconv.Itoa(i)
+		if result, expected := c.Get(key), i; !reflect.DeepEqual(result, expected) {
+			t.Errorf("Entry for key '%s' was %#v, expected %#v", key, result, expected)
+		}
+	}
+
+	if size := c.Size(); size != 100 {
+		t.Errorf("Cache size was %d, expected 100", size)
+	}
+
+	var used int
+	for _, shard := range c.shards {
+		if !shard.IsEmpty() {
+			used++
+		}
+	}
+
+	if used != 4 {
+		t.Errorf("Entries were spread over %d shards, expected 4", used)
+	}
+}
+
+func TestNewShardedCache(t *testing.T) {
+	c := NewShardedCache[int, string](WithShards(4))
+	for i := 0; i < 20; i++ {
+		c.Set(i, strconv.Itoa(i))
+	}
+
+	if result, expected := c.Get(7), "7"; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if result := c.Keys(); len(result) != 20 || result[0] != 0 || result[19] != 19 {
+		t.Errorf("Result was %#v, expected the keys 0 to 19 in order", result)
+	}
+}
+
+func TestShardedKeyTypes(t *testing.T) {
+	type key struct {
+		name string
+		n    float64
+		id   any
+	}
+
+	c := NewShardedCache[key, int](WithShards(16))
+	for i := 0; i < 20; i++ {
+		c.Set(key{strconv.Itoa(i), 0, i}, i)
+	}
+
+	if result, expected := c.Get(key{"7", math.Copysign(0, -1), 7}), 7; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if result, expected := c.Size(), 20; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestShardedBulk(t *testing.T) {
+	c := NewSharded(WithShards(4))
+	c.SetMany(map[string]T{"0": 0, "1": 1, "2": 2, "3": 3, "4": 4})
+
+	expected := map[string]T{"1": 1, "3": 3}
+	if result := c.GetMany([]string{"1", "3", "5"}); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if removed := c.DeleteMany([]string{"1", "3", "5"}); removed != 2 {
+		t.Errorf("DeleteMany removed %d entries, expected 2", removed)
+	}
+
+	if result, expected := c.Keys(), []string{"0", "2", "4"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	c.Clear()
+	if !c.IsEmpty() {
+		t.Errorf("Cache should have been empty, had keys: %v", c.Keys())
+	}
+}
+
+func TestShardedRename(t *testing.T) {
+	c := NewSharded(WithShards(16))
+
+	// find two keys held by different shards
+	oldKey, newKey := "0", "1"
+	for i := 1; c.shard(oldKey) == c.shard(newKey); i++ {
+		newKey = strconv.Itoa(i)
+	}
+
+	c.Set(oldKey, 1, Expire(time.Hour))
+	if !c.Rename(oldKey, newKey) {
+		t.Errorf("Rename should have found key '%s'", oldKey)
+	}
+
+	if _, exists := c.GetOK(oldKey); exists {
+		t.Errorf("Entry for key '%s' should not exist", oldKey)
+	}
+
+	if result, expected := c.Get(newKey), 1; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Entry for key '%s' was %#v, expected %#v", newKey, result, expected)
+	}
+
+	if _, ok := c.RemainingTTL(newKey); !ok {
+		t.Errorf("Entry for key '%s' should have kept its TTL", newKey)
+	}
+}
+
+func TestShardedRenameKeepsEntry(t *testing.T) {
+	c := NewSharded(WithShards(16))
+
+	oldKey, newKey := "0", "1"
+	for i := 1; c.shard(oldKey) == c.shard(newKey); i++ {
+		newKey = strconv.Itoa(i)
+	}
+
+	expired := make(chan T, 1)
+	c.SetWithTags(oldKey, 1, []string{"tag"}, AfterFunc(time.Millisecond*50, func(val T) { expired <- val }))
+	c.Rename(oldKey, newKey)
+
+	if result, expected := c.DeleteByTag("tag"), 1; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	c.SetWithTags(oldKey, 2, []string{"tag"}, AfterFunc(time.Millisecond*50, func(val T) { expired <- val }))
+	c.Rename(oldKey, newKey)
+
+	select {
+	case val := <-expired:
+		if val != 2 {
+			t.Errorf("Result was %#v, expected %#v", val, 2)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("AfterFunc was not called for the renamed entry")
+	}
+}
+
+func TestShardedWithMaxSize(t *testing.T) {
+	c := NewSharded(WithShards(4), WithMaxSize(8))
+	for i := 0; i < 100; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+
+	for i, shard := range c.shards {
+		if size := shard.Size(); size > 2 {
+			t.Errorf("Shard %d had %d entries, expected at most 2", i, size)
+		}
+	}
+}
+
+func TestShardedClose(t *testing.T) {
+	c := NewSharded(WithShards(4))
+	c.ClearEvery(time.Millisecond)
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	if err := c.Close(); err != ErrClosed {
+		t.Errorf("Second Close returned %v, expected %v", err, ErrClosed)
+	}
+}
+
+func TestShardedAllShardsConcurrent(t *testing.T) {
+	c := NewSharded(WithShards(4))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				c.SetMany(map[string]T{strconv.Itoa(i): i, strconv.Itoa(i + 10): i})
+				c.Items()
+				c.Keys()
+				if j%10 == 0 {
+					c.Clear()
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	c.Clear()
+	if !c.IsEmpty() {
+		t.Errorf("Cache should have been empty, had keys: %v", c.Keys())
+	}
+}
+
+func benchmarkShardedConcurrent(shards int, b *testing.B) {
+	c := NewSharded(WithShards(shards))
+	defer c.Close()
+
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+		c.Set(keys[i], i)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			key := keys[i%len(keys)]
+			if i%4 == 0 {
+				c.Set(key, i)
+			} else {
+				c.Get(key)
+			}
+		}
+	})
+}
+
+func BenchmarkShardedConcurrent1(b *testing.B)  { benchmarkShardedConcurrent(1, b) }
+func BenchmarkShardedConcurrent2(b *testing.B)  { benchmarkShardedConcurrent(2, b) }
+func BenchmarkShardedConcurrent4(b *testing.B)  { benchmarkShardedConcurrent(4, b) }
+func BenchmarkShardedConcurrent8(b *testing.B)  { benchmarkShardedConcurrent(8, b) }
+func BenchmarkShardedConcurrent16(b *testing.B) { benchmarkShardedConcurrent(16, b) }
+
+func benchmarkShardedRead(shards int, b *testing.B) {
+	c := NewSharded(WithShards(shards))
+	defer c.Close()
+
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+		c.Set(keys[i], i)
+	}
+
+	// give each goroutine its own range of keys, so no two goroutines read the same key
+	var next atomic.Int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		start := int(next.Add(64)) % len(keys)
+		for i := 0; pb.Next(); i++ {
+			c.Get(keys[start+i%64])
+		}
+	})
+}
+
+func BenchmarkShardedRead1(b *testing.B)  { benchmarkShardedRead(1, b) }
+func BenchmarkShardedRead2(b *testing.B)  { benchmarkShardedRead(2, b) }
+func BenchmarkShardedRead4(b *testing.B)  { benchmarkShardedRead(4, b) }
+func BenchmarkShardedRead8(b *testing.B)  { benchmarkShardedRead(8, b) }
+func BenchmarkShardedRead16(b *testing.B) { benchmarkShardedRead(16, b) }
@@ -0,0 +1,79 @@
+package cache
+
+import "bytes"
+
+// Snapshot returns every entry in the cache that has not expired, along with its deadline,
+// encoded with encoding/gob as by ExportGob. Values must be of registered types; see Register
+func (c *Cache[K, V]) Snapshot() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := c.ExportGob(&buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Restore replaces every entry in the cache with the entries in data, a snapshot returned by Snapshot.
+// The entries and their expiries are swapped in a single pass, so other operations see the cache either before or after.
+// Entries whose deadline has passed are skipped, and those without one get the cache's default expiry.
+// Nothing is changed if data cannot be decoded
+func (c *Cache[K, V]) Restore(data []byte) error {
+	entries, err := importGob[K, V](bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	live := liveEntries(entries)
+	c.itemOp(func(items backend[K, V]) {
+		c.replaceItems(items, live)
+	})
+
+	return nil
+}
+
+// replaceItems removes every entry and expiry from items, and then stores entries along with their expiries.
+// It must only be called with mu held
+func (c *Cache[K, V]) replaceItems(items backend[K, V], entries []entry[K, V]) {
+	c.clearItems(items)
+	c.tryExpiryOp(func(expiries map[K]*expiry[K]) {
+		for key := range expiries {
+			stopExpiry(expiries, key)
+		}
+	})
+
+	for _, e := range entries {
+		c.placeEntry(items, e)
+	}
+}
+
+// Snapshot returns every entry that has not expired encoded with encoding/gob. See Cache.Snapshot
+func (c *ShardedCache[K, V]) Snapshot() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := c.ExportGob(&buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Restore replaces every entry in the cache with the entries in data, a snapshot returned by Snapshot.
+// All shards are locked while the entries are swapped. See Cache.Restore
+func (c *ShardedCache[K, V]) Restore(data []byte) error {
+	entries, err := importGob[K, V](bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	live := liveEntries(entries)
+	groups := map[*Cache[K, V]][]entry[K, V]{}
+	for _, e := range live {
+		shard := c.shard(e.Key)
+		groups[shard] = append(groups[shard], e)
+	}
+
+	c.allShardsOp(true, func(shard *Cache[K, V], items backend[K, V]) {
+		shard.replaceItems(items, groups[shard])
+	})
+
+	return nil
+}
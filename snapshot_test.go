@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	c := New()
+	c.Set("int", 1)
+	c.Set("string", "a")
+	c.Set("struct", persistPoint{X: 1, Y: 2})
+	c.Set("slice", []T{1, "a"})
+	c.Set("expiring", 1, Expire(time.Hour))
+
+	data, err := c.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := New()
+	restored.Set("stale", 1, Expire(time.Millisecond))
+	restored.Set("int", 2)
+	if err := restored.Restore(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if result, expected := restored.Items(), c.Items(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if ttl, ok := restored.RemainingTTL("expiring"); !ok || ttl <= time.Hour-time.Minute {
+		t.Errorf("Remaining TTL was %v, expected about %v", ttl, time.Hour)
+	}
+
+	if _, ok := restored.RemainingTTL("stale"); ok {
+		t.Errorf("Expiry for key 'stale' should have been removed")
+	}
+
+	if err := restored.Restore([]byte("invalid")); err == nil {
+		t.Errorf("Restoring an invalid snapshot did not fail")
+	}
+
+	if size := restored.Size(); size != 5 {
+		t.Errorf("Cache size was %d, expected 5", size)
+	}
+}
+
+func TestRestoreDefaultExpiry(t *testing.T) {
+	c := New()
+	c.Set("1", 1)
+	c.Set("2", 2, Expire(time.Hour))
+
+	data, err := c.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewWithOptions(WithDefaultExpiry(time.Minute))
+	if err := restored.Restore(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if ttl, ok := restored.RemainingTTL("1"); !ok || ttl > time.Minute {
+		t.Errorf("Remaining TTL was %v, expected up to %v", ttl, time.Minute)
+	}
+
+	if ttl, ok := restored.RemainingTTL("2"); !ok || ttl <= time.Hour-time.Minute {
+		t.Errorf("Remaining TTL was %v, expected about %v", ttl, time.Hour)
+	}
+
+	var buf bytes.Buffer
+	if err := c.ExportGob(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	imported := NewWithOptions(WithDefaultExpiry(time.Minute))
+	if err := imported.ImportGob(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if ttl, ok := imported.RemainingTTL("1"); !ok || ttl > time.Minute {
+		t.Errorf("Remaining TTL was %v, expected up to %v", ttl, time.Minute)
+	}
+}
+
+func TestShardedSnapshotRestore(t *testing.T) {
+	c := NewSharded(WithShards(4))
+	for i := 0; i < 10; i++ {
+		c.Set(string(rune('a'+i)), i)
+	}
+
+	data, err := c.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewSharded(WithShards(4))
+	restored.Set("stale", 1)
+	if err := restored.Restore(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if result, expected := restored.Items(), c.Items(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
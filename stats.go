@@ -0,0 +1,116 @@
+package cache
+
+import "sync/atomic"
+
+// CacheStats holds counters describing how a cache has been used
+type CacheStats struct {
+	// Hits is the number of reads that found an entry
+	Hits int64
+	// Misses is the number of reads that found no entry
+	Misses int64
+	// Sets is the number of entries stored
+	Sets int64
+	// Deletes is the number of entries removed by Delete, Clear or a similar call
+	Deletes int64
+	// Evictions is the number of entries evicted to make room in a cache created with WithMaxSize
+	Evictions int64
+	// Expired is the number of entries removed because their expiry elapsed
+	Expired int64
+	// CurrentSize is the number of entries in the cache
+	CurrentSize int64
+}
+
+// HitRate returns the fraction of reads that found an entry, or 0 if there have been no reads
+func (s CacheStats) HitRate() float64 {
+	if total := s.Hits + s.Misses; total > 0 {
+		return float64(s.Hits) / float64(total)
+	}
+
+	return 0
+}
+
+// stats holds a cache's counters.
+// They are updated atomically, so reading them does not need to lock the cache
+type stats struct {
+	hits      atomic.Int64
+	misses    atomic.Int64
+	sets      atomic.Int64
+	deletes   atomic.Int64
+	evictions atomic.Int64
+	expired   atomic.Int64
+	size      atomic.Int64
+}
+
+// record counts an entry removed for the specified reason
+func (s *stats) record(reason EvictionReason) {
+	switch reason {
+	case Expired:
+		s.expired.Add(1)
+	case Capacity:
+		s.evictions.Add(1)
+	case Manual:
+		s.deletes.Add(1)
+	}
+}
+
+func (s *stats) snapshot() CacheStats {
+	return CacheStats{
+		Hits:        s.hits.Load(),
+		Misses:      s.misses.Load(),
+		Sets:        s.sets.Load(),
+		Deletes:     s.deletes.Load(),
+		Evictions:   s.evictions.Load(),
+		Expired:     s.expired.Load(),
+		CurrentSize: s.size.Load(),
+	}
+}
+
+func (s *stats) reset() {
+	s.hits.Store(0)
+	s.misses.Store(0)
+	s.sets.Store(0)
+	s.deletes.Store(0)
+	s.evictions.Store(0)
+	s.expired.Store(0)
+}
+
+// Stats returns the cache's usage counters
+func (c *Cache[K, V]) Stats() CacheStats {
+	return c.stats.snapshot()
+}
+
+// ResetStats zeroes the cache's usage counters.
+// CurrentSize describes the cache's contents rather than its usage, so it is left as is
+func (c *Cache[K, V]) ResetStats() {
+	c.stats.reset()
+}
+
+// Stats returns the usage counters summed over all shards
+func (c *ShardedCache[K, V]) Stats() CacheStats {
+	var total CacheStats
+	for _, shard := range c.shards {
+		s := shard.Stats()
+		total.Hits += s.Hits
+		total.Misses += s.Misses
+		total.Sets += s.Sets
+		total.Deletes += s.Deletes
+		total.Evictions += s.Evictions
+		total.Expired += s.Expired
+		total.CurrentSize += s.CurrentSize
+	}
+
+	return total
+}
+
+// ResetStats zeroes the usage counters of all shards. See Cache.ResetStats
+func (c *ShardedCache[K, V]) ResetStats() {
+	for _, shard := range c.shards {
+		shard.ResetStats()
+	}
+}
+
+// CacheMetrics is implemented by caches that report usage counters.
+// Integrations such as the prometheus sub-package accept it so this package does not depend on them
+type CacheMetrics interface {
+	Stats() CacheStats
+}
@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	c := NewWithOptions(WithMaxSize(3))
+	c.Set("1", 1)
+	c.Set("1", 1)
+	c.SetMany(map[string]T{"2": 2, "3": 3})
+	c.Set("4", 4, Expire(time.Millisecond))
+
+	c.Get("2")
+	c.GetOK("5")
+	c.GetMany([]string{"2", "3", "6"})
+	c.GetOrSet("2", func() T { return 0 })
+
+	c.Delete("3")
+	c.Delete("3")
+	time.Sleep(time.Millisecond * 2)
+
+	expected := CacheStats{
+		Hits:        4,
+		Misses:      2,
+		Sets:        5,
+		Deletes:     1,
+		Evictions:   1,
+		Expired:     1,
+		CurrentSize: 1,
+	}
+
+	if result := c.Stats(); result != expected {
+		t.Errorf("Stats were %+v, expected %+v", result, expected)
+	}
+
+	if result, expected := c.Stats().HitRate(), 4.0/6.0; result != expected {
+		t.Errorf("Hit rate was %v, expected %v", result, expected)
+	}
+
+	c.ResetStats()
+	if result, expected := c.Stats(), (CacheStats{CurrentSize: 1}); result != expected {
+		t.Errorf("Stats were %+v, expected %+v", result, expected)
+	}
+}
+
+func TestShardedStats(t *testing.T) {
+	c := NewSharded(WithShards(4))
+	for i := 0; i < 10; i++ {
+		c.Set(string(rune('a'+i)), i)
+	}
+
+	c.Get("a")
+	c.Get("z")
+
+	expected := CacheStats{Hits: 1, Misses: 1, Sets: 10, CurrentSize: 10}
+	if result := c.Stats(); result != expected {
+		t.Errorf("Stats were %+v, expected %+v", result, expected)
+	}
+}
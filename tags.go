@@ -0,0 +1,91 @@
+package cache
+
+// SetWithTags will set the val into the cache at the specified key, like Set, and register the key under tags,
+// so that it can later be removed along with every other entry sharing a tag by DeleteByTag.
+// The tags replace any the entry was previously set with. They are dropped when the entry is removed,
+// but kept if it is overwritten by Set. In a cache created with WithWriteThrough, the entry is only stored,
+// and the tags only registered, if the write succeeds, as with Set.
+func (c *Cache[K, V]) SetWithTags(key K, val V, tags []string, options ...SetOption) {
+	if !c.writeOrLog(key, val) {
+		return
+	}
+
+	c.itemOp(func(items backend[K, V]) {
+		c.placeWritten(items, key, val, options)
+		if _, ok := items.load(key); ok {
+			c.untag(key)
+			c.tag(key, tags)
+		}
+	})
+}
+
+// DeleteByTag removes every entry registered under tag by SetWithTags in a single pass.
+// Returns the number of entries that were removed
+func (c *Cache[K, V]) DeleteByTag(tag string) int {
+	result := make(chan int, 1)
+	c.itemOp(func(items backend[K, V]) {
+		keys := make([]K, 0, len(c.tags[tag]))
+		for key := range c.tags[tag] {
+			keys = append(keys, key)
+		}
+
+		for _, key := range keys {
+			c.evict(items, key, Manual)
+		}
+
+		c.tryExpiryOp(func(expiries map[K]*expiry[K]) {
+			for _, key := range keys {
+				stopExpiry(expiries, key)
+			}
+		})
+
+		result <- len(keys)
+	})
+
+	return <-result
+}
+
+// tag registers key under tags. It must only be called with mu held
+func (c *Cache[K, V]) tag(key K, tags []string) {
+	for _, tag := range tags {
+		keys, ok := c.tags[tag]
+		if !ok {
+			keys = map[K]struct{}{}
+			c.tags[tag] = keys
+		}
+
+		if _, ok := keys[key]; !ok {
+			keys[key] = struct{}{}
+			c.keyTags[key] = append(c.keyTags[key], tag)
+		}
+	}
+}
+
+// untag removes key from every tag it is registered under. It must only be called with mu held
+func (c *Cache[K, V]) untag(key K) {
+	for _, tag := range c.keyTags[key] {
+		delete(c.tags[tag], key)
+		if len(c.tags[tag]) == 0 {
+			delete(c.tags, tag)
+		}
+	}
+
+	delete(c.keyTags, key)
+}
+
+// SetWithTags will set the val into the cache at the specified key and register it under tags.
+// See Cache.SetWithTags
+func (c *ShardedCache[K, V]) SetWithTags(key K, val V, tags []string, options ...SetOption) {
+	c.shard(key).SetWithTags(key, val, tags, options...)
+}
+
+// DeleteByTag removes every entry registered under tag, using a single pass per shard.
+// Returns the number of entries that were removed
+func (c *ShardedCache[K, V]) DeleteByTag(tag string) int {
+	count := 0
+	for _, shard := range c.shards {
+		count += shard.DeleteByTag(tag)
+	}
+
+	return count
+}
@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDeleteByTag(t *testing.T) {
+	c := New()
+	c.SetWithTags("1", 1, []string{"a", "b"})
+	c.SetWithTags("2", 2, []string{"a"}, Expire(time.Hour))
+	c.SetWithTags("3", 3, []string{"b"})
+	c.Set("4", 4)
+
+	if result, expected := c.DeleteByTag("a"), 2; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if result, expected := c.Keys(), []string{"3", "4"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if _, ok := c.RemainingTTL("2"); ok {
+		t.Errorf("Expiry was not cancelled")
+	}
+
+	if result, expected := c.DeleteByTag("b"), 1; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if result, expected := c.DeleteByTag("missing"), 0; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestDeleteByTagRemoved(t *testing.T) {
+	c := New()
+	c.SetWithTags("1", 1, []string{"a"})
+	c.SetWithTags("2", 2, []string{"a"})
+	c.SetWithTags("3", 3, []string{"a"})
+	c.SetWithTags("3", 3, []string{"b"})
+	c.Delete("1")
+	c.Rename("2", "4")
+
+	if result, expected := c.DeleteByTag("a"), 1; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if result, expected := c.Keys(), []string{"3"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
@@ -0,0 +1,654 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// A TwoLevelCache is a Cacher that layers a small, fast L1 cache, typically a Cache, in front of a larger,
+// slower L2 cache, such as one shared between processes.
+// Reads check L1 first, and entries found only in L2 are promoted to L1 with the remaining TTL L2 reports.
+// Writes go to L2 and then L1, so L2 always holds every entry and answers all calls that list or count entries.
+// Calls whose result depends on the current value, such as CompareAndSwap or Increment, run on L2 alone
+// and remove the entry from L1, so its next read fetches the result from L2.
+// SetOptions apply to both levels, so an AfterFunc callback runs once for each.
+// A TwoLevelCache is safe for concurrent use if both levels are, but since the levels are updated one after
+// the other, a concurrent read may find an entry in one level before it has been written to the other
+type TwoLevelCache[K comparable, V any] struct {
+	L1 Cacher[K, V]
+	L2 Cacher[K, V]
+
+	doneOnce  sync.Once
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewTwoLevel returns a TwoLevelCache that consults l1 before l2
+func NewTwoLevel[K comparable, V any](l1, l2 Cacher[K, V]) *TwoLevelCache[K, V] {
+	return &TwoLevelCache[K, V]{L1: l1, L2: l2}
+}
+
+// closed returns a channel that is closed once the cache is closed
+func (c *TwoLevelCache[K, V]) closed() chan struct{} {
+	c.doneOnce.Do(func() {
+		c.done = make(chan struct{})
+	})
+
+	return c.done
+}
+
+// promote copies an entry read from L2 into L1, keeping the expiry L2 reports for it
+func (c *TwoLevelCache[K, V]) promote(key K, val V) {
+	if ttl, ok := c.L2.RemainingTTL(key); ok {
+		c.L1.Set(key, val, Expire(ttl))
+		return
+	}
+
+	c.L1.Set(key, val)
+}
+
+// Stats returns the usage counters of L1, whose hit rate shows how often L2 is spared a read
+func (c *TwoLevelCache[K, V]) Stats() CacheStats {
+	return c.L1.Stats()
+}
+
+// ResetStats zeroes the usage counters of both levels
+func (c *TwoLevelCache[K, V]) ResetStats() {
+	c.L2.ResetStats()
+	c.L1.ResetStats()
+}
+
+// Set will set the val into both levels at the specified key. See Cache.Set
+func (c *TwoLevelCache[K, V]) Set(key K, val V, options ...SetOption) {
+	c.L2.Set(key, val, options...)
+	c.L1.Set(key, val, options...)
+}
+
+// SetE will set the val into L2 and then L1 at the specified key, returning the first error without writing
+// the other level. See Cache.SetE
+func (c *TwoLevelCache[K, V]) SetE(key K, val V, options ...SetOption) error {
+	if err := c.L2.SetE(key, val, options...); err != nil {
+		return err
+	}
+
+	return c.L1.SetE(key, val, options...)
+}
+
+// SetMany will set each entry of entries into both levels. See Cache.SetMany
+func (c *TwoLevelCache[K, V]) SetMany(entries map[K]V, options ...SetOption) {
+	c.L2.SetMany(entries, options...)
+	c.L1.SetMany(entries, options...)
+}
+
+// MSet will set each entry of entries into both levels. L1 is only written once L2 has been. See Cache.MSet
+func (c *TwoLevelCache[K, V]) MSet(entries map[K]V) error {
+	if err := c.L2.MSet(entries); err != nil {
+		return err
+	}
+
+	return c.L1.MSet(entries)
+}
+
+// SetBatch will set each of entries into both levels with its own options. See Cache.SetBatch
+func (c *TwoLevelCache[K, V]) SetBatch(entries []BatchEntry[K, V]) {
+	c.L2.SetBatch(entries)
+	c.L1.SetBatch(entries)
+}
+
+// SetCtx behaves like Set, but gives up if ctx is done first. L1 is only written once L2 has been
+func (c *TwoLevelCache[K, V]) SetCtx(ctx context.Context, key K, val V, options ...SetOption) error {
+	if err := c.L2.SetCtx(ctx, key, val, options...); err != nil {
+		return err
+	}
+
+	return c.L1.SetCtx(ctx, key, val, options...)
+}
+
+// SetWithTags will set the val into both levels at the specified key and register it under tags.
+// See Cache.SetWithTags
+func (c *TwoLevelCache[K, V]) SetWithTags(key K, val V, tags []string, options ...SetOption) {
+	c.L2.SetWithTags(key, val, tags, options...)
+	c.L1.SetWithTags(key, val, tags, options...)
+}
+
+// GetOrSet retrieves an entry at the specified key from either level, storing the result of fn in L2
+// if neither holds one. See Cache.GetOrSet
+func (c *TwoLevelCache[K, V]) GetOrSet(key K, fn func() V, options ...SetOption) V {
+	if v, ok := c.L1.GetOK(key); ok {
+		return v
+	}
+
+	v := c.L2.GetOrSet(key, fn, options...)
+	c.promote(key, v)
+	return v
+}
+
+// GetOrLoad retrieves an entry at the specified key from either level, loading it into L2 with loader
+// if neither holds one. See Cache.GetOrLoad
+func (c *TwoLevelCache[K, V]) GetOrLoad(key K, loader func() (V, time.Duration, error)) (V, error) {
+	if v, ok := c.L1.GetOK(key); ok {
+		return v, nil
+	}
+
+	v, err := c.L2.GetOrLoad(key, loader)
+	if err != nil {
+		return v, err
+	}
+
+	c.promote(key, v)
+	return v, nil
+}
+
+// GetOrSetMany retrieves the entries at the specified keys from either level, loading the keys neither holds
+// into L2 with a single call to loader. See Cache.GetOrSetMany
+func (c *TwoLevelCache[K, V]) GetOrSetMany(keys []K, loader func(keys []K) map[K]V, options ...SetOption) map[K]V {
+	found := c.L1.GetMany(keys)
+
+	var missing []K
+	for _, key := range keys {
+		if _, ok := found[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+
+	if len(missing) == 0 {
+		return found
+	}
+
+	for key, val := range c.L2.GetOrSetMany(missing, loader, options...) {
+		found[key] = val
+		c.promote(key, val)
+	}
+
+	return found
+}
+
+// SetIfAbsent will set the val into both levels at the specified key only if L2 holds no entry there.
+// See Cache.SetIfAbsent
+func (c *TwoLevelCache[K, V]) SetIfAbsent(key K, val V, options ...SetOption) bool {
+	if !c.L2.SetIfAbsent(key, val, options...) {
+		return false
+	}
+
+	c.L1.Set(key, val, options...)
+	return true
+}
+
+// SetDefault will set val as the default value at the specified key. See Cache.SetDefault
+func (c *TwoLevelCache[K, V]) SetDefault(key K, val V, options ...SetOption) bool {
+	return c.SetIfAbsent(key, val, options...)
+}
+
+// SetNX will set the val into both levels at the specified key only if L2 holds no entry there. See Cache.SetNX
+func (c *TwoLevelCache[K, V]) SetNX(key K, val V, options ...SetOption) bool {
+	return c.SetIfAbsent(key, val, options...)
+}
+
+// SetIfPresent will set the val into both levels at the specified key only if L2 already holds an entry there.
+// See Cache.SetIfPresent
+func (c *TwoLevelCache[K, V]) SetIfPresent(key K, val V, options ...SetOption) bool {
+	if !c.L2.SetIfPresent(key, val, options...) {
+		c.L1.Delete(key)
+		return false
+	}
+
+	c.L1.Set(key, val, options...)
+	return true
+}
+
+// SetXX will set the val into both levels at the specified key only if L2 already holds an entry there.
+// See Cache.SetXX
+func (c *TwoLevelCache[K, V]) SetXX(key K, val V, options ...SetOption) bool {
+	return c.SetIfPresent(key, val, options...)
+}
+
+// GetAndSet will set the val into both levels at the specified key and return the entry it replaced in L2.
+// See Cache.GetAndSet
+func (c *TwoLevelCache[K, V]) GetAndSet(key K, val V, options ...SetOption) (V, bool) {
+	v, ok := c.L2.GetAndSet(key, val, options...)
+	c.L1.Set(key, val, options...)
+	return v, ok
+}
+
+// CompareAndSwap will set newVal into the cache at the specified key only if the entry in L2 is deeply equal
+// to oldVal. See Cache.CompareAndSwap
+func (c *TwoLevelCache[K, V]) CompareAndSwap(key K, oldVal, newVal V, options ...SetOption) bool {
+	swapped := c.L2.CompareAndSwap(key, oldVal, newVal, options...)
+	c.L1.Delete(key)
+	return swapped
+}
+
+// Increment adds delta to the number stored in L2 at the specified key. See Cache.Increment
+func (c *TwoLevelCache[K, V]) Increment(key K, delta int64) (int64, error) {
+	n, err := c.L2.Increment(key, delta)
+	c.L1.Delete(key)
+	return n, err
+}
+
+// Decrement subtracts delta from the number stored in L2 at the specified key. See Cache.Increment
+func (c *TwoLevelCache[K, V]) Decrement(key K, delta int64) (int64, error) {
+	n, err := c.L2.Decrement(key, delta)
+	c.L1.Delete(key)
+	return n, err
+}
+
+// Append adds suffix to the end of the string stored in L2 at the specified key. See Cache.Append
+func (c *TwoLevelCache[K, V]) Append(key K, suffix string) (string, error) {
+	s, err := c.L2.Append(key, suffix)
+	c.L1.Delete(key)
+	return s, err
+}
+
+// Modify replaces the value stored in L2 at the specified key with the result of calling fn on it.
+// See Cache.Modify
+func (c *TwoLevelCache[K, V]) Modify(key K, fn func(current V) V) bool {
+	modified := c.L2.Modify(key, fn)
+	c.L1.Delete(key)
+	return modified
+}
+
+// Touch resets the expiry of the entry at the specified key in both levels. See Cache.Touch
+func (c *TwoLevelCache[K, V]) Touch(key K, d time.Duration) bool {
+	c.L1.Touch(key, d)
+	return c.L2.Touch(key, d)
+}
+
+// ExpireAt sets the entry at the specified key in both levels to expire at the deadline t. See Cache.ExpireAt
+func (c *TwoLevelCache[K, V]) ExpireAt(key K, t time.Time) bool {
+	c.L1.ExpireAt(key, t)
+	return c.L2.ExpireAt(key, t)
+}
+
+// Expire sets the entry at the specified key in both levels to expire d from now. See Cache.Expire
+func (c *TwoLevelCache[K, V]) Expire(key K, d time.Duration) bool {
+	c.L1.Expire(key, d)
+	return c.L2.Expire(key, d)
+}
+
+// Persist removes the expiry from the entry at the specified key in both levels.
+// Returns true if the entry in L2 had one. See Cache.Persist
+func (c *TwoLevelCache[K, V]) Persist(key K) bool {
+	c.L1.Persist(key)
+	return c.L2.Persist(key)
+}
+
+// Rename moves the entry at oldKey to newKey in L2, and removes both keys from L1. See Cache.Rename
+func (c *TwoLevelCache[K, V]) Rename(oldKey, newKey K) bool {
+	renamed := c.L2.Rename(oldKey, newKey)
+	c.L1.DeleteMany([]K{oldKey, newKey})
+	return renamed
+}
+
+// RemainingTTL returns how long the entry at the specified key in L2 has left before it expires.
+// See Cache.RemainingTTL
+func (c *TwoLevelCache[K, V]) RemainingTTL(key K) (time.Duration, bool) {
+	return c.L2.RemainingTTL(key)
+}
+
+// TTL returns how long the entry at the specified key in L2 has left before it expires. See Cache.TTL
+func (c *TwoLevelCache[K, V]) TTL(key K) (time.Duration, error) {
+	return c.L2.TTL(key)
+}
+
+// Clear removes all entries from both levels
+func (c *TwoLevelCache[K, V]) Clear() {
+	c.L2.Clear()
+	c.L1.Clear()
+}
+
+// ClearExpired removes every expired entry from both levels.
+// Returns the number of entries removed from L2
+func (c *TwoLevelCache[K, V]) ClearExpired() int {
+	n := c.L2.ClearExpired()
+	c.L1.ClearExpired()
+	return n
+}
+
+// ClearEvery clears both levels on a loop at the specified interval.
+// The loop stops when the cache is closed or when the returned stop func is called. See Cache.ClearEvery
+func (c *TwoLevelCache[K, V]) ClearEvery(d time.Duration) (stop func()) {
+	return clearLoop(d, c.closed(), func() bool {
+		c.Clear()
+		return true
+	})
+}
+
+// Delete removes the entry at the specified key from both levels. See Cache.Delete
+func (c *TwoLevelCache[K, V]) Delete(key K) {
+	c.L2.Delete(key)
+	c.L1.Delete(key)
+}
+
+// DeleteCtx behaves like Delete, but gives up if ctx is done first. See Cache.DeleteCtx
+func (c *TwoLevelCache[K, V]) DeleteCtx(ctx context.Context, key K) error {
+	if err := c.L2.DeleteCtx(ctx, key); err != nil {
+		return err
+	}
+
+	return c.L1.DeleteCtx(ctx, key)
+}
+
+// DeleteMany removes the entries at the specified keys from both levels.
+// Returns the number of entries that were removed from L2
+func (c *TwoLevelCache[K, V]) DeleteMany(keys []K) int {
+	c.L1.DeleteMany(keys)
+	return c.L2.DeleteMany(keys)
+}
+
+// DeletePrefix removes every entry whose key starts with prefix from both levels.
+// Returns the number of entries that were removed from L2
+func (c *TwoLevelCache[K, V]) DeletePrefix(prefix string) int {
+	c.L1.DeletePrefix(prefix)
+	return c.L2.DeletePrefix(prefix)
+}
+
+// DeleteSuffix removes every entry whose key ends with suffix from both levels.
+// Returns the number of entries that were removed from L2
+func (c *TwoLevelCache[K, V]) DeleteSuffix(suffix string) int {
+	c.L1.DeleteSuffix(suffix)
+	return c.L2.DeleteSuffix(suffix)
+}
+
+// DeleteContains removes every entry whose key contains substr from both levels.
+// Returns the number of entries that were removed from L2
+func (c *TwoLevelCache[K, V]) DeleteContains(substr string) int {
+	c.L1.DeleteContains(substr)
+	return c.L2.DeleteContains(substr)
+}
+
+// DeleteByTag removes every entry registered under tag from both levels.
+// Returns the number of entries that were removed from L2
+func (c *TwoLevelCache[K, V]) DeleteByTag(tag string) int {
+	c.L1.DeleteByTag(tag)
+	return c.L2.DeleteByTag(tag)
+}
+
+// GetAndDelete removes the entry at the specified key from both levels and returns it as held in L2.
+// See Cache.GetAndDelete
+func (c *TwoLevelCache[K, V]) GetAndDelete(key K) (V, bool) {
+	c.L1.Delete(key)
+	return c.L2.GetAndDelete(key)
+}
+
+// Evict removes the entry at the specified key from both levels and returns it as held in L2,
+// calling L2's OnEvict. See Cache.Evict
+func (c *TwoLevelCache[K, V]) Evict(key K) (V, bool) {
+	c.L1.Delete(key)
+	return c.L2.Evict(key)
+}
+
+// CompareAndDelete removes the entry at the specified key from both levels only if the entry in L2 is deeply
+// equal to expected. See Cache.CompareAndDelete
+func (c *TwoLevelCache[K, V]) CompareAndDelete(key K, expected V) bool {
+	if !c.L2.CompareAndDelete(key, expected) {
+		return false
+	}
+
+	c.L1.Delete(key)
+	return true
+}
+
+// Get retrieves an entry at the specified key from L1, or from L2 if L1 has none
+func (c *TwoLevelCache[K, V]) Get(key K) V {
+	v, _ := c.GetOK(key)
+	return v
+}
+
+// GetOK retrieves an entry at the specified key from L1, or from L2 if L1 has none.
+// Returns bool specifying if the entry exists
+func (c *TwoLevelCache[K, V]) GetOK(key K) (V, bool) {
+	if v, ok := c.L1.GetOK(key); ok {
+		return v, true
+	}
+
+	v, ok := c.L2.GetOK(key)
+	if ok {
+		c.promote(key, v)
+	}
+
+	return v, ok
+}
+
+// Contains reports whether an entry exists at the specified key in either level.
+// Unlike GetOK, an entry found only in L2 is not promoted to L1
+func (c *TwoLevelCache[K, V]) Contains(key K) bool {
+	return c.L1.Contains(key) || c.L2.Contains(key)
+}
+
+// Probabilistic retrieves an entry at the specified key from L1, or from L2 if L1 has none or reports it
+// missing early. See Cache.Probabilistic
+func (c *TwoLevelCache[K, V]) Probabilistic(key K, beta float64) (V, bool) {
+	if v, ok := c.L1.Probabilistic(key, beta); ok {
+		return v, true
+	}
+
+	v, ok := c.L2.Probabilistic(key, beta)
+	if ok {
+		c.promote(key, v)
+	}
+
+	return v, ok
+}
+
+// GetCtx behaves like Get, but gives up if ctx is done first. See Cache.GetCtx.
+// Since GetCtx cannot tell a missing entry from a zero value, entries it reads from L2 are not promoted
+func (c *TwoLevelCache[K, V]) GetCtx(ctx context.Context, key K) (V, error) {
+	if v, ok := c.L1.GetOK(key); ok {
+		return v, nil
+	}
+
+	return c.L2.GetCtx(ctx, key)
+}
+
+// GetMany retrieves the entries at the specified keys, reading from L2 only the keys missing from L1.
+// See Cache.GetMany
+func (c *TwoLevelCache[K, V]) GetMany(keys []K) map[K]V {
+	found, err := c.MGet(keys)
+	if err != nil {
+		panic(err)
+	}
+
+	return found
+}
+
+// MGet retrieves the entries at the specified keys, reading from L2 only the keys missing from L1.
+// See Cache.MGet
+func (c *TwoLevelCache[K, V]) MGet(keys []K) (map[K]V, error) {
+	found, err := c.L1.MGet(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []K
+	for _, key := range keys {
+		if _, ok := found[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+
+	if len(missing) == 0 {
+		return found, nil
+	}
+
+	vals, err := c.L2.MGet(missing)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, val := range vals {
+		found[key] = val
+		c.promote(key, val)
+	}
+
+	return found, nil
+}
+
+// Watch returns a channel that receives changes to the entry at the specified key in L2. See Cache.Watch
+func (c *TwoLevelCache[K, V]) Watch(key K) (<-chan WatchEvent[K, V], func()) {
+	return c.L2.Watch(key)
+}
+
+// WatchPrefix returns a channel that receives changes to the entries in L2 whose keys start with prefix.
+// See Cache.WatchPrefix
+func (c *TwoLevelCache[K, V]) WatchPrefix(prefix string) (<-chan WatchEvent[K, V], func()) {
+	return c.L2.WatchPrefix(prefix)
+}
+
+// WaitForKey retrieves the entry at the specified key, blocking until L2 holds one. See Cache.WaitForKey
+func (c *TwoLevelCache[K, V]) WaitForKey(ctx context.Context, key K) (V, error) {
+	if v, ok := c.L1.GetOK(key); ok {
+		return v, nil
+	}
+
+	v, err := c.L2.WaitForKey(ctx, key)
+	if err == nil {
+		c.promote(key, v)
+	}
+
+	return v, err
+}
+
+// Items retrieves all entries in L2
+func (c *TwoLevelCache[K, V]) Items() map[K]V {
+	return c.L2.Items()
+}
+
+// Dump retrieves all entries in L2 along with when they expire. See Cache.Dump
+func (c *TwoLevelCache[K, V]) Dump() map[K]CacheEntry[V] {
+	return c.L2.Dump()
+}
+
+// Values retrieves all values in L2 in no particular order
+func (c *TwoLevelCache[K, V]) Values() []V {
+	return c.L2.Values()
+}
+
+// ForEach calls fn for each entry in L2. See Cache.ForEach
+func (c *TwoLevelCache[K, V]) ForEach(fn func(key K, val V)) {
+	c.L2.ForEach(fn)
+}
+
+// FilterItems retrieves the entries in L2 for which predicate returns true. See Cache.FilterItems
+func (c *TwoLevelCache[K, V]) FilterItems(predicate func(K, V) bool) map[K]V {
+	return c.L2.FilterItems(predicate)
+}
+
+// Count returns the number of entries in L2 for which predicate returns true. See Cache.Count
+func (c *TwoLevelCache[K, V]) Count(predicate func(K, V) bool) int {
+	return c.L2.Count(predicate)
+}
+
+// IsEmpty returns wherever L2 is empty
+func (c *TwoLevelCache[K, V]) IsEmpty() bool {
+	return c.L2.IsEmpty()
+}
+
+// Size returns the number of entries in L2
+func (c *TwoLevelCache[K, V]) Size() int {
+	return c.L2.Size()
+}
+
+// Keys retrieves a sorted list of all keys in L2
+func (c *TwoLevelCache[K, V]) Keys() []K {
+	return c.L2.Keys()
+}
+
+// UnsortedKeys retrieves a list of all keys in L2 in no particular order
+func (c *TwoLevelCache[K, V]) UnsortedKeys() []K {
+	return c.L2.UnsortedKeys()
+}
+
+// KeysExpiringSoon retrieves a sorted list of the keys in L2 whose entries expire within the specified duration.
+// See Cache.KeysExpiringSoon
+func (c *TwoLevelCache[K, V]) KeysExpiringSoon(within time.Duration) []K {
+	return c.L2.KeysExpiringSoon(within)
+}
+
+// ExpiredKeys retrieves a sorted list of the keys in L2 whose entries have passed their deadline
+// but not yet been removed. See Cache.ExpiredKeys
+func (c *TwoLevelCache[K, V]) ExpiredKeys() []K {
+	return c.L2.ExpiredKeys()
+}
+
+// Scan returns up to count of the keys in L2, along with the cursor to continue from. See Cache.Scan
+func (c *TwoLevelCache[K, V]) Scan(cursor, count int) (int, []K) {
+	return c.L2.Scan(cursor, count)
+}
+
+// FilterKeys retrieves a sorted list of the keys in L2 for which predicate returns true. See Cache.FilterKeys
+func (c *TwoLevelCache[K, V]) FilterKeys(predicate func(K) bool) []K {
+	return c.L2.FilterKeys(predicate)
+}
+
+// ExportJSON writes every entry in L2 that has not expired to w as JSON. See Cache.ExportJSON
+func (c *TwoLevelCache[K, V]) ExportJSON(w io.Writer) error {
+	return c.L2.ExportJSON(w)
+}
+
+// ImportJSON reads entries written by ExportJSON from r into L2, and clears L1. See Cache.ImportJSON
+func (c *TwoLevelCache[K, V]) ImportJSON(r io.Reader) error {
+	if err := c.L2.ImportJSON(r); err != nil {
+		return err
+	}
+
+	c.L1.Clear()
+	return nil
+}
+
+// ExportGob writes every entry in L2 that has not expired to w using encoding/gob. See Cache.ExportGob
+func (c *TwoLevelCache[K, V]) ExportGob(w io.Writer) error {
+	return c.L2.ExportGob(w)
+}
+
+// ImportGob reads entries written by ExportGob from r into L2, and clears L1. See Cache.ImportGob
+func (c *TwoLevelCache[K, V]) ImportGob(r io.Reader) error {
+	if err := c.L2.ImportGob(r); err != nil {
+		return err
+	}
+
+	c.L1.Clear()
+	return nil
+}
+
+// SaveToFile writes L2 to the file at path. See Cache.SaveToFile
+func (c *TwoLevelCache[K, V]) SaveToFile(path string) error {
+	return c.L2.SaveToFile(path)
+}
+
+// LoadFromFile imports the file at path written by SaveToFile into L2, and clears L1. See Cache.LoadFromFile
+func (c *TwoLevelCache[K, V]) LoadFromFile(path string) error {
+	if err := c.L2.LoadFromFile(path); err != nil {
+		return err
+	}
+
+	c.L1.Clear()
+	return nil
+}
+
+// Snapshot returns every entry in L2 that has not expired encoded with encoding/gob. See Cache.Snapshot
+func (c *TwoLevelCache[K, V]) Snapshot() ([]byte, error) {
+	return c.L2.Snapshot()
+}
+
+// Restore replaces every entry in L2 with the entries in data, and clears L1. See Cache.Restore
+func (c *TwoLevelCache[K, V]) Restore(data []byte) error {
+	if err := c.L2.Restore(data); err != nil {
+		return err
+	}
+
+	c.L1.Clear()
+	return nil
+}
+
+// Close closes both levels, and stops any loop started by ClearEvery.
+// Returns the errors from closing either level, joined by errors.Join
+func (c *TwoLevelCache[K, V]) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed())
+	})
+
+	return errors.Join(c.L1.Close(), c.L2.Close())
+}
@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTwoLevelReadThrough(t *testing.T) {
+	l1, l2 := New(), New()
+	c := NewTwoLevel[string, T](l1, l2)
+
+	l2.Set("1", 1, Expire(time.Hour))
+	if result, ok := c.GetOK("1"); !ok || result != 1 {
+		t.Errorf("Result was %#v, %v, expected 1, true", result, ok)
+	}
+
+	if result, ok := l1.GetOK("1"); !ok || result != 1 {
+		t.Errorf("Entry for key '1' should have been promoted to L1")
+	}
+
+	if ttl, ok := l1.RemainingTTL("1"); !ok || ttl <= time.Hour-time.Minute {
+		t.Errorf("Remaining TTL in L1 was %v, expected about %v", ttl, time.Hour)
+	}
+
+	l2.Delete("1")
+	if result := c.Get("1"); result != 1 {
+		t.Errorf("Result was %#v, expected the value cached in L1", result)
+	}
+
+	if _, ok := c.GetOK("2"); ok {
+		t.Errorf("Entry for key '2' should not exist")
+	}
+
+	l2.SetMany(map[string]T{"3": 3, "4": 4})
+	l1.Set("4", 5)
+	if result, expected := c.GetMany([]string{"3", "4", "5"}), map[string]T{"3": 3, "4": 5}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestTwoLevelWriteThrough(t *testing.T) {
+	l1, l2 := New(), New()
+	c := NewTwoLevel[string, T](l1, l2)
+
+	c.Set("1", 1)
+	c.Set("2", 2)
+	for name, level := range map[string]*StringCache{"L1": l1, "L2": l2} {
+		if result, expected := level.Items(), map[string]T{"1": 1, "2": 2}; !reflect.DeepEqual(result, expected) {
+			t.Errorf("%s was %#v, expected %#v", name, result, expected)
+		}
+	}
+
+	c.Delete("1")
+	if l1.Size() != 1 || l2.Size() != 1 {
+		t.Errorf("Entry for key '1' should have been removed from both levels")
+	}
+
+	if _, err := c.Increment("2", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := l1.GetOK("2"); ok {
+		t.Errorf("Entry for key '2' should have been removed from L1")
+	}
+
+	if result := c.Get("2"); result != 3 {
+		t.Errorf("Result was %#v, expected 3", result)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Close(); err == nil {
+		t.Errorf("Closing the cache twice should fail")
+	}
+}
@@ -0,0 +1,157 @@
+package cache
+
+import "errors"
+
+// ErrConflict is returned by Transaction in a cache created with WithWriteThrough when the cache
+// kept changing while the transaction's entries were being written
+var ErrConflict = errors.New("cache: transaction conflicts with other changes")
+
+// maxTxAttempts is the number of times Transaction calls fn in a cache created with WithWriteThrough
+// before giving up with ErrConflict
+const maxTxAttempts = 3
+
+// A Tx buffers the changes made in a transaction started by Cache.Transaction,
+// and reads the cache as it would be with them applied. It is only valid until fn returns
+type Tx[K comparable, V any] struct {
+	c     *Cache[K, V]
+	items backend[K, V]
+
+	// writes holds the latest change buffered at each key, and order the keys in the order they were first changed
+	writes map[K]txWrite[V]
+	order  []K
+}
+
+// A txWrite is a change buffered in a Tx: a Set of val with options, or a Delete if deleted is true
+type txWrite[V any] struct {
+	val     V
+	options []SetOption
+	deleted bool
+}
+
+// Get retrieves the entry at the specified key, as changed by the transaction so far.
+// Returns false if no entry exists. Unlike Cache.Get, the cache's loader is not called
+func (tx *Tx[K, V]) Get(key K) (V, bool) {
+	if w, ok := tx.writes[key]; ok {
+		var zero V
+		if w.deleted {
+			return zero, false
+		}
+
+		return w.val, true
+	}
+
+	return tx.c.lookup(tx.items, key)
+}
+
+// Set buffers setting val at the specified key, as Cache.Set does once the transaction commits
+func (tx *Tx[K, V]) Set(key K, val V, options ...SetOption) {
+	tx.write(key, txWrite[V]{val: val, options: options})
+}
+
+// Delete buffers removing the entry at the specified key once the transaction commits
+func (tx *Tx[K, V]) Delete(key K) {
+	tx.write(key, txWrite[V]{deleted: true})
+}
+
+func (tx *Tx[K, V]) write(key K, w txWrite[V]) {
+	if _, ok := tx.writes[key]; !ok {
+		tx.order = append(tx.order, key)
+	}
+
+	tx.writes[key] = w
+}
+
+// Transaction calls fn with a Tx, and then applies the changes fn made through it all at once.
+// If fn returns an error, the changes are discarded and the error is returned.
+// fn runs while the cache is locked, so transactions never interleave with each other or with any other operation,
+// and fn must not call back into the cache other than through tx.
+// In a cache created with WithWriteThrough, each entry set is written once fn has returned, with the cache unlocked
+// as in Pipeline.Execute, and the changes are applied with the cache locked again. If an entry was stored or removed
+// in the meantime, what fn read may be out of date, so fn is called again and its entries written again.
+// fn and the writes may therefore run up to 3 times, after which ErrConflict is returned and nothing is applied,
+// though the entries have been written. If a write fails, none of the changes are applied and its error is returned,
+// though the writes before it have been made
+func (c *Cache[K, V]) Transaction(fn func(tx *Tx[K, V]) error) error {
+	type prepared struct {
+		tx      *Tx[K, V]
+		version uint64
+		err     error
+	}
+
+	for attempt := 0; attempt < maxTxAttempts; attempt++ {
+		result := make(chan prepared, 1)
+		c.itemOp(func(items backend[K, V]) {
+			tx := &Tx[K, V]{c: c, items: items, writes: map[K]txWrite[V]{}}
+			if err := fn(tx); err != nil {
+				result <- prepared{err: err}
+				return
+			}
+
+			if c.write == nil || !tx.sets() {
+				tx.commit(items)
+				result <- prepared{}
+				return
+			}
+
+			result <- prepared{tx: tx, version: c.version}
+		})
+
+		p := <-result
+		if p.tx == nil {
+			return p.err
+		}
+
+		for _, key := range p.tx.order {
+			if w := p.tx.writes[key]; !w.deleted {
+				if err := c.writeThrough(key, w.val); err != nil {
+					return err
+				}
+			}
+		}
+
+		committed := make(chan bool, 1)
+		c.itemOp(func(items backend[K, V]) {
+			if c.version != p.version {
+				committed <- false
+				return
+			}
+
+			p.tx.commit(items)
+			committed <- true
+		})
+
+		if <-committed {
+			return nil
+		}
+	}
+
+	return ErrConflict
+}
+
+// sets reports if the transaction buffered any Set
+func (tx *Tx[K, V]) sets() bool {
+	for _, w := range tx.writes {
+		if !w.deleted {
+			return true
+		}
+	}
+
+	return false
+}
+
+// commit applies the changes buffered in the transaction to items, with any entries set already written through.
+// It must only be called with mu held
+func (tx *Tx[K, V]) commit(items backend[K, V]) {
+	for _, key := range tx.order {
+		w := tx.writes[key]
+		if !w.deleted {
+			tx.c.placeWritten(items, key, w.val, w.options)
+			continue
+		}
+
+		tx.c.evict(items, key, Manual)
+		tx.c.tryExpiryOp(func(expiries map[K]*expiry[K]) {
+			stopExpiry(expiries, key)
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package cache
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestTransaction(t *testing.T) {
+	c := New()
+	c.Set("1", 1)
+	c.Set("2", 2)
+
+	err := c.Transaction(func(tx *Tx[string, T]) error {
+		v, _ := tx.Get("1")
+		tx.Set("1", v.(int)+10)
+		tx.Delete("2")
+		tx.Set("3", 3)
+
+		if v, ok := tx.Get("1"); !ok || v != T(11) {
+			t.Errorf("Result was %#v, %v, expected %#v", v, ok, 11)
+		}
+
+		if _, ok := tx.Get("2"); ok {
+			t.Errorf("Entry for key '2' should have been deleted within the transaction")
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result, expected := c.Items(), map[string]T{"1": 11, "3": 3}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestTransactionRollback(t *testing.T) {
+	c := New()
+	c.Set("1", 1)
+
+	errAbort := errors.New("abort")
+	err := c.Transaction(func(tx *Tx[string, T]) error {
+		tx.Set("1", 2)
+		tx.Delete("1")
+		tx.Set("2", 2)
+		return errAbort
+	})
+
+	if err != errAbort {
+		t.Errorf("Error was %v, expected %v", err, errAbort)
+	}
+
+	if result, expected := c.Items(), map[string]T{"1": 1}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestTransactionConcurrent(t *testing.T) {
+	c := New()
+	c.Set("a", 0)
+	c.Set("b", 0)
+
+	const callers = 100
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Transaction(func(tx *Tx[string, T]) error {
+				a, _ := tx.Get("a")
+				b, _ := tx.Get("b")
+				tx.Set("a", a.(int)+1)
+				tx.Set("b", b.(int)-1)
+				return nil
+			})
+		}()
+	}
+
+	wg.Wait()
+
+	if result, expected := c.Items(), map[string]T{"a": callers, "b": -callers}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestTransactionWriteThrough(t *testing.T) {
+	errWrite := errors.New("write failed")
+	c := NewWithOptions(WithWriteThrough(func(key string, val T) error {
+		if key == "2" {
+			return errWrite
+		}
+
+		return nil
+	}))
+
+	err := c.Transaction(func(tx *Tx[string, T]) error {
+		tx.Set("1", 1)
+		tx.Set("2", 2)
+		return nil
+	})
+
+	if err != errWrite {
+		t.Errorf("Error was %v, expected %v", err, errWrite)
+	}
+
+	if !c.IsEmpty() {
+		t.Errorf("A failed write should have discarded the whole transaction")
+	}
+}
+
+func TestTransactionWriteThroughUnlocked(t *testing.T) {
+	var c *StringCache
+	c = NewWithOptions(WithWriteThrough(func(key string, val T) error {
+		// the cache is unlocked while entries are written, so this neither deadlocks nor interleaves with the transaction
+		if key == "1" && val == 1 {
+			c.Set("2", 2)
+		}
+
+		return nil
+	}))
+
+	calls := 0
+	err := c.Transaction(func(tx *Tx[string, T]) error {
+		calls++
+		n, _ := tx.Get("2")
+		tx.Set("1", calls)
+		tx.Set("copy", n)
+		return nil
+	})
+
+	if err != nil {
+		t.Errorf("Error was %v, expected nil", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("fn was called %d times, expected it to be called again after the cache changed", calls)
+	}
+
+	if result, expected := c.Items(), map[string]T{"1": 2, "2": 2, "copy": 2}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestTransactionWriteThroughConflict(t *testing.T) {
+	var c *StringCache
+	c = NewWithOptions(WithWriteThrough(func(key string, val T) error {
+		if key == "1" {
+			c.Set("2", val)
+		}
+
+		return nil
+	}))
+
+	calls := 0
+	err := c.Transaction(func(tx *Tx[string, T]) error {
+		calls++
+		tx.Set("1", calls)
+		return nil
+	})
+
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("Error was %v, expected %v", err, ErrConflict)
+	}
+
+	if calls != maxTxAttempts {
+		t.Errorf("fn was called %d times, expected %d", calls, maxTxAttempts)
+	}
+
+	if _, ok := c.GetOK("1"); ok {
+		t.Errorf("Entry for key '1' should not have been stored")
+	}
+}
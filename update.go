@@ -0,0 +1,211 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// ErrTypeMismatch is returned when an operation finds an entry whose value has the wrong type for it
+var ErrTypeMismatch = errors.New("cache: value has the wrong type")
+
+// ErrOverflow is returned by Increment when the result does not fit in the type of the value, or in an int64
+var ErrOverflow = errors.New("cache: increment overflows the value")
+
+// Increment adds delta to the number stored at the specified key in a single step, and returns the result.
+// The value may be an integer of any size, signed or unsigned, or a floating-point number, whose result is
+// returned truncated to an integer. A missing entry counts as 0 and is stored as an int64, or as V if V is
+// a numeric type, with the cache's default expiry; otherwise the value keeps its type and expiry.
+// If the value is not a number, the returned error wraps ErrTypeMismatch, and if the result would not fit
+// in the value's type or in an int64, it wraps ErrOverflow. In both cases the entry is left unchanged
+func (c *Cache[K, V]) Increment(key K, delta int64) (int64, error) {
+	type incremented struct {
+		n   int64
+		err error
+	}
+
+	result := make(chan incremented, 1)
+	c.itemOp(func(items backend[K, V]) {
+		v, ok := c.find(items, key)
+		if !ok {
+			zero := reflect.ValueOf(int64(0))
+			if t := reflect.TypeFor[V](); t.Kind() != reflect.Interface {
+				zero = reflect.Zero(t)
+			}
+
+			sum, n, err := add(zero, delta)
+			val, isV := sum.Interface().(V)
+			if !isV {
+				err = ErrTypeMismatch
+			}
+
+			if errors.Is(err, ErrTypeMismatch) {
+				result <- incremented{err: fmt.Errorf("%w: cannot store an integer at %q", ErrTypeMismatch, keyString(key))}
+				return
+			}
+
+			if err != nil {
+				result <- incremented{err: fmt.Errorf("%w: cannot store %d at %q", err, delta, keyString(key))}
+				return
+			}
+
+			c.place(items, key, val, nil)
+			result <- incremented{n: n}
+			return
+		}
+
+		sum, n, err := add(reflect.ValueOf(v), delta)
+		if errors.Is(err, ErrTypeMismatch) {
+			result <- incremented{err: fmt.Errorf("%w: cannot increment %q holding %T", err, keyString(key), v)}
+			return
+		}
+
+		if err != nil {
+			result <- incremented{err: fmt.Errorf("%w: cannot add %d to %q holding %v", err, delta, keyString(key), v)}
+			return
+		}
+
+		c.store(items, key, sum.Interface().(V))
+		result <- incremented{n: n}
+	})
+
+	r := <-result
+	return r.n, r.err
+}
+
+// Decrement subtracts delta from the number stored at the specified key. See Increment
+func (c *Cache[K, V]) Decrement(key K, delta int64) (int64, error) {
+	return c.Increment(key, -delta)
+}
+
+// Append adds suffix to the end of the string stored at the specified key in a single step,
+// and returns the result. A missing entry counts as "" and is stored with the cache's default expiry;
+// otherwise the entry keeps its expiry. If the value is not a string, the returned error wraps
+// ErrTypeMismatch and the entry is left unchanged
+func (c *Cache[K, V]) Append(key K, suffix string) (string, error) {
+	type appended struct {
+		s   string
+		err error
+	}
+
+	result := make(chan appended, 1)
+	c.itemOp(func(items backend[K, V]) {
+		v, ok := c.find(items, key)
+		if !ok {
+			val, ok := newValue[V](reflect.ValueOf(suffix))
+			if !ok {
+				result <- appended{err: fmt.Errorf("%w: cannot store a string at %q", ErrTypeMismatch, keyString(key))}
+				return
+			}
+
+			c.place(items, key, val, nil)
+
+			result <- appended{s: suffix}
+			return
+		}
+
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.String {
+			result <- appended{err: fmt.Errorf("%w: cannot append to %q holding %T", ErrTypeMismatch, keyString(key), v)}
+			return
+		}
+
+		s := rv.String() + suffix
+		val, _ := newValue[V](reflect.ValueOf(s).Convert(rv.Type()))
+		c.store(items, key, val)
+		result <- appended{s: s}
+	})
+
+	r := <-result
+	return r.s, r.err
+}
+
+// add returns the number in rv plus delta, both as a value of rv's type and as an int64.
+// The error wraps ErrTypeMismatch if rv is not a number, or ErrOverflow if the sum does not fit
+// in rv's type or in an int64, rather than letting it wrap around
+func add(rv reflect.Value, delta int64) (reflect.Value, int64, error) {
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x := rv.Int()
+		n := x + delta
+		if (n > x) != (delta > 0) || rv.OverflowInt(n) {
+			return rv, 0, ErrOverflow
+		}
+
+		return reflect.ValueOf(n).Convert(rv.Type()), n, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		x := rv.Uint()
+		n := x + uint64(delta)
+		if delta >= 0 && n < x || delta < 0 && n > x || rv.OverflowUint(n) || n > math.MaxInt64 {
+			return rv, 0, ErrOverflow
+		}
+
+		return reflect.ValueOf(n).Convert(rv.Type()), int64(n), nil
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float() + float64(delta)
+		if rv.OverflowFloat(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+			return rv, 0, ErrOverflow
+		}
+
+		return reflect.ValueOf(f).Convert(rv.Type()), int64(f), nil
+	default:
+		return rv, 0, ErrTypeMismatch
+	}
+}
+
+// newValue returns x as a V, converting it to V if both are strings,
+// so Append works on caches whose value type is a named string type.
+// Returns false if x cannot be stored as a V
+func newValue[V any](x reflect.Value) (V, bool) {
+	t := reflect.TypeFor[V]()
+	if t.Kind() == reflect.Interface {
+		v, ok := x.Interface().(V)
+		return v, ok
+	}
+
+	if t.Kind() == reflect.String && x.Kind() == reflect.String {
+		return x.Convert(t).Interface().(V), true
+	}
+
+	var zero V
+	return zero, false
+}
+
+// Modify replaces the value stored at the specified key with the result of calling fn on it, in a single step.
+// The entry keeps its expiry. Returns false, without calling fn, if no entry exists.
+// Since fn runs while the cache is locked, it must not call back into the cache.
+func (c *Cache[K, V]) Modify(key K, fn func(current V) V) bool {
+	modified := make(chan bool, 1)
+	c.itemOp(func(items backend[K, V]) {
+		v, ok := c.find(items, key)
+		if ok {
+			c.store(items, key, fn(v))
+		}
+
+		modified <- ok
+	})
+
+	return <-modified
+}
+
+// Increment adds delta to the number stored at the specified key. See Cache.Increment
+func (c *ShardedCache[K, V]) Increment(key K, delta int64) (int64, error) {
+	return c.shard(key).Increment(key, delta)
+}
+
+// Decrement subtracts delta from the number stored at the specified key. See Cache.Increment
+func (c *ShardedCache[K, V]) Decrement(key K, delta int64) (int64, error) {
+	return c.shard(key).Decrement(key, delta)
+}
+
+// Append adds suffix to the end of the string stored at the specified key. See Cache.Append
+func (c *ShardedCache[K, V]) Append(key K, suffix string) (string, error) {
+	return c.shard(key).Append(key, suffix)
+}
+
+// Modify replaces the value stored at the specified key with the result of calling fn on it.
+// See Cache.Modify
+func (c *ShardedCache[K, V]) Modify(key K, fn func(current V) V) bool {
+	return c.shard(key).Modify(key, fn)
+}
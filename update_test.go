@@ -0,0 +1,231 @@
+package cache
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIncrement(t *testing.T) {
+	c := New()
+	c.Set("int", 1, Expire(time.Hour))
+
+	if result, err := c.Increment("int", 2); err != nil || result != 3 {
+		t.Errorf("Result was %d, %v, expected 3", result, err)
+	}
+
+	if result, expected := c.Get("int"), T(3); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if _, ok := c.RemainingTTL("int"); !ok {
+		t.Errorf("Expiry for key 'int' should have been kept")
+	}
+
+	if result, err := c.Decrement("missing", 2); err != nil || result != -2 {
+		t.Errorf("Result was %d, %v, expected -2", result, err)
+	}
+
+	if result, expected := c.Get("missing"), T(int64(-2)); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	c.Set("string", "a")
+	if _, err := c.Increment("string", 1); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("Error was %v, expected %v", err, ErrTypeMismatch)
+	}
+}
+
+func TestIncrementTyped(t *testing.T) {
+	c := NewCache[string, int32]()
+	if result, err := c.Increment("missing", 2); err != nil || result != 2 {
+		t.Errorf("Result was %d, %v, expected 2", result, err)
+	}
+
+	if result, err := c.Increment("missing", 3); err != nil || result != 5 {
+		t.Errorf("Result was %d, %v, expected 5", result, err)
+	}
+
+	if result, expected := c.Get("missing"), int32(5); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	s := NewCache[string, []byte]()
+	if _, err := s.Increment("missing", 1); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("Error was %v, expected %v", err, ErrTypeMismatch)
+	}
+}
+
+func TestIncrementOverflow(t *testing.T) {
+	c := New()
+	c.Set("int8", int8(127))
+	if _, err := c.Increment("int8", 1); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Error was %v, expected %v", err, ErrOverflow)
+	}
+
+	if result, expected := c.Get("int8"), T(int8(127)); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if result, err := c.Decrement("int8", 255); err != nil || result != -128 {
+		t.Errorf("Result was %d, %v, expected -128", result, err)
+	}
+
+	c.Set("int64", int64(math.MaxInt64))
+	if _, err := c.Increment("int64", 1); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Error was %v, expected %v", err, ErrOverflow)
+	}
+
+	c.Set("uint8", uint8(1))
+	if _, err := c.Decrement("uint8", 2); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Error was %v, expected %v", err, ErrOverflow)
+	}
+
+	if result, err := c.Increment("uint8", 254); err != nil || result != 255 {
+		t.Errorf("Result was %d, %v, expected 255", result, err)
+	}
+
+	if result, expected := c.Get("uint8"), T(uint8(255)); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	c.Set("uint64", uint64(math.MaxUint64))
+	if _, err := c.Increment("uint64", 0); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Error was %v, expected %v", err, ErrOverflow)
+	}
+
+	c.Set("float", 1.5)
+	if result, err := c.Increment("float", 2); err != nil || result != 3 {
+		t.Errorf("Result was %d, %v, expected 3", result, err)
+	}
+
+	if result, expected := c.Get("float"), T(3.5); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	u := NewCache[string, uint]()
+	if _, err := u.Decrement("missing", 1); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Error was %v, expected %v", err, ErrOverflow)
+	}
+
+	if u.Contains("missing") {
+		t.Errorf("Entry for key 'missing' should not have been stored")
+	}
+
+	f := NewCache[string, float32]()
+	if result, err := f.Increment("missing", 2); err != nil || result != 2 {
+		t.Errorf("Result was %d, %v, expected 2", result, err)
+	}
+
+	if result, expected := f.Get("missing"), float32(2); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestIncrementConcurrent(t *testing.T) {
+	c := New()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Increment("1", 1)
+		}()
+	}
+
+	wg.Wait()
+	if result, expected := c.Get("1"), T(int64(100)); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestAppend(t *testing.T) {
+	c := New()
+
+	if result, err := c.Append("1", "a"); err != nil || result != "a" {
+		t.Errorf("Result was %q, %v, expected %q", result, err, "a")
+	}
+
+	if result, err := c.Append("1", "b"); err != nil || result != "ab" {
+		t.Errorf("Result was %q, %v, expected %q", result, err, "ab")
+	}
+
+	if result, expected := c.Get("1"), T("ab"); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	c.Set("2", 2)
+	if _, err := c.Append("2", "a"); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("Error was %v, expected %v", err, ErrTypeMismatch)
+	}
+
+	if result, expected := c.Get("2"), T(2); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestModify(t *testing.T) {
+	c := New()
+	c.Set("1", []int{1}, Expire(time.Hour))
+
+	ok := c.Modify("1", func(current T) T {
+		return append(current.([]int), 2)
+	})
+
+	if !ok {
+		t.Errorf("Entry for key '1' should have been modified")
+	}
+
+	if result, expected := c.Get("1"), T([]int{1, 2}); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if _, ok := c.RemainingTTL("1"); !ok {
+		t.Errorf("Expiry for key '1' should have been kept")
+	}
+
+	called := false
+	if c.Modify("2", func(current T) T { called = true; return current }) || called {
+		t.Errorf("Missing entry for key '2' should not have been modified")
+	}
+}
+
+func TestModifyPanic(t *testing.T) {
+	c := New()
+	c.Set("1", 0)
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("Recovered %v, expected %v", r, "boom")
+			}
+		}()
+
+		c.Modify("1", func(current T) T { panic("boom") })
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		func() {
+			defer func() { recover() }()
+			c.ForEach(func(key string, val T) { panic("boom") })
+		}()
+
+		c.Set("1", 1)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Set did not return after a callback panicked")
+	}
+
+	if result, expected := c.Get("1"), T(1); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
@@ -0,0 +1,199 @@
+package cache
+
+import (
+	"strings"
+	"sync"
+)
+
+// watchBuffer is the number of events a Watch channel holds before further events are dropped
+const watchBuffer = 64
+
+// A WatchEventType describes the change a WatchEvent reports
+type WatchEventType int
+
+const (
+	// WatchSet means an entry was stored at the key
+	WatchSet WatchEventType = iota
+	// WatchDeleted means the entry at the key was removed by Delete, Clear or a similar call,
+	// or evicted to make room in a cache created with WithMaxSize
+	WatchDeleted
+	// WatchExpired means the entry at the key expired
+	WatchExpired
+)
+
+func (t WatchEventType) String() string {
+	switch t {
+	case WatchSet:
+		return "set"
+	case WatchDeleted:
+		return "deleted"
+	case WatchExpired:
+		return "expired"
+	default:
+		return "unknown"
+	}
+}
+
+// A WatchEvent reports a change to the entry at a watched key.
+// OldValue is the value replaced or removed, or the zero value if a new entry was stored,
+// and NewValue is the value stored, or the zero value if the entry was removed
+type WatchEvent[K comparable, V any] struct {
+	Key       K
+	OldValue  V
+	NewValue  V
+	EventType WatchEventType
+}
+
+// Watch returns a channel that receives a WatchEvent whenever an entry is stored at the specified key,
+// or the entry there is removed or expires, along with a function that stops watching and closes the channel.
+// Events are sent without blocking the cache: the channel buffers a number of events,
+// and events that arrive while it is full are dropped. The channel is also closed when the cache is closed
+func (c *Cache[K, V]) Watch(key K) (<-chan WatchEvent[K, V], func()) {
+	ch := make(chan WatchEvent[K, V], watchBuffer)
+	c.itemOp(func(backend[K, V]) {
+		c.watchers[key] = append(c.watchers[key], ch)
+	})
+
+	return ch, c.unwatch(ch, func() {
+		watchers := c.watchers[key]
+		for i, w := range watchers {
+			if w == ch {
+				watchers = append(watchers[:i], watchers[i+1:]...)
+				break
+			}
+		}
+
+		if len(watchers) == 0 {
+			delete(c.watchers, key)
+		} else {
+			c.watchers[key] = watchers
+		}
+	})
+}
+
+// A prefixWatcher is a channel returned by WatchPrefix, with the prefix it watches
+type prefixWatcher[K comparable, V any] struct {
+	prefix string
+	ch     chan WatchEvent[K, V]
+}
+
+// WatchPrefix behaves like Watch, but the channel receives a WatchEvent for every key that starts with prefix.
+// Keys that are not strings are matched by their fmt.Sprint form.
+// Every change is matched against each WatchPrefix caller's prefix, so keep their number small
+func (c *Cache[K, V]) WatchPrefix(prefix string) (<-chan WatchEvent[K, V], func()) {
+	ch := make(chan WatchEvent[K, V], watchBuffer)
+	c.itemOp(func(backend[K, V]) {
+		c.prefixWatchers = append(c.prefixWatchers, prefixWatcher[K, V]{prefix: prefix, ch: ch})
+	})
+
+	return ch, c.unwatch(ch, func() {
+		for i, w := range c.prefixWatchers {
+			if w.ch == ch {
+				c.prefixWatchers = append(c.prefixWatchers[:i], c.prefixWatchers[i+1:]...)
+				break
+			}
+		}
+	})
+}
+
+// unwatch returns the function that stops a watch: it calls remove with mu held to forget ch, then closes ch.
+// It does nothing if it has already been called, or if the cache was closed, since Close closes ch
+func (c *Cache[K, V]) unwatch(ch chan WatchEvent[K, V], remove func()) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.tryItemOp(func(backend[K, V]) {
+				remove()
+				close(ch)
+			})
+		})
+	}
+}
+
+// publish sends a WatchEvent to the channels watching the specified key, dropping it for any that are full.
+// It must only be called with mu held
+func (c *Cache[K, V]) publish(key K, old, val V, t WatchEventType) {
+	watchers := c.watchers[key]
+	if len(watchers) == 0 && len(c.prefixWatchers) == 0 {
+		return
+	}
+
+	e := WatchEvent[K, V]{Key: key, OldValue: old, NewValue: val, EventType: t}
+	for _, ch := range watchers {
+		send(ch, e)
+	}
+
+	if len(c.prefixWatchers) == 0 {
+		return
+	}
+
+	s := keyString(key)
+	for _, w := range c.prefixWatchers {
+		if strings.HasPrefix(s, w.prefix) {
+			send(w.ch, e)
+		}
+	}
+}
+
+// send sends e to ch unless ch is full
+func send[K comparable, V any](ch chan WatchEvent[K, V], e WatchEvent[K, V]) {
+	select {
+	case ch <- e:
+	default:
+	}
+}
+
+// closeWatchers closes every Watch and WatchPrefix channel. It must only be called with mu held
+func (c *Cache[K, V]) closeWatchers() {
+	for key, watchers := range c.watchers {
+		for _, ch := range watchers {
+			close(ch)
+		}
+
+		delete(c.watchers, key)
+	}
+
+	for _, w := range c.prefixWatchers {
+		close(w.ch)
+	}
+
+	c.prefixWatchers = nil
+}
+
+// Watch returns a channel that receives changes to the entry at the specified key. See Cache.Watch
+func (c *ShardedCache[K, V]) Watch(key K) (<-chan WatchEvent[K, V], func()) {
+	return c.shard(key).Watch(key)
+}
+
+// WatchPrefix returns a channel that receives changes to the entries whose keys start with prefix,
+// merged from every shard. See Cache.WatchPrefix
+func (c *ShardedCache[K, V]) WatchPrefix(prefix string) (<-chan WatchEvent[K, V], func()) {
+	out := make(chan WatchEvent[K, V], watchBuffer)
+	stops := make([]func(), len(c.shards))
+	var wg sync.WaitGroup
+	for i, shard := range c.shards {
+		events, stop := shard.WatchPrefix(prefix)
+		stops[i] = stop
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range events {
+				send(out, e)
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	var once sync.Once
+	return out, func() {
+		once.Do(func() {
+			for _, stop := range stops {
+				stop()
+			}
+		})
+	}
+}
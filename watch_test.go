@@ -0,0 +1,133 @@
+package cache
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	c := New()
+	events, stop := c.Watch("1")
+
+	c.Set("1", 1)
+	c.Set("2", 2)
+	c.Set("1", 3)
+	c.Delete("1")
+	c.Set("1", 4, Expire(time.Millisecond))
+	time.Sleep(time.Millisecond * 10)
+	stop()
+
+	var result []WatchEvent[string, T]
+	for e := range events {
+		result = append(result, e)
+	}
+
+	expected := []WatchEvent[string, T]{
+		{Key: "1", NewValue: 1, EventType: WatchSet},
+		{Key: "1", OldValue: 1, NewValue: 3, EventType: WatchSet},
+		{Key: "1", OldValue: 3, EventType: WatchDeleted},
+		{Key: "1", NewValue: 4, EventType: WatchSet},
+		{Key: "1", OldValue: 4, EventType: WatchExpired},
+	}
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestWatchDropsWhenFull(t *testing.T) {
+	c := New()
+	events, stop := c.Watch("1")
+	defer stop()
+
+	for i := 0; i < watchBuffer*2; i++ {
+		c.Set("1", i)
+	}
+
+	if result, expected := len(events), watchBuffer; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestWatchStop(t *testing.T) {
+	c := New()
+	_, stop1 := c.Watch("1")
+	events, stop2 := c.Watch("1")
+	stop1()
+	stop1()
+
+	c.Set("1", 1)
+	if result, expected := len(events), 1; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	stop2()
+	if result, expected := len(c.watchers), 0; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestWatchClose(t *testing.T) {
+	c := New()
+	events, stop := c.Watch("1")
+	c.Close()
+	stop()
+
+	if _, ok := <-events; ok {
+		t.Errorf("Channel should have been closed")
+	}
+}
+
+func TestWatchPrefix(t *testing.T) {
+	c := New()
+	events, stop := c.WatchPrefix("user:42:")
+
+	c.Set("user:42:name", "a")
+	c.Set("user:4:name", "b")
+	c.Set("user:42:email", "c")
+	c.Delete("user:42:name")
+	c.Delete("user:421")
+	stop()
+
+	var result []WatchEvent[string, T]
+	for e := range events {
+		result = append(result, e)
+	}
+
+	expected := []WatchEvent[string, T]{
+		{Key: "user:42:name", NewValue: "a", EventType: WatchSet},
+		{Key: "user:42:email", NewValue: "c", EventType: WatchSet},
+		{Key: "user:42:name", OldValue: "a", EventType: WatchDeleted},
+	}
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if result, expected := len(c.prefixWatchers), 0; result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestShardedWatchPrefix(t *testing.T) {
+	c := NewSharded(WithShards(4))
+	events, stop := c.WatchPrefix("a")
+
+	keys := []string{"a1", "b1", "a2", "b2", "a3", "b3", "a4", "b4"}
+	for _, key := range keys {
+		c.Set(key, 1)
+	}
+
+	stop()
+
+	result := map[string]bool{}
+	for e := range events {
+		result[e.Key] = true
+	}
+
+	expected := map[string]bool{"a1": true, "a2": true, "a3": true, "a4": true}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
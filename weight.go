@@ -0,0 +1,138 @@
+package cache
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// weighing returns the weight limit set by WithMaxWeight or WithMaxMemory, and the function weighing each entry
+// against it, or 0 and nil if the cache's weight is not limited
+func weighing[K comparable, V any](opts cacheOptions) (int64, func(K, V) int64) {
+	var limit int64
+	var weigh func(K, V) int64
+	switch {
+	case opts.maxWeight > 0:
+		limit = int64(opts.maxWeight)
+		weigh = func(K, V) int64 { return 1 }
+	case opts.maxMemory > 0:
+		limit = opts.maxMemory
+		weigh = estimateSize[K, V]
+	default:
+		return 0, nil
+	}
+
+	if fn := option[func(K, V) int](opts.weigher, "WithWeigher"); fn != nil {
+		weigh = func(key K, val V) int64 { return int64(max(fn(key, val), 0)) }
+	}
+
+	return limit, weigh
+}
+
+// overCapacity reports if the cache holds more entries than its WithMaxSize limit,
+// or more weight than its WithMaxMemory or WithMaxWeight limit. It must only be called with mu held
+func (c *Cache[K, V]) overCapacity(items backend[K, V]) bool {
+	if c.maxSize > 0 && items.len() > c.maxSize {
+		return true
+	}
+
+	return c.maxWeight > 0 && c.weight > c.maxWeight && items.len() > 0
+}
+
+// oversized reports if the entry at the specified key weighs more than the WithMaxMemory or WithMaxWeight limit
+// on its own. It must only be called with mu held
+func (c *Cache[K, V]) oversized(key K) bool {
+	return c.maxWeight > 0 && c.weights[key] > c.maxWeight
+}
+
+// addWeight records the weight of the entry stored at the specified key, replacing any weight it had.
+// It must only be called with mu held
+func (c *Cache[K, V]) addWeight(key K, val V) {
+	if c.weigh == nil {
+		return
+	}
+
+	w := c.weigh(key, val)
+	c.weight += w - c.weights[key]
+	c.weights[key] = w
+}
+
+// removeWeight forgets the weight of the entry at the specified key. It must only be called with mu held
+func (c *Cache[K, V]) removeWeight(key K) {
+	if c.weigh == nil {
+		return
+	}
+
+	c.weight -= c.weights[key]
+	delete(c.weights, key)
+}
+
+// estimateSize approximates the bytes of memory used by an entry: the size of its key and value,
+// plus the strings, slices, maps and pointed-to values they reference. Memory shared between entries
+// is counted for each of them, and allocator and map overhead is not counted at all
+func estimateSize[K comparable, V any](key K, val V) int64 {
+	seen := map[uintptr]bool{}
+	return int64(unsafe.Sizeof(key)) + int64(unsafe.Sizeof(val)) +
+		indirectSize(reflect.ValueOf(&key).Elem(), seen) + indirectSize(reflect.ValueOf(&val).Elem(), seen)
+}
+
+// indirectSize approximates the bytes of memory referenced by v, not counting v itself.
+// seen holds the pointers already counted, so cycles are only followed once
+func indirectSize(v reflect.Value, seen map[uintptr]bool) int64 {
+	switch v.Kind() {
+	case reflect.String:
+		return int64(v.Len())
+	case reflect.Slice:
+		if v.IsNil() || seen[v.Pointer()] {
+			return 0
+		}
+
+		seen[v.Pointer()] = true
+		n := int64(v.Cap()) * int64(v.Type().Elem().Size())
+		for i := 0; i < v.Len(); i++ {
+			n += indirectSize(v.Index(i), seen)
+		}
+
+		return n
+	case reflect.Array:
+		var n int64
+		for i := 0; i < v.Len(); i++ {
+			n += indirectSize(v.Index(i), seen)
+		}
+
+		return n
+	case reflect.Map:
+		if v.IsNil() || seen[v.Pointer()] {
+			return 0
+		}
+
+		seen[v.Pointer()] = true
+		n := int64(v.Len()) * int64(v.Type().Key().Size()+v.Type().Elem().Size())
+		for iter := v.MapRange(); iter.Next(); {
+			n += indirectSize(iter.Key(), seen) + indirectSize(iter.Value(), seen)
+		}
+
+		return n
+	case reflect.Pointer:
+		if v.IsNil() || seen[v.Pointer()] {
+			return 0
+		}
+
+		seen[v.Pointer()] = true
+		return int64(v.Type().Elem().Size()) + indirectSize(v.Elem(), seen)
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+
+		return int64(v.Elem().Type().Size()) + indirectSize(v.Elem(), seen)
+	case reflect.Struct:
+		var n int64
+		for i := 0; i < v.NumField(); i++ {
+			n += indirectSize(v.Field(i), seen)
+		}
+
+		return n
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithMaxMemory(t *testing.T) {
+	c := NewWithOptions(WithMaxMemory(3000))
+	for _, key := range []string{"1", "2", "3", "4"} {
+		c.Set(key, strings.Repeat("x", 1000))
+	}
+
+	if result, expected := c.Keys(), []string{"3", "4"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	c.Set("3", "small")
+	c.Set("5", "small")
+	if result, expected := c.Keys(), []string{"3", "4", "5"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	c.Delete("4")
+	c.Delete("5")
+	c.Rename("3", "6")
+	if result, expected := c.weight, estimateSize[string, T]("6", "small"); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestEstimateSize(t *testing.T) {
+	type node struct {
+		name string
+		next *node
+	}
+
+	cyclic := &node{name: "abcd"}
+	cyclic.next = cyclic
+
+	tests := []struct {
+		name     string
+		size     int64
+		expected int64
+	}{
+		{"int", estimateSize("", 1), 16 + 8},
+		{"string", estimateSize("abc", "abcd"), 16 + 3 + 16 + 4},
+		{"interface", estimateSize[string, T]("", "abcd"), 16 + 16 + 16 + 4},
+		{"slice", estimateSize("", []string{"ab", "cd"}), 16 + 24 + 2*16 + 4},
+		{"map", estimateSize("", map[string]int{"ab": 1}), 16 + 8 + 16 + 8 + 2},
+		{"cycle", estimateSize("", cyclic), 16 + 8 + 24 + 4},
+	}
+
+	for _, test := range tests {
+		if test.size != test.expected {
+			t.Errorf("%s: Result was %#v, expected %#v", test.name, test.size, test.expected)
+		}
+	}
+}
+
+func TestWithMaxWeight(t *testing.T) {
+	c := NewWithOptions(WithMaxWeight(10), WithWeigher(func(key string, val T) int {
+		return val.(int)
+	}))
+
+	c.Set("1", 4)
+	c.Set("2", 4)
+	c.Set("3", 2)
+	if result, expected := c.Keys(), []string{"1", "2", "3"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	c.Get("1")
+	c.Set("4", 5)
+	if result, expected := c.Keys(), []string{"1", "4"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	c.Set("5", -3)
+	if result, expected := c.weight, int64(9); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	c.Set("6", 20)
+	if result, expected := c.Keys(), []string{"1", "4", "5"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestWithMaxWeightOversized(t *testing.T) {
+	c := NewWithOptions(WithMaxWeight(10), WithWeigher(func(key string, val T) int {
+		return val.(int)
+	}))
+
+	for _, key := range []string{"1", "2", "3", "4", "5"} {
+		c.Set(key, 1)
+	}
+
+	c.Set("big", 11, Expire(time.Hour))
+	if result, expected := c.Keys(), []string{"1", "2", "3", "4", "5"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if result, expected := c.weight, int64(5); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+
+	if result, expected := c.Stats().Evictions, int64(1); result != expected {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
+
+func TestWithMaxWeightNoWeigher(t *testing.T) {
+	c := NewWithOptions(WithMaxWeight(2))
+	for _, key := range []string{"1", "2", "3"} {
+		c.Set(key, key)
+	}
+
+	if result, expected := c.Keys(), []string{"2", "3"}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was %#v, expected %#v", result, expected)
+	}
+}
@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// A writeBehind queues the entries stored in a cache created with WithWriteBehind,
+// and passes them to fn from its own goroutine, at most once per maxDelay.
+// Only the latest value stored at each key since the last flush is passed on
+type writeBehind[K comparable, V any] struct {
+	fn       func(key K, val V)
+	maxDelay time.Duration
+
+	mu      sync.Mutex
+	pending map[K]V
+
+	// queued is signalled when pending gains an entry
+	queued chan struct{}
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	drained  chan struct{}
+}
+
+func newWriteBehind[K comparable, V any](opts cacheOptions) *writeBehind[K, V] {
+	w := &writeBehind[K, V]{
+		fn:       option[func(K, V)](opts.writeBehind, "WithWriteBehind"),
+		maxDelay: opts.writeBehindDelay,
+		pending:  map[K]V{},
+		queued:   make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		drained:  make(chan struct{}),
+	}
+
+	go w.loop()
+	return w
+}
+
+// add queues val to be written at key, replacing any value still pending there
+func (w *writeBehind[K, V]) add(key K, val V) {
+	w.mu.Lock()
+	w.pending[key] = val
+	w.mu.Unlock()
+
+	select {
+	case w.queued <- struct{}{}:
+	default:
+	}
+}
+
+// loop waits for an entry to be queued, then flushes the pending entries once maxDelay has passed.
+// Once close is called, it flushes whatever is still pending and returns
+func (w *writeBehind[K, V]) loop() {
+	defer close(w.drained)
+	for {
+		select {
+		case <-w.queued:
+		case <-w.stop:
+			w.flush()
+			return
+		}
+
+		timer := time.NewTimer(w.maxDelay)
+		select {
+		case <-timer.C:
+		case <-w.stop:
+			timer.Stop()
+			w.flush()
+			return
+		}
+
+		w.flush()
+	}
+}
+
+// flush passes every pending entry to fn
+func (w *writeBehind[K, V]) flush() {
+	w.mu.Lock()
+	pending := w.pending
+	w.pending = map[K]V{}
+	w.mu.Unlock()
+
+	for key, val := range pending {
+		w.fn(key, val)
+	}
+}
+
+// close stops the goroutine once it has flushed the pending entries, and waits for it to do so
+func (w *writeBehind[K, V]) close() {
+	w.stopOnce.Do(func() { close(w.stop) })
+	<-w.drained
+}
@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithWriteBehind(t *testing.T) {
+	var mu sync.Mutex
+	written := map[string][]T{}
+	c := NewWithOptions(WithWriteBehind(func(key string, val T) {
+		mu.Lock()
+		defer mu.Unlock()
+		written[key] = append(written[key], val)
+	}, time.Millisecond*50))
+
+	c.Set("1", 1)
+	c.Set("1", 2)
+	c.Set("2", 3)
+
+	mu.Lock()
+	if len(written) != 0 {
+		t.Errorf("Entries were written before maxDelay had passed: %#v", written)
+	}
+
+	mu.Unlock()
+
+	time.Sleep(time.Millisecond * 100)
+	mu.Lock()
+	if expected := map[string][]T{"1": {2}, "2": {3}}; !reflect.DeepEqual(written, expected) {
+		t.Errorf("Result was %#v, expected %#v", written, expected)
+	}
+
+	mu.Unlock()
+
+	c.Set("1", 4)
+	c.Close()
+
+	if expected := map[string][]T{"1": {2, 4}, "2": {3}}; !reflect.DeepEqual(written, expected) {
+		t.Errorf("Result was %#v, expected %#v", written, expected)
+	}
+}
+
+func TestShardedWithWriteBehind(t *testing.T) {
+	var mu sync.Mutex
+	written := map[string]T{}
+	c := NewSharded(WithShards(4), WithWriteBehind(func(key string, val T) {
+		mu.Lock()
+		defer mu.Unlock()
+		written[key] = val
+	}, time.Hour))
+
+	c.Set("1", 1)
+	c.Set("2", 2)
+	c.Set("2", 3)
+	c.Close()
+
+	if expected := map[string]T{"1": 1, "2": 3}; !reflect.DeepEqual(written, expected) {
+		t.Errorf("Result was %#v, expected %#v", written, expected)
+	}
+}
+
+func TestWriteBehindRename(t *testing.T) {
+	written := map[string]T{}
+	c := NewWithOptions(WithWriteBehind(func(key string, val T) {
+		written[key] = val
+	}, time.Hour))
+
+	c.Set("1", 1)
+	c.Rename("1", "2")
+	c.Close()
+
+	if expected := map[string]T{"1": 1, "2": 1}; !reflect.DeepEqual(written, expected) {
+		t.Errorf("Result was %#v, expected %#v", written, expected)
+	}
+}